@@ -0,0 +1,161 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// maxAdaptiveDuration is the maximum duration of the adaptive level.
+const maxAdaptiveDuration = 15 * time.Minute
+
+// AdaptiveLevelOptions for the adaptive level of the handler. When the handler
+// observes more than Threshold entries at LevelError or above within Window,
+// it lowers the minimum level to Level for Duration, then reverts to the
+// configured level. A zero AdaptiveLevelOptions consists entirely of default
+// values.
+type AdaptiveLevelOptions struct {
+	// Threshold is the number of error entries that triggers the adaptive
+	// level. If Threshold is zero, the handler assumes 10.
+	Threshold int
+
+	// Window is the duration in which the error entries are counted.
+	// If Window is zero, the handler assumes 1m.
+	Window time.Duration
+
+	// Duration is how long the adaptive level is in effect. It's capped at
+	// 15m. If Duration is zero, the handler assumes 1m.
+	Duration time.Duration
+
+	// Cooldown is the duration after the adaptive level reverts in which it
+	// cannot be triggered again. If Cooldown is zero, the handler assumes 5m.
+	Cooldown time.Duration
+
+	// Level is the minimum level while the adaptive level is in effect.
+	// If Level is nil, the handler assumes LevelDebug.
+	Level slog.Leveler
+
+	// Now returns the current time. If Now is nil, the handler uses time.Now.
+	Now func() time.Time
+}
+
+// adaptive represents the adaptive level state shared by the handler clones.
+type adaptive struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+	cooldown  time.Duration
+	level     slog.Leveler
+	now       func() time.Time
+
+	// until is the end of the adaptive level in unix nanoseconds
+	until atomic.Int64
+
+	mu    sync.Mutex
+	start time.Time
+	count int
+	quiet time.Time
+}
+
+func newAdaptive(opts *AdaptiveLevelOptions) *adaptive {
+	if opts == nil {
+		return nil
+	}
+
+	a := &adaptive{
+		threshold: opts.Threshold,
+		window:    opts.Window,
+		duration:  min(opts.Duration, maxAdaptiveDuration),
+		cooldown:  opts.Cooldown,
+		level:     opts.Level,
+		now:       opts.Now,
+	}
+
+	if a.threshold <= 0 {
+		a.threshold = 10
+	}
+
+	if a.window <= 0 {
+		a.window = time.Minute
+	}
+
+	if a.duration <= 0 {
+		a.duration = time.Minute
+	}
+
+	if a.cooldown <= 0 {
+		a.cooldown = 5 * time.Minute
+	}
+
+	if a.level == nil {
+		a.level = slog.LevelDebug
+	}
+
+	if a.now == nil {
+		a.now = time.Now
+	}
+
+	return a
+}
+
+// active reports whether the adaptive level is in effect.
+func (a *adaptive) active() bool {
+	until := a.until.Load()
+	return until != 0 && a.now().UnixNano() < until
+}
+
+// observe counts an error entry and reports whether it triggered the adaptive
+// level, in which case it returns the end of the adaptive level.
+func (a *adaptive) observe() (time.Time, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	// the adaptive level is in effect or cooling down
+	if now.Before(a.quiet) {
+		return time.Time{}, false
+	}
+
+	if now.Sub(a.start) > a.window {
+		a.start = now
+		a.count = 0
+	}
+
+	a.count++
+
+	if a.count <= a.threshold {
+		return time.Time{}, false
+	}
+
+	until := now.Add(a.duration)
+	// start the adaptive level
+	a.until.Store(until.UnixNano())
+	a.quiet = until.Add(a.cooldown)
+	a.count = 0
+
+	return until, true
+}
+
+// adapt observes a given record and announces the adaptive level when the
+// record triggers it.
+func (h *Handler) adapt(ctx context.Context, r slog.Record) {
+	if r.Level < slog.LevelError {
+		return
+	}
+
+	until, ok := h.adaptive.observe()
+	if !ok {
+		return
+	}
+
+	record := slog.NewRecord(h.adaptive.now(), LevelNotice, "adaptive level enabled after an error burst", r.PC)
+	record.AddAttrs(
+		slog.String("adaptive_level", h.adaptive.level.Level().String()),
+		slog.Time("adaptive_until", until),
+	)
+
+	// the diagnostic is below error, so it doesn't count
+	_ = h.Handle(ctx, record)
+}
@@ -0,0 +1,118 @@
+package slogr
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// clock is a manual clock for the adaptive level.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestAdaptiveLevel(t *testing.T) {
+	now := &clock{now: time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)}
+
+	logger, entries := capture(t, &HandlerOptions{
+		AdaptiveLevel: &AdaptiveLevelOptions{
+			Threshold: 2,
+			Window:    time.Minute,
+			Duration:  time.Minute,
+			Cooldown:  time.Minute,
+			Now:       now.Now,
+		},
+	})
+
+	count := func() int { return len(entries()) }
+
+	logger.Debug("dropped")
+	for index := 0; index < 3; index++ {
+		logger.Error("failed")
+	}
+
+	// the third error triggers the adaptive level, which is announced
+	collection := entries()
+	if len(collection) != 4 {
+		t.Fatalf("got %d entries, want 3 errors and the notice", len(collection))
+	}
+
+	notice := collection[3]
+	if got := notice["severity"]; got != "NOTICE" {
+		t.Fatalf("got severity %v, want NOTICE", got)
+	}
+
+	if got := payloadOf(notice)["adaptive_level"]; got != "DEBUG" {
+		t.Fatalf("got adaptive level %v, want DEBUG", got)
+	}
+
+	logger.Debug("kept")
+	if got := count(); got != 5 {
+		t.Fatalf("got %d entries, want the debug entry", got)
+	}
+
+	// the level reverts after the duration
+	now.advance(time.Minute)
+	logger.Debug("dropped")
+
+	if got := count(); got != 5 {
+		t.Fatalf("got %d entries, want the debug entry dropped", got)
+	}
+
+	// the burst in the cooldown doesn't trigger it again
+	for index := 0; index < 3; index++ {
+		logger.Error("failed")
+	}
+
+	logger.Debug("dropped")
+
+	if got := count(); got != 8 {
+		t.Fatalf("got %d entries, want the errors only", got)
+	}
+
+	now.advance(time.Minute)
+	for index := 0; index < 3; index++ {
+		logger.Error("failed")
+	}
+
+	logger.Debug("kept")
+
+	if got := count(); got != 13 {
+		t.Fatalf("got %d entries, want the errors, the notice and the debug entry", got)
+	}
+}
+
+func TestAdaptiveLevelWindow(t *testing.T) {
+	now := &clock{now: time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)}
+
+	logger, entries := capture(t, &HandlerOptions{
+		AdaptiveLevel: &AdaptiveLevelOptions{Threshold: 1, Window: time.Second, Now: now.Now},
+	})
+
+	// the errors are spread beyond the window
+	for index := 0; index < 3; index++ {
+		logger.Error("failed")
+		now.advance(2 * time.Second)
+	}
+
+	logger.Debug("dropped")
+
+	if got := len(entries()); got != 3 {
+		t.Fatalf("got %d entries, want the errors only", got)
+	}
+}
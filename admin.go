@@ -0,0 +1,147 @@
+package slogr
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// LevelHandler returns an [http.Handler] that reports and changes a given
+// level at runtime. GET returns the current level as JSON. PUT and POST change
+// the level from a JSON body like {"level":"debug"} or from a level form
+// value. An optional duration, e.g. "10m", reverts the level once it elapses.
+// Setting the level again before that resets the timer.
+func LevelHandler(v *slog.LevelVar) http.Handler {
+	return &levelHandler{level: v}
+}
+
+// levelResponse represents the body of a level handler response.
+type levelResponse struct {
+	Level    string     `json:"level"`
+	RevertAt *time.Time `json:"revert_at,omitempty"`
+}
+
+// levelRequest represents the JSON body of a level handler request.
+type levelRequest struct {
+	Level    string `json:"level"`
+	Duration string `json:"duration"`
+}
+
+type levelHandler struct {
+	level *slog.LevelVar
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	previous slog.Level
+	deadline time.Time
+}
+
+// ServeHTTP implements http.Handler
+func (h *levelHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		if err := h.update(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	h.mu.Lock()
+	response := levelResponse{
+		Level: levelString(h.level.Level()),
+	}
+	// the level is reverted later
+	if deadline := h.deadline; !deadline.IsZero() {
+		response.RevertAt = &deadline
+	}
+	h.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	// write the response
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+func (h *levelHandler) update(r *http.Request) error {
+	var input levelRequest
+
+	if kind, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); kind == "application/json" {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			return fmt.Errorf("slogr: invalid request body: %w", err)
+		}
+	} else {
+		input.Level = r.FormValue("level")
+		input.Duration = r.FormValue("duration")
+	}
+
+	// the duration can be a query parameter
+	if input.Duration == "" {
+		input.Duration = r.URL.Query().Get("duration")
+	}
+
+	level, err := ParseLevel(input.Level)
+	if err != nil {
+		return err
+	}
+
+	var duration time.Duration
+
+	if input.Duration != "" {
+		value, err := time.ParseDuration(input.Duration)
+		if err != nil || value <= 0 {
+			return fmt.Errorf("slogr: invalid duration %q", input.Duration)
+		}
+
+		duration = value
+	}
+
+	h.set(level, duration)
+	// done!
+	return nil
+}
+
+// set changes the level, and reverts it after a given duration unless it's
+// zero. The level is reverted to the one before the first pending change.
+func (h *levelHandler) set(level slog.Level, duration time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	pending := h.timer != nil && h.timer.Stop()
+
+	if duration <= 0 {
+		h.timer = nil
+		h.deadline = time.Time{}
+		h.level.Set(level)
+		return
+	}
+
+	if !pending {
+		h.previous = h.level.Level()
+	}
+
+	h.level.Set(level)
+	h.deadline = time.Now().Add(duration)
+
+	var timer *time.Timer
+	// revert the level unless it was changed in the meantime
+	timer = time.AfterFunc(duration, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		if h.timer == timer {
+			h.level.Set(h.previous)
+			h.timer = nil
+			h.deadline = time.Time{}
+		}
+	})
+
+	h.timer = timer
+}
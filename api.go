@@ -0,0 +1,481 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"golang.org/x/oauth2/google"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/oauth"
+	"google.golang.org/protobuf/proto"
+)
+
+// ErrClosed is returned when an entry is written to a closed writer or handler.
+var ErrClosed = errors.New("slogr: closed")
+
+// ErrUnsupportedPlatform is returned by the features that the platform lacks,
+// such as the journal off linux, or the reload signal on js.
+var ErrUnsupportedPlatform = errors.New("slogr: unsupported platform")
+
+// QueuePolicy reports what a queue does with a new entry when it's full.
+type QueuePolicy int
+
+const (
+	// QueueDropNewest drops the new entry.
+	QueueDropNewest QueuePolicy = iota
+	// QueueDropOldest drops the oldest queued entry to make room for the new
+	// one.
+	QueueDropOldest
+	// QueueBlock waits until there's room for the new entry.
+	QueueBlock
+)
+
+// APIWriterOptions for an APIWriter.
+type APIWriterOptions struct {
+	// Client is the Cloud Logging client. The credentials are the ones of its
+	// connection. If Client is nil, the writer connects to the API with the
+	// Application Default Credentials, the ones of google.DefaultClient, and
+	// Close closes the connection.
+	Client loggingpb.LoggingServiceV2Client
+
+	// ProjectID is the Google Cloud Project ID of the log names.
+	ProjectID string
+
+	// LogName is the name of the entries without a name.
+	// If LogName is empty, the writer assumes "slogr".
+	LogName string
+
+	// Resource is the monitored resource of the entries without one. It's
+	// mandatory, since the API rejects the entries without a resource.
+	Resource *monitoredres.MonitoredResource
+
+	// MaxEntries is the maximum number of entries of a batch.
+	// If MaxEntries is zero, the writer assumes 1000.
+	MaxEntries int
+
+	// MaxBytes is the maximum size of a batch in bytes.
+	// If MaxBytes is zero, the writer assumes 5MiB.
+	MaxBytes int
+
+	// Interval is the maximum time an entry is kept in a batch.
+	// If Interval is zero, the writer assumes 1s.
+	Interval time.Duration
+
+	// QueueSize is the maximum number of entries waiting for a batch.
+	// If QueueSize is zero, the writer assumes 10000.
+	QueueSize int
+
+	// QueuePolicy reports what happens to an entry when the queue is full.
+	// By default, the entry is dropped. The dropped entries are counted as
+	// overflowed by the stats of the handler.
+	QueuePolicy QueuePolicy
+
+	// Backoff retries the transient errors of a batch. If Backoff is nil, the
+	// writer retries 5 times.
+	Backoff *Backoff
+
+	// OnError is called with the error of a batch that couldn't be written.
+	// If OnError is nil, the errors are only counted.
+	OnError func(error)
+}
+
+// APIWriter writes the entries to the Cloud Logging API in batches, for the
+// workloads without a logging agent. A batch is sent when it's full, either by
+// count or by size, or when its interval elapses. The stats of the handler
+// count the entries once their batch is written.
+type APIWriter struct {
+	client   loggingpb.LoggingServiceV2Client
+	project  string
+	name     string
+	resource *monitoredres.MonitoredResource
+	entries  int
+	bytes    int
+	interval time.Duration
+	policy   QueuePolicy
+	backoff  *Backoff
+	onError  func(error)
+	conn     *grpc.ClientConn
+
+	mu      sync.RWMutex
+	queue   chan apiItem
+	closed  bool
+	closing chan struct{}
+	pending sync.WaitGroup
+	done    chan struct{}
+	cancel  context.CancelFunc
+	once    sync.Once
+	dropped atomic.Int64
+	errors  atomic.Int64
+}
+
+// apiItem represents a queued entry, and the stats that count it.
+type apiItem struct {
+	entry *Entry
+	stats *stats
+}
+
+// apiEndpoint is the address of the Cloud Logging API.
+const apiEndpoint = "logging.googleapis.com:443"
+
+// apiScope is the OAuth2 scope of the writes to the Cloud Logging API.
+const apiScope = "https://www.googleapis.com/auth/logging.write"
+
+// NewAPIWriter creates an APIWriter, and starts its batching. Call Close to
+// write the queued entries.
+func NewAPIWriter(opts *APIWriterOptions) (*APIWriter, error) {
+	switch {
+	case opts.ProjectID == "":
+		return nil, errors.New("slogr: the api writer requires a project id")
+	case opts.Resource == nil:
+		return nil, errors.New("slogr: the api writer requires a monitored resource")
+	}
+
+	w := &APIWriter{
+		client:   opts.Client,
+		project:  opts.ProjectID,
+		name:     opts.LogName,
+		resource: opts.Resource,
+		entries:  opts.MaxEntries,
+		bytes:    opts.MaxBytes,
+		interval: opts.Interval,
+		policy:   opts.QueuePolicy,
+		backoff:  opts.Backoff,
+		onError:  opts.OnError,
+		closing:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+
+	if w.name == "" {
+		w.name = "slogr"
+	}
+
+	if w.entries <= 0 {
+		w.entries = 1000
+	}
+
+	if w.bytes <= 0 {
+		w.bytes = 5 << 20
+	}
+
+	if w.interval <= 0 {
+		w.interval = time.Second
+	}
+
+	if w.backoff == nil {
+		w.backoff = &Backoff{MaxAttempts: 5, Jitter: 0.2}
+	}
+
+	size := opts.QueueSize
+	// set the default queue size
+	if size <= 0 {
+		size = 10000
+	}
+
+	if w.client == nil {
+		conn, err := dialAPI(context.Background())
+		if err != nil {
+			return nil, err
+		}
+
+		w.conn = conn
+		w.client = loggingpb.NewLoggingServiceV2Client(conn)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	w.queue = make(chan apiItem, size)
+	w.cancel = cancel
+
+	go w.run(ctx)
+	// done!
+	return w, nil
+}
+
+// NewAPIHandler creates a [slog.Handler] that writes the entries to a given
+// APIWriter instead of an io.Writer. The options are the ones of NewHandler,
+// and the project of the writer is used when ProjectID is empty.
+func NewAPIHandler(w *APIWriter, opts *HandlerOptions) slog.Handler {
+	options := *opts
+	// the log names are qualified by the writer project
+	if options.ProjectID == "" {
+		options.ProjectID = w.project
+	}
+
+	return NewEntryHandler(w, &options)
+}
+
+// dialAPI connects to the Cloud Logging API with the Application Default
+// Credentials.
+func dialAPI(ctx context.Context) (*grpc.ClientConn, error) {
+	source, err := google.DefaultTokenSource(ctx, apiScope)
+	if err != nil {
+		return nil, err
+	}
+
+	return grpc.DialContext(ctx, apiEndpoint,
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(nil, "")),
+		grpc.WithPerRPCCredentials(oauth.TokenSource{TokenSource: source}),
+	)
+}
+
+// WriteEntry queues a given entry. The entry is dropped when the queue is
+// full, unless the policy is QueueBlock.
+func (w *APIWriter) WriteEntry(e *Entry) error {
+	return w.writeEntry(e, nil)
+}
+
+// writeEntry queues a given entry, which is counted by the given stats once
+// it's written or dropped. With QueueBlock, it waits until there's room for
+// the entry or the writer is closed.
+func (w *APIWriter) writeEntry(e *Entry, s *stats) error {
+	w.mu.RLock()
+	if w.closed {
+		w.mu.RUnlock()
+		s.count(e.Severity, ErrClosed)
+		return ErrClosed
+	}
+
+	// the queue is closed once the pending writes are done
+	w.pending.Add(1)
+	w.mu.RUnlock()
+
+	defer w.pending.Done()
+
+	item := apiItem{entry: e, stats: s}
+
+	if w.policy == QueueBlock {
+		select {
+		case w.queue <- item:
+			return nil
+		case <-w.closing:
+			s.count(e.Severity, ErrClosed)
+			return ErrClosed
+		}
+	}
+
+	for {
+		select {
+		case w.queue <- item:
+			return nil
+		default:
+		}
+
+		if w.policy == QueueDropNewest {
+			w.drop(item)
+			return nil
+		}
+
+		// make room for the entry
+		select {
+		case oldest := <-w.queue:
+			w.drop(oldest)
+		default:
+		}
+	}
+}
+
+// drop counts a given item that was dropped by the queue policy.
+func (w *APIWriter) drop(item apiItem) {
+	w.dropped.Add(1)
+	item.stats.overflow()
+}
+
+// Dropped returns the number of entries dropped by the queue policy.
+func (w *APIWriter) Dropped() int64 {
+	return w.dropped.Load()
+}
+
+// Errors returns the number of entries that couldn't be written.
+func (w *APIWriter) Errors() int64 {
+	return w.errors.Load()
+}
+
+// Close writes the queued entries, and stops the batching. The writes that
+// wait for room in the queue return ErrClosed. The entries still queued when
+// the context is done are lost, and the context error is returned.
+func (w *APIWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.closing)
+
+		go func() {
+			w.pending.Wait()
+			close(w.queue)
+		}()
+	}
+	w.mu.Unlock()
+
+	select {
+	case <-w.done:
+		return w.disconnect()
+	case <-ctx.Done():
+		w.cancel()
+		return errors.Join(ctx.Err(), w.disconnect())
+	}
+}
+
+// disconnect closes the connection of the writer when it created it.
+func (w *APIWriter) disconnect() error {
+	var err error
+
+	w.once.Do(func() {
+		if w.conn != nil {
+			err = w.conn.Close()
+		}
+	})
+
+	return err
+}
+
+// run collects the queued entries in batches until the queue is closed.
+func (w *APIWriter) run(ctx context.Context) {
+	defer close(w.done)
+
+	var (
+		batch []apiItem
+		size  int
+	)
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	send := func() {
+		if len(batch) > 0 {
+			w.send(ctx, batch)
+		}
+
+		batch, size = nil, 0
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			send()
+		case item, ok := <-w.queue:
+			if !ok {
+				send()
+				return
+			}
+
+			// the batch would be too large with the entry
+			n := proto.Size((*loggingpb.LogEntry)(item.entry))
+			if size+n > w.bytes && len(batch) > 0 {
+				send()
+			}
+
+			batch = append(batch, item)
+			size += n
+
+			if len(batch) >= w.entries {
+				send()
+			}
+		}
+	}
+}
+
+// send writes a given batch, one request per project of its entries, and
+// counts its entries.
+func (w *APIWriter) send(ctx context.Context, batch []apiItem) {
+	var (
+		projects []string
+		groups   = make(map[string][]apiItem)
+	)
+
+	// the entries of a project override are written to their project
+	for _, item := range batch {
+		project := w.projectOf(item.entry)
+		if _, ok := groups[project]; !ok {
+			projects = append(projects, project)
+		}
+
+		groups[project] = append(groups[project], item)
+	}
+
+	for _, project := range projects {
+		w.write(ctx, project, groups[project])
+	}
+}
+
+// write writes the entries of a given project, retrying the transient errors,
+// and counts them.
+func (w *APIWriter) write(ctx context.Context, project string, batch []apiItem) {
+	entries := make([]*loggingpb.LogEntry, 0, len(batch))
+	for _, item := range batch {
+		entries = append(entries, w.entry(item.entry))
+	}
+
+	request := &loggingpb.WriteLogEntriesRequest{
+		LogName:        w.path(project, w.name),
+		Resource:       w.resource,
+		Entries:        entries,
+		PartialSuccess: true,
+	}
+
+	err := w.backoff.Do(ctx, func() error {
+		_, err := w.client.WriteLogEntries(ctx, request)
+		return err
+	}, nil)
+
+	for _, item := range batch {
+		item.stats.count(item.entry.Severity, err)
+	}
+
+	if err != nil {
+		w.errors.Add(int64(len(batch)))
+		// report the error
+		if w.onError != nil {
+			w.onError(err)
+		}
+	}
+}
+
+// projectOf returns the project of a given entry: the one of its qualified
+// log name, or the project of the writer.
+func (w *APIWriter) projectOf(e *Entry) string {
+	if name, ok := strings.CutPrefix(e.LogName, "projects/"); ok {
+		if project, _, ok := strings.Cut(name, "/"); ok && project != "" {
+			return project
+		}
+	}
+
+	return w.project
+}
+
+// entry returns the entry in the format of the API. The short log names are
+// qualified with the project of the writer.
+func (w *APIWriter) entry(e *Entry) *loggingpb.LogEntry {
+	entry := (*loggingpb.LogEntry)(e)
+
+	if entry.LogName != "" && !strings.HasPrefix(entry.LogName, "projects/") {
+		entry = proto.Clone(entry).(*loggingpb.LogEntry)
+		entry.LogName = w.path(w.project, entry.LogName)
+	}
+
+	return entry
+}
+
+// logName implements namedWriter
+func (w *APIWriter) logName() string {
+	return w.name
+}
+
+func (w *APIWriter) path(project, name string) string {
+	// the name is escaped already when it comes from the handler
+	if value, err := url.PathUnescape(name); err == nil {
+		name = value
+	}
+
+	return "projects/" + project + "/logs/" + url.PathEscape(name)
+}
@@ -0,0 +1,246 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/genproto/googleapis/api/monitoredres"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// loggingClient is a fake Cloud Logging client that records the requests,
+// and fails them with the queued errors. It waits for release before it
+// answers when release is set.
+type loggingClient struct {
+	loggingpb.LoggingServiceV2Client
+
+	mu       sync.Mutex
+	requests []*loggingpb.WriteLogEntriesRequest
+	errs     []error
+	release  chan struct{}
+}
+
+// WriteLogEntries implements loggingpb.LoggingServiceV2Client
+func (c *loggingClient) WriteLogEntries(ctx context.Context, r *loggingpb.WriteLogEntriesRequest, _ ...grpc.CallOption) (*loggingpb.WriteLogEntriesResponse, error) {
+	if c.release != nil {
+		select {
+		case <-c.release:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.requests = append(c.requests, r)
+
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+
+	return &loggingpb.WriteLogEntriesResponse{}, nil
+}
+
+func (c *loggingClient) sizes() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var sizes []int
+	for _, request := range c.requests {
+		sizes = append(sizes, len(request.Entries))
+	}
+
+	return sizes
+}
+
+func newTestAPIWriter(t *testing.T, client *loggingClient, opts APIWriterOptions) *APIWriter {
+	t.Helper()
+
+	opts.Client = client
+	opts.ProjectID = "my-project"
+	opts.Resource = &monitoredres.MonitoredResource{Type: "global"}
+
+	if opts.Interval == 0 {
+		opts.Interval = time.Hour
+	}
+
+	writer, err := NewAPIWriter(&opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return writer
+}
+
+func TestNewAPIWriterValidation(t *testing.T) {
+	for _, opts := range []*APIWriterOptions{
+		{Resource: &monitoredres.MonitoredResource{Type: "global"}},
+		{ProjectID: "my-project"},
+	} {
+		if _, err := NewAPIWriter(opts); err == nil {
+			t.Errorf("got no error for %+v", opts)
+		}
+	}
+}
+
+func TestAPIWriterBatches(t *testing.T) {
+	client := &loggingClient{}
+	writer := newTestAPIWriter(t, client, APIWriterOptions{MaxEntries: 2})
+
+	logger := slog.New(NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+	logger.Info("first", Name("audit"))
+	logger.Info("second")
+	logger.Info("third")
+
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.sizes(); len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Fatalf("got batches of %v entries, want [2 1]", got)
+	}
+
+	request := client.requests[0]
+	if got, want := request.LogName, "projects/my-project/logs/slogr"; got != want {
+		t.Errorf("got log name %q, want %q", got, want)
+	}
+
+	if got, want := request.Entries[0].LogName, "projects/my-project/logs/audit"; got != want {
+		t.Errorf("got entry log name %q, want %q", got, want)
+	}
+
+	if request.Resource.GetType() != "global" {
+		t.Errorf("got resource %v, want global", request.Resource)
+	}
+}
+
+func TestAPIWriterStats(t *testing.T) {
+	client := &loggingClient{errs: []error{status.Error(codes.InvalidArgument, "invalid")}}
+
+	var reported []error
+	writer := newTestAPIWriter(t, client, APIWriterOptions{
+		MaxEntries: 1,
+		OnError:    func(err error) { reported = append(reported, err) },
+	})
+
+	handler := NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo}).(*Handler)
+	logger := slog.New(handler)
+	logger.Info("rejected")
+	logger.Info("written")
+
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := handler.Stats()
+	if got := stats.Emitted["INFO"]; got != 1 {
+		t.Errorf("got %d emitted entries, want 1", got)
+	}
+
+	if stats.Errors != 1 || writer.Errors() != 1 {
+		t.Errorf("got %d errors and %d writer errors, want 1", stats.Errors, writer.Errors())
+	}
+
+	if len(reported) != 1 {
+		t.Errorf("got %d reported errors, want 1", len(reported))
+	}
+}
+
+func TestAPIWriterDrop(t *testing.T) {
+	for _, policy := range []QueuePolicy{QueueDropNewest, QueueDropOldest} {
+		client := &loggingClient{release: make(chan struct{})}
+		writer := newTestAPIWriter(t, client, APIWriterOptions{
+			MaxEntries:  1,
+			QueueSize:   1,
+			QueuePolicy: policy,
+		})
+
+		handler := NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo}).(*Handler)
+		logger := slog.New(handler)
+		logger.Info("sending")
+		// wait until the first entry is taken from the queue
+		for len(writer.queue) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		logger.Info("queued")
+		logger.Info("dropped")
+
+		if got := handler.Stats().Emitted["INFO"]; got != 0 {
+			t.Errorf("policy %v: got %d emitted entries before the batch, want 0", policy, got)
+		}
+
+		close(client.release)
+
+		if err := writer.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		stats := handler.Stats()
+		if stats.Overflowed != 1 || writer.Dropped() != 1 {
+			t.Errorf("policy %v: got %d overflowed and %d dropped, want 1", policy, stats.Overflowed, writer.Dropped())
+		}
+
+		if got := stats.Emitted["INFO"]; got != 2 {
+			t.Errorf("policy %v: got %d emitted entries, want 2", policy, got)
+		}
+	}
+}
+
+func TestAPIWriterCloseWithBlockedWrites(t *testing.T) {
+	client := &loggingClient{release: make(chan struct{})}
+	writer := newTestAPIWriter(t, client, APIWriterOptions{
+		MaxEntries:  1,
+		QueueSize:   1,
+		QueuePolicy: QueueBlock,
+	})
+
+	handler := NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo})
+
+	errs := make(chan error, 3)
+	for index := 0; index < 3; index++ {
+		go func() {
+			errs <- handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "blocked", 0))
+		}()
+	}
+
+	// the first entry is sent, the second is queued, and the third waits
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := writer.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context error", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Close took %v despite the context", elapsed)
+	}
+
+	var closed int
+	for index := 0; index < 3; index++ {
+		if err := <-errs; errors.Is(err, ErrClosed) {
+			closed++
+		}
+	}
+
+	if closed != 1 {
+		t.Fatalf("got %d writes that failed with ErrClosed, want 1", closed)
+	}
+
+	if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v after Close, want ErrClosed", err)
+	}
+}
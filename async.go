@@ -0,0 +1,273 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+)
+
+// AsyncOptions for the async handler. A zero AsyncOptions consists entirely
+// of default values.
+type AsyncOptions struct {
+	// QueueSize is the maximum number of records waiting to be handled.
+	// If QueueSize is zero, the handler assumes 1024.
+	QueueSize int
+
+	// QueuePolicy reports what happens to a record when the queue is full.
+	// By default, the record is dropped. The dropped records are counted as
+	// overflowed by the stats of the handler, or of the slogr Handler it
+	// wraps.
+	QueuePolicy QueuePolicy
+}
+
+// AsyncHandler implements a [slog.Handler] that handles the records on a
+// worker goroutine, so the callers don't wait for the encoding and the write.
+// The records are handled in the order they were logged. An AsyncHandler and
+// its clones share the queue.
+type AsyncHandler struct {
+	inner slog.Handler
+	queue *asyncQueue
+}
+
+// asyncItem represents a queued record, or a flush marker when flush is set.
+type asyncItem struct {
+	ctx     context.Context
+	handler slog.Handler
+	record  slog.Record
+	flush   chan struct{}
+}
+
+type asyncQueue struct {
+	mu      sync.RWMutex
+	items   chan asyncItem
+	closed  bool
+	closing chan struct{}
+	pending sync.WaitGroup
+	done    chan struct{}
+	cancel  chan struct{}
+	once    sync.Once
+	busy    sync.Mutex
+	policy  QueuePolicy
+	dropped atomic.Int64
+	stats   *stats
+}
+
+// NewAsyncHandler creates an AsyncHandler that handles the records with a
+// given handler on a worker goroutine. Call Close to handle the queued
+// records before the program exits.
+func NewAsyncHandler(inner slog.Handler, opts AsyncOptions) *AsyncHandler {
+	size := opts.QueueSize
+	// set the default queue size
+	if size <= 0 {
+		size = 1024
+	}
+
+	queue := &asyncQueue{
+		items:   make(chan asyncItem, size),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+		cancel:  make(chan struct{}),
+		policy:  opts.QueuePolicy,
+	}
+
+	// the drops are counted by the stats of the handler, or of the one it wraps
+	queue.stats = statsOf(inner)
+
+	go queue.run()
+	// done!
+	return &AsyncHandler{inner: inner, queue: queue}
+}
+
+// Enabled implements slog.Handler
+func (h *AsyncHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler. The record is queued, and the errors of the
+// handler are lost. ErrClosed is returned after Close.
+func (h *AsyncHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.queue.push(asyncItem{
+		// the context outlives the call
+		ctx:     context.WithoutCancel(ctx),
+		handler: h.inner,
+		record:  r.Clone(),
+	})
+}
+
+// WithAttrs implements slog.Handler
+func (h *AsyncHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &AsyncHandler{inner: h.inner.WithAttrs(attrs), queue: h.queue}
+}
+
+// WithGroup implements slog.Handler
+func (h *AsyncHandler) WithGroup(name string) slog.Handler {
+	return &AsyncHandler{inner: h.inner.WithGroup(name), queue: h.queue}
+}
+
+// handlerStats implements statsHolder
+func (h *AsyncHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+// Flush waits until the records queued before the call are handled, then
+// flushes the handler when it implements Flusher.
+func (h *AsyncHandler) Flush() error {
+	item := asyncItem{flush: make(chan struct{})}
+
+	if !h.queue.enter() {
+		<-h.queue.done
+		return h.flush()
+	}
+
+	// the marker is never dropped, and Close handles the queue anyway
+	select {
+	case h.queue.items <- item:
+	case <-h.queue.closing:
+	}
+	h.queue.pending.Done()
+
+	select {
+	case <-item.flush:
+	case <-h.queue.done:
+	}
+
+	return h.flush()
+}
+
+func (h *AsyncHandler) flush() error {
+	if flusher, ok := h.inner.(Flusher); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// Close handles the queued records, stops the worker, and flushes the handler
+// when it implements Flusher. The records that wait for room in the queue
+// return ErrClosed. The records still queued when the context is done are
+// lost, and the context error is returned.
+func (h *AsyncHandler) Close(ctx context.Context) error {
+	h.queue.mu.Lock()
+	if !h.queue.closed {
+		h.queue.closed = true
+		close(h.queue.closing)
+
+		go func() {
+			h.queue.pending.Wait()
+			close(h.queue.items)
+		}()
+	}
+	h.queue.mu.Unlock()
+
+	select {
+	case <-h.queue.done:
+		return h.flush()
+	case <-ctx.Done():
+		h.queue.stop()
+		return ctx.Err()
+	}
+}
+
+// Dropped returns the number of records dropped by the queue policy.
+func (h *AsyncHandler) Dropped() int64 {
+	return h.queue.dropped.Load()
+}
+
+// enter reports whether the queue is open, and then counts the caller as a
+// pending push until it calls pending.Done. Close closes the items once the
+// pending pushes are done, so they never wait while holding the lock.
+func (q *asyncQueue) enter() bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+
+	if q.closed {
+		return false
+	}
+
+	q.pending.Add(1)
+	return true
+}
+
+func (q *asyncQueue) push(item asyncItem) error {
+	if !q.enter() {
+		return ErrClosed
+	}
+
+	defer q.pending.Done()
+
+	if q.policy == QueueBlock {
+		select {
+		case q.items <- item:
+		case <-q.closing:
+			return ErrClosed
+		}
+
+		return nil
+	}
+
+	for {
+		select {
+		case q.items <- item:
+			return nil
+		default:
+		}
+
+		if q.policy == QueueDropNewest {
+			q.drop()
+			return nil
+		}
+
+		// make room for the record
+		select {
+		case oldest := <-q.items:
+			if oldest.flush == nil {
+				q.drop()
+				continue
+			}
+
+			// the records before the marker are handled or in progress
+			q.busy.Lock()
+			q.busy.Unlock()
+			close(oldest.flush)
+		default:
+		}
+	}
+}
+
+func (q *asyncQueue) drop() {
+	q.dropped.Add(1)
+	// report the drop to the stats too
+	q.stats.overflow()
+}
+
+func (q *asyncQueue) stop() {
+	q.once.Do(func() {
+		close(q.cancel)
+	})
+}
+
+// run handles the queued records until the queue is closed or stopped.
+func (q *asyncQueue) run() {
+	defer close(q.done)
+
+	for {
+		select {
+		case <-q.cancel:
+			return
+		case item, ok := <-q.items:
+			if !ok {
+				return
+			}
+
+			if item.flush != nil {
+				close(item.flush)
+				continue
+			}
+
+			q.busy.Lock()
+			_ = item.handler.Handle(item.ctx, item.record)
+			q.busy.Unlock()
+		}
+	}
+}
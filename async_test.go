@@ -0,0 +1,168 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// blockingHandler is a handler that waits for release before it handles a
+// record.
+type blockingHandler struct {
+	slog.Handler
+	release chan struct{}
+}
+
+// Handle implements slog.Handler
+func (h *blockingHandler) Handle(ctx context.Context, r slog.Record) error {
+	<-h.release
+	return h.Handler.Handle(ctx, r)
+}
+
+// handlerStats implements statsHolder
+func (h *blockingHandler) handlerStats() *stats {
+	return statsOf(h.Handler)
+}
+
+func TestAsyncHandlerOrder(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	handler := NewAsyncHandler(NewHandler(buffer, &HandlerOptions{Level: slog.LevelInfo}), AsyncOptions{})
+	defer handler.Close(context.Background())
+
+	logger := slog.New(handler)
+	for index := 0; index < 100; index++ {
+		logger.Info(fmt.Sprint(index))
+	}
+
+	if err := handler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := decodeEntries(t, buffer.Bytes())
+	if len(entries) != 100 {
+		t.Fatalf("got %d entries, want 100", len(entries))
+	}
+
+	for index, entry := range entries {
+		if got := entry["message"]; got != fmt.Sprint(index) {
+			t.Fatalf("got entry %v at %d", got, index)
+		}
+	}
+}
+
+func TestAsyncHandlerClose(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	handler := NewAsyncHandler(NewHandler(buffer, &HandlerOptions{Level: slog.LevelInfo}), AsyncOptions{})
+
+	logger := slog.New(handler.WithAttrs([]slog.Attr{slog.String("user", "alice")}))
+	logger.Info("queued")
+
+	if err := handler.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := payloadOf(single(t, decodeEntries(t, buffer.Bytes())))["user"]; got != "alice" {
+		t.Fatalf("got user %v, want alice", got)
+	}
+
+	if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "late", 0)); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got %v after Close, want ErrClosed", err)
+	}
+
+	// Flush returns after Close
+	if err := handler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAsyncHandlerDrop(t *testing.T) {
+	for _, policy := range []QueuePolicy{QueueDropNewest, QueueDropOldest} {
+		var (
+			buffer  = &bytes.Buffer{}
+			inner   = NewHandler(buffer, &HandlerOptions{Level: slog.LevelInfo}).(*Handler)
+			release = make(chan struct{})
+		)
+
+		// the drops are counted through the wrappers
+		wrapped := NewSamplingHandler(&blockingHandler{Handler: inner, release: release}, nil)
+
+		handler := NewAsyncHandler(wrapped, AsyncOptions{QueueSize: 1, QueuePolicy: policy})
+		logger := slog.New(handler)
+		logger.Info("handled")
+		// wait until the first record is taken from the queue
+		for len(handler.queue.items) > 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		logger.Info("queued")
+		logger.Info("dropped")
+		close(release)
+
+		if err := handler.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		if got := handler.Dropped(); got != 1 {
+			t.Errorf("policy %v: got %d dropped records, want 1", policy, got)
+		}
+
+		if got := inner.Stats().Overflowed; got != 1 {
+			t.Errorf("policy %v: got %d overflowed records, want 1", policy, got)
+		}
+
+		if got := len(decodeEntries(t, buffer.Bytes())); got != 2 {
+			t.Errorf("policy %v: got %d entries, want 2", policy, got)
+		}
+	}
+}
+
+func TestAsyncHandlerCloseWithBlockedRecords(t *testing.T) {
+	var (
+		inner   = NewHandler(&bytes.Buffer{}, &HandlerOptions{Level: slog.LevelInfo})
+		release = make(chan struct{})
+	)
+
+	defer close(release)
+
+	handler := NewAsyncHandler(&blockingHandler{Handler: inner, release: release}, AsyncOptions{
+		QueueSize:   1,
+		QueuePolicy: QueueBlock,
+	})
+
+	errs := make(chan error, 3)
+	for index := 0; index < 3; index++ {
+		go func() {
+			errs <- handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "blocked", 0))
+		}()
+	}
+
+	// the first record is handled, the second is queued, and the third waits
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if err := handler.Close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context error", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Close took %v despite the context", elapsed)
+	}
+
+	var closed int
+	for index := 0; index < 3; index++ {
+		if err := <-errs; errors.Is(err, ErrClosed) {
+			closed++
+		}
+	}
+
+	if closed != 1 {
+		t.Fatalf("got %d records that failed with ErrClosed, want 1", closed)
+	}
+}
@@ -0,0 +1,148 @@
+package slogr
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/genproto/googleapis/cloud/audit"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// personal reports the fragments of the audit metadata keys that look like
+// personal data.
+var personal = []string{
+	"address",
+	"birth",
+	"card",
+	"dob",
+	"email",
+	"iban",
+	"passport",
+	"password",
+	"phone",
+	"ssn",
+}
+
+// Audit returns an Attr for an audit event.
+// The caller must not subsequently mutate the
+// argument map.
+//
+// The handler emits the event as an AuditLog proto payload with at least
+// notice severity under the audit log name, unless the record has a name.
+func Audit(principal, method, resource string, err error, metadata map[string]any) slog.Attr {
+	value := &audit.AuditLog{
+		MethodName:   method,
+		ResourceName: resource,
+		AuthenticationInfo: &audit.AuthenticationInfo{
+			PrincipalEmail: principal,
+		},
+	}
+
+	if err != nil {
+		value.Status = status.Convert(err).Proto()
+	}
+
+	if len(metadata) > 0 {
+		value.Metadata = &structpb.Struct{
+			Fields: make(map[string]*structpb.Value),
+		}
+
+		for k, v := range metadata {
+			value.Metadata.Fields[k] = structValue(v)
+		}
+	}
+
+	return slog.Attr{
+		Key:   AuditKey,
+		Value: slog.AnyValue(value),
+	}
+}
+
+func (h *Handler) audit(r slog.Record) *audit.AuditLog {
+	var value *audit.AuditLog
+
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == AuditKey && isAuditLog(attr) {
+			value = attr.Value.Any().(*audit.AuditLog)
+			return false
+		}
+
+		return true
+	})
+
+	return value
+}
+
+// isAuditLog reports whether a given attribute holds the audit log of Audit.
+func isAuditLog(attr slog.Attr) bool {
+	value, ok := attr.Value.Any().(*audit.AuditLog)
+	return ok && value != nil
+}
+
+func (h *Handler) auditPayload(value *audit.AuditLog, message string, props map[string]interface{}) (interface{}, error) {
+	value = proto.Clone(value).(*audit.AuditLog)
+	// set the service name if not present
+	if value.ServiceName == "" {
+		value.ServiceName = h.service
+	}
+
+	if message != "" {
+		props["message"] = message
+	}
+
+	if value.Metadata == nil && len(props) > 0 {
+		value.Metadata = &structpb.Struct{
+			Fields: make(map[string]*structpb.Value),
+		}
+	}
+
+	// the explicit metadata wins over the record attributes
+	for k, v := range props {
+		if _, ok := value.Metadata.Fields[k]; !ok {
+			value.Metadata.Fields[k] = structValue(v)
+		}
+	}
+
+	if value.Metadata != nil {
+		for k := range value.Metadata.Fields {
+			if !h.allowed[k] && isPersonal(k) {
+				delete(value.Metadata.Fields, k)
+			}
+		}
+	}
+
+	payload, err := anypb.New(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &loggingpb.LogEntry_ProtoPayload{
+		ProtoPayload: payload,
+	}, nil
+}
+
+func isPersonal(key string) bool {
+	key = strings.ToLower(key)
+
+	for _, fragment := range personal {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func structValue(v any) *structpb.Value {
+	value, err := structpb.NewValue(v)
+	if err != nil {
+		value = structpb.NewStringValue(fmt.Sprintf("%v", v))
+	}
+
+	return value
+}
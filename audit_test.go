@@ -0,0 +1,57 @@
+package slogr
+
+import (
+	"errors"
+	"log/slog"
+	"testing"
+)
+
+func TestAudit(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("audited", Audit("ana", "List", "books", errors.New("denied"), nil))
+
+	entry := single(t, entries())
+	if got := entry["severity"]; got != "NOTICE" {
+		t.Errorf("got severity %v, want NOTICE", got)
+	}
+
+	payload := payloadOf(entry)
+	if got := payload["@type"]; got != "type.googleapis.com/google.cloud.audit.AuditLog" {
+		t.Errorf("got @type %v, want the AuditLog", got)
+	}
+
+	if metadata, _ := payload["metadata"].(map[string]any); metadata[FieldLogName] != AuditKey {
+		t.Errorf("got metadata %v, want the audit log name", metadata)
+	}
+}
+
+func TestAuditOfOtherValues(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	// any other value is an attribute of the payload
+	logger.Info("kept", slog.String(AuditKey, "login"))
+	logger.Info("kept", slog.Group(AuditKey, slog.String("actor", "ana")))
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	for index, entry := range collection {
+		if got := entry["severity"]; got != "INFO" {
+			t.Errorf("entry %d: got severity %v, want INFO", index, got)
+		}
+
+		if got, ok := payloadOf(entry)[FieldLogName]; ok {
+			t.Errorf("entry %d: got log name %v, want none", index, got)
+		}
+	}
+
+	if got := payloadOf(collection[0])[AuditKey]; got != "login" {
+		t.Errorf("got audit %v, want login", got)
+	}
+
+	if got, _ := payloadOf(collection[1])[AuditKey].(map[string]any); got["actor"] != "ana" {
+		t.Errorf("got audit %v, want the group", got)
+	}
+}
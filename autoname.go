@@ -0,0 +1,103 @@
+package slogr
+
+import (
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// AutoNameEnv is the environment variable that overrides the derived names,
+// e.g. for the forks that keep the import path of the upstream module.
+const AutoNameEnv = "SLOGR_AUTO_NAME"
+
+// autoNames caches the derived names per call site.
+var autoNames sync.Map
+
+// AutoName returns an Attr for a log name derived from the import path of the
+// calling package.
+func AutoName() slog.Attr {
+	return Name(autoName(1))
+}
+
+// AutoProducer returns an operation producer derived from the import path of
+// the calling package, for StartOperation.
+func AutoProducer() string {
+	return autoName(1)
+}
+
+// autoName returns the import path of the package of a given caller. It
+// returns the binary name when the caller cannot be mapped to a package.
+func autoName(skip int) string {
+	if name := os.Getenv(AutoNameEnv); name != "" {
+		return name
+	}
+
+	pc, _, _, ok := runtime.Caller(skip + 1)
+	if !ok {
+		return binaryName()
+	}
+
+	if name, ok := autoNames.Load(pc); ok {
+		return name.(string)
+	}
+
+	name := packageName(pc)
+	// cache the name of the call site
+	autoNames.Store(pc, name)
+	// done!
+	return name
+}
+
+func packageName(pc uintptr) string {
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return binaryName()
+	}
+
+	name := importPath(fn.Name())
+	if name == "main" {
+		if info, ok := debug.ReadBuildInfo(); ok && info.Path != "" {
+			name = info.Path
+		}
+	}
+
+	if name == "" || name == "main" {
+		return binaryName()
+	}
+
+	return name
+}
+
+// importPath returns the import path of a given function name, e.g.
+// github.com/ralch/slogr of github.com/ralch/slogr.(*Handler).Handle.
+func importPath(name string) string {
+	slash := strings.LastIndex(name, "/") + 1
+	if index := strings.Index(name[slash:], "."); index >= 0 {
+		name = name[:slash+index]
+	}
+
+	// the dots of the last element are escaped, e.g. gopkg.in/yaml%2ev3
+	if path, err := url.PathUnescape(name); err == nil {
+		name = path
+	}
+
+	// the vendored packages have the import path of the vendoring module
+	if index := strings.LastIndex(name, "/vendor/"); index >= 0 {
+		name = name[index+len("/vendor/"):]
+	}
+
+	return name
+}
+
+func binaryName() string {
+	if path, err := os.Executable(); err == nil {
+		return filepath.Base(path)
+	}
+
+	return filepath.Base(os.Args[0])
+}
@@ -0,0 +1,46 @@
+package slogr
+
+import (
+	"context"
+	"testing"
+)
+
+func TestImportPath(t *testing.T) {
+	for name, want := range map[string]string{
+		"github.com/ralch/slogr.(*Handler).Handle":         "github.com/ralch/slogr",
+		"github.com/ralch/slogr.TestImportPath.func1":      "github.com/ralch/slogr",
+		"gopkg.in/yaml%2ev3.Marshal":                       "gopkg.in/yaml.v3",
+		"example.com/foo%2ebar.(*T).Run":                   "example.com/foo.bar",
+		"example.com/app/vendor/github.com/pkg/errors.New": "github.com/pkg/errors",
+		"example.com/pkg.Map[...]":                         "example.com/pkg",
+		"main.main":                                        "main",
+	} {
+		if got := importPath(name); got != want {
+			t.Errorf("got %q of %q, want %q", got, name, want)
+		}
+	}
+}
+
+func TestAutoName(t *testing.T) {
+	if got := autoName(0); got != "github.com/ralch/slogr" {
+		t.Fatalf("got name %q, want github.com/ralch/slogr", got)
+	}
+
+	t.Setenv(AutoNameEnv, "fork")
+
+	if got := autoName(0); got != "fork" {
+		t.Fatalf("got name %q, want the name of %s", got, AutoNameEnv)
+	}
+}
+
+func TestAutoProducer(t *testing.T) {
+	ctx, _ := StartOperation(context.Background(), AutoProducer())
+	if got := OperationFromContext(ctx).GetProducer(); got != "github.com/ralch/slogr" {
+		t.Fatalf("got producer %q, want github.com/ralch/slogr", got)
+	}
+
+	ctx, _ = StartOperation(context.Background(), "")
+	if got := OperationFromContext(ctx).GetProducer(); got != "" {
+		t.Fatalf("got producer %q, want none", got)
+	}
+}
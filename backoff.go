@@ -0,0 +1,166 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// RetryableCodes reports the gRPC codes that IsRetryable classifies as
+// transient.
+var RetryableCodes = map[codes.Code]bool{
+	codes.Unavailable:       true,
+	codes.DeadlineExceeded:  true,
+	codes.ResourceExhausted: true,
+	codes.Aborted:           true,
+	codes.Internal:          true,
+}
+
+// RetryableStatusCodes reports the HTTP status codes that IsRetryable
+// classifies as transient.
+var RetryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+}
+
+// IsRetryable reports whether a given error is transient. The gRPC status
+// errors are classified by RetryableCodes, and the errors that have a
+// StatusCode() int method are classified by RetryableStatusCodes. Context
+// errors are never retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	type StatusCoder interface {
+		StatusCode() int
+	}
+
+	var coder StatusCoder
+	// classify the http errors
+	if errors.As(err, &coder) {
+		return RetryableStatusCodes[coder.StatusCode()]
+	}
+
+	if s, ok := status.FromError(err); ok {
+		return RetryableCodes[s.Code()]
+	}
+
+	return false
+}
+
+// Backoff represents a bounded exponential backoff with jitter. A zero
+// Backoff consists entirely of default values.
+type Backoff struct {
+	// Initial is the delay before the first retry.
+	// If Initial is zero, the backoff assumes 100ms.
+	Initial time.Duration
+
+	// Max is the maximum delay between the attempts.
+	// If Max is zero, the backoff assumes 30s.
+	Max time.Duration
+
+	// Multiplier is the factor by which the delay grows after every attempt.
+	// If Multiplier is less than 1, the backoff assumes 2.
+	Multiplier float64
+
+	// Jitter is the fraction of the delay that is randomized between 0 and 1.
+	// A zero Jitter disables the randomization.
+	Jitter float64
+
+	// MaxAttempts is the maximum number of attempts.
+	// If MaxAttempts is zero, the attempts are only bounded by the context.
+	MaxAttempts int
+
+	// Random returns a random number in [0.0,1.0) for the jitter.
+	// If Random is nil, the backoff uses math/rand.
+	Random func() float64
+}
+
+// Delay returns the delay before a given retry, starting from zero.
+func (b *Backoff) Delay(retry int) time.Duration {
+	var (
+		initial    = b.Initial
+		limit      = b.Max
+		multiplier = b.Multiplier
+		random     = b.Random
+	)
+
+	if initial <= 0 {
+		initial = 100 * time.Millisecond
+	}
+
+	if limit <= 0 {
+		limit = 30 * time.Second
+	}
+
+	if multiplier < 1 {
+		multiplier = 2
+	}
+
+	if random == nil {
+		random = rand.Float64
+	}
+
+	delay := math.Min(float64(initial)*math.Pow(multiplier, float64(retry)), float64(limit))
+	// randomize the delay
+	if jitter := math.Min(math.Max(b.Jitter, 0), 1); jitter > 0 {
+		delay -= delay * jitter * random()
+	}
+
+	return time.Duration(delay)
+}
+
+// Do calls fn until it succeeds, returns an error that isRetryable reports as
+// permanent, the attempts are exhausted or the context is done. If isRetryable
+// is nil, the backoff uses IsRetryable. Do returns the last error of fn, or
+// the context error when the context is done before the first attempt.
+func (b *Backoff) Do(ctx context.Context, fn func() error, isRetryable func(error) bool) error {
+	if isRetryable == nil {
+		isRetryable = IsRetryable
+	}
+
+	for retry := 0; ; retry++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil || !isRetryable(err) {
+			return err
+		}
+
+		if b.MaxAttempts > 0 && retry+1 >= b.MaxAttempts {
+			return err
+		}
+
+		delay := b.Delay(retry)
+		// there's no point in sleeping past the deadline
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package slogr
+
+import (
+	"context"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+const (
+	// BaggageWildcard represents the BaggageLabels item that adds every
+	// baggage member as a label.
+	BaggageWildcard = "*"
+
+	// baggageMembers is the maximum number of labels added by the wildcard.
+	baggageMembers = 16
+	// baggageValueSize is the maximum length in bytes of a label value added
+	// by the wildcard.
+	baggageValueSize = 128
+)
+
+// baggageLabels adds the baggage members of a given context to a given labels
+// map. The existing labels are never replaced.
+func (h *Handler) baggageLabels(ctx context.Context, kv map[string]string) {
+	if len(h.baggage) == 0 {
+		return
+	}
+
+	bag := baggage.FromContext(ctx)
+	// an empty baggage has no members
+	if bag.Len() == 0 {
+		return
+	}
+
+	set := func(key, value string) {
+		if _, ok := kv[key]; !ok {
+			kv[key] = value
+		}
+	}
+
+	for _, key := range h.baggage {
+		if key == BaggageWildcard {
+			for index, member := range bag.Members() {
+				if index >= baggageMembers {
+					break
+				}
+
+				value := member.Value()
+				// truncate the value at a rune boundary
+				if len(value) > baggageValueSize {
+					size := baggageValueSize
+					for size > 0 && !utf8.RuneStart(value[size]) {
+						size--
+					}
+
+					value = value[:size]
+				}
+
+				set(member.Key(), value)
+			}
+
+			continue
+		}
+
+		if member := bag.Member(key); member.Key() != "" {
+			set(member.Key(), member.Value())
+		}
+	}
+}
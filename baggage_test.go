@@ -0,0 +1,79 @@
+package slogr
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/baggage"
+)
+
+// withBaggage returns a context with a baggage of given members.
+func withBaggage(t *testing.T, kv ...string) context.Context {
+	t.Helper()
+
+	var members []baggage.Member
+
+	for index := 0; index < len(kv); index += 2 {
+		member, err := baggage.NewMember(kv[index], url.QueryEscape(kv[index+1]))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		members = append(members, member)
+	}
+
+	bag, err := baggage.New(members...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return baggage.ContextWithBaggage(context.Background(), bag)
+}
+
+func TestBaggageLabelsWildcard(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		value string
+		want  string
+	}{
+		{"short", "alpha", "alpha"},
+		{"ascii", strings.Repeat("a", 200), strings.Repeat("a", 128)},
+		// the two byte runes end at the size
+		{"accents", strings.Repeat("é", 100), strings.Repeat("é", 64)},
+		// the three byte runes are cut before the size
+		{"euro", strings.Repeat("€", 100), strings.Repeat("€", 42)},
+		{"mixed", "a" + strings.Repeat("日本", 50), "a" + strings.Repeat("日本", 21)},
+	} {
+		logger, entries := capture(t, &HandlerOptions{BaggageLabels: []string{BaggageWildcard}})
+		logger.InfoContext(withBaggage(t, "tenant", tc.value), "bagged")
+
+		labels, _ := single(t, entries())[FieldLabels].(map[string]any)
+
+		got, _ := labels["tenant"].(string)
+		if got != tc.want {
+			t.Errorf("%s: got label %q, want %q", tc.name, got, tc.want)
+		}
+
+		if !utf8.ValidString(got) || len(got) > 128 {
+			t.Errorf("%s: got an invalid label of %d bytes", tc.name, len(got))
+		}
+	}
+}
+
+func TestBaggageLabelsOfLabels(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{BaggageLabels: []string{"tenant", "region"}})
+
+	ctx := withBaggage(t, "tenant", "alpha", "region", strings.Repeat("ü", 100), "user", "bob")
+	logger.InfoContext(ctx, "bagged", Label("tenant", "explicit"))
+
+	labels, _ := single(t, entries())[FieldLabels].(map[string]any)
+	// the named members are neither truncated nor replace the labels
+	for key, want := range map[string]any{"tenant": "explicit", "region": strings.Repeat("ü", 100), "user": nil} {
+		if got := labels[key]; got != want {
+			t.Errorf("got label %s %v, want %v", key, got, want)
+		}
+	}
+}
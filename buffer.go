@@ -0,0 +1,188 @@
+package slogr
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Flusher is implemented by the writers and the handlers that buffer the
+// entries, such as the buffered writer and the async handler.
+type Flusher interface {
+	// Flush writes the buffered entries.
+	Flush() error
+}
+
+// Buffered represents a buffered writer. It's safe for concurrent use.
+type Buffered struct {
+	mu     sync.Mutex
+	writer io.Writer
+	buffer *bufio.Writer
+}
+
+// BufferedWriter creates a Buffered that buffers up to size bytes before
+// writing them to w. Call Flush or Close before the program exits, or use
+// AutoFlush, since the buffered entries are lost otherwise.
+//
+// The async handler buffers the records already, so there's no need for a
+// buffered writer behind it. With both, Flush of the async handler flushes
+// the writer too.
+func BufferedWriter(w io.Writer, size int) *Buffered {
+	return &Buffered{
+		writer: w,
+		buffer: bufio.NewWriterSize(w, size),
+	}
+}
+
+// Write implements io.Writer
+func (b *Buffered) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.Write(p)
+}
+
+// Flush writes the buffered data.
+func (b *Buffered) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buffer.Flush()
+}
+
+// Close writes the buffered data, and closes the writer when it implements
+// io.Closer.
+func (b *Buffered) Close() error {
+	err := b.Flush()
+	// close the underlying writer
+	if closer, ok := b.writer.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+
+	return err
+}
+
+// Flush flushes the writers of the handler that implement Flusher, such as a
+// buffered writer.
+func (h *Handler) Flush() error {
+	var errs []error
+
+	for _, writer := range h.writers() {
+		if flusher, ok := writer.(Flusher); ok {
+			errs = append(errs, flusher.Flush())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Close flushes the writers of the handler, and closes the writers of the
+// log names (NameWriters) that implement io.Closer, except os.Stdout and
+// os.Stderr. The main writer and the error writer belong to the caller, who
+// closes them. The handler and its clones share the writers, so it's meant to
+// be called once at exit.
+func (h *Handler) Close() error {
+	errs := []error{h.Flush()}
+
+	for _, writer := range h.routeWriters() {
+		if closer, ok := writer.(io.Closer); ok {
+			errs = append(errs, closer.Close())
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// routeWriters returns the distinct writers of the log names, without the
+// writers of the caller.
+func (h *Handler) routeWriters() []io.Writer {
+	var collection []io.Writer
+
+	for _, w := range h.writers() {
+		switch w {
+		case h.writer, h.errOut, os.Stdout, os.Stderr:
+			continue
+		}
+
+		collection = append(collection, w)
+	}
+
+	return collection
+}
+
+// writers returns the distinct writers of the handler.
+func (h *Handler) writers() []io.Writer {
+	var collection []io.Writer
+
+	add := func(w io.Writer) {
+		for _, item := range collection {
+			if item == w {
+				return
+			}
+		}
+
+		collection = append(collection, w)
+	}
+
+	for _, w := range []io.Writer{h.writer, h.errOut} {
+		if w != nil {
+			add(w)
+		}
+	}
+
+	for _, route := range h.routes {
+		add(route.writer)
+	}
+
+	return collection
+}
+
+// AutoFlush flushes a given flusher every interval, and on SIGINT and
+// SIGTERM. An interval of zero or less disables the periodic flush. AutoFlush
+// only flushes on the signals: it doesn't terminate the program, so handle
+// them in the program as well, for example with signal.NotifyContext. Call the
+// returned function to stop it.
+func AutoFlush(f Flusher, interval time.Duration) (stop func()) {
+	var (
+		signals = make(chan os.Signal, 1)
+		done    = make(chan struct{})
+		once    sync.Once
+	)
+
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		// a nil channel never receives
+		var tick <-chan time.Time
+
+		if interval > 0 {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			tick = ticker.C
+		}
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-tick:
+				_ = f.Flush()
+			case <-signals:
+				_ = f.Flush()
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+		})
+	}
+}
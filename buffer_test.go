@@ -0,0 +1,179 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// closeBuffer is a buffer that records whether it was closed.
+type closeBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+// Close implements io.Closer
+func (b *closeBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// countFlusher counts the calls of Flush.
+type countFlusher struct {
+	mu    sync.Mutex
+	count int
+}
+
+// Flush implements Flusher
+func (f *countFlusher) Flush() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.count++
+	return nil
+}
+
+func (f *countFlusher) calls() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.count
+}
+
+func TestBufferedWriter(t *testing.T) {
+	target := &closeBuffer{}
+	writer := BufferedWriter(target, 4096)
+
+	if _, err := writer.Write([]byte("hello\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if target.Len() != 0 {
+		t.Fatalf("got %q before Flush, want nothing", target.String())
+	}
+
+	if err := writer.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := target.String(); got != "hello\n" {
+		t.Fatalf("got %q, want %q", got, "hello\n")
+	}
+
+	if _, err := writer.Write([]byte("world\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := target.String(); got != "hello\nworld\n" {
+		t.Fatalf("got %q, want %q", got, "hello\nworld\n")
+	}
+
+	if !target.closed {
+		t.Fatal("the writer is not closed")
+	}
+}
+
+func TestHandlerFlush(t *testing.T) {
+	target := &bytes.Buffer{}
+	logger := NewLogger(BufferedWriter(target, 4096), &HandlerOptions{Level: slog.LevelInfo})
+	logger.Info("buffered")
+
+	if target.Len() != 0 {
+		t.Fatalf("got %q before Flush, want nothing", target.String())
+	}
+
+	if err := logger.Handler().(*Handler).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := single(t, decodeEntries(t, target.Bytes()))
+	if got := entry["message"]; got != "buffered" {
+		t.Fatalf("got message %v, want buffered", got)
+	}
+}
+
+func TestHandlerClose(t *testing.T) {
+	var (
+		main   = &closeBuffer{}
+		errOut = &closeBuffer{}
+		audit  = &closeBuffer{}
+	)
+
+	handler := NewHandler(main, &HandlerOptions{
+		Level:       slog.LevelInfo,
+		ErrorWriter: errOut,
+		NameWriters: map[string]io.Writer{"audit": audit},
+	})
+
+	if err := handler.(*Handler).Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if main.closed {
+		t.Error("the main writer is closed")
+	}
+
+	if errOut.closed {
+		t.Error("the error writer is closed")
+	}
+
+	if !audit.closed {
+		t.Error("the writer of the log name is not closed")
+	}
+}
+
+func TestAsyncHandlerFlushesBufferedWriter(t *testing.T) {
+	target := &bytes.Buffer{}
+	inner := NewHandler(BufferedWriter(target, 4096), &HandlerOptions{Level: slog.LevelInfo})
+
+	handler := NewAsyncHandler(inner, AsyncOptions{})
+	defer handler.Close(context.Background())
+
+	slog.New(handler).Info("queued")
+
+	if err := handler.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	single(t, decodeEntries(t, target.Bytes()))
+}
+
+func TestAutoFlush(t *testing.T) {
+	flusher := &countFlusher{}
+
+	stop := AutoFlush(flusher, time.Millisecond)
+	defer stop()
+
+	deadline := time.Now().Add(time.Second)
+	for flusher.calls() < 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("the flusher is not flushed periodically")
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAutoFlushWithoutInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		flusher := &countFlusher{}
+
+		stop := AutoFlush(flusher, interval)
+		time.Sleep(10 * time.Millisecond)
+		stop()
+		// stop is idempotent
+		stop()
+
+		if got := flusher.calls(); got != 0 {
+			t.Fatalf("interval %v: got %d flushes, want 0", interval, got)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+)
+
+// The ANSI colors of the console handler.
+const (
+	colorReset   = "\x1b[0m"
+	colorFaint   = "\x1b[2m"
+	colorRed     = "\x1b[31m"
+	colorGreen   = "\x1b[32m"
+	colorYellow  = "\x1b[33m"
+	colorBlue    = "\x1b[34m"
+	colorMagenta = "\x1b[35m"
+	colorCyan    = "\x1b[36m"
+	colorBoldRed = "\x1b[1;31m"
+)
+
+// NewConsoleHandler creates a [slog.Handler] that writes one human-readable
+// line per entry to w, for local development. The line is the time, the
+// severity, the message, and the payload fields as key=value pairs. The
+// labels, the operation and the HTTP request are written compactly, and the
+// trace id is shortened. The options are the ones of NewHandler.
+//
+// The severities and the errors are colored when w is a terminal, unless the
+// NO_COLOR environment variable is set.
+func NewConsoleHandler(w io.Writer, opts *HandlerOptions) slog.Handler {
+	h := NewHandler(w, opts).(*Handler)
+	h.encoder = &consoleEncoder{color: isTerminal(w) && os.Getenv("NO_COLOR") == ""}
+	// done!
+	return h
+}
+
+// isTerminal reports whether a given writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	file, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// consoleEncoder encodes the entries of the console handler.
+type consoleEncoder struct {
+	color bool
+}
+
+// Encode implements Encoder.
+func (x *consoleEncoder) Encode(w io.Writer, e *Entry) error {
+	buffer := &bytes.Buffer{}
+
+	if e.Timestamp != nil {
+		if timestamp := e.Timestamp.AsTime(); !timestamp.IsZero() {
+			x.write(buffer, colorFaint, timestamp.Local().Format("15:04:05.000"))
+			buffer.WriteByte(' ')
+		}
+	}
+
+	severity := e.Severity.String()
+	// the names are aligned
+	x.write(buffer, consoleColor(e.Severity), fmt.Sprintf("%-8s", severity))
+
+	if location := e.SourceLocation; location != nil {
+		buffer.WriteByte(' ')
+		x.write(buffer, colorFaint, fmt.Sprintf("%s:%d", location.File[strings.LastIndex(location.File, "/")+1:], location.Line))
+	}
+
+	if name := e.LogName; name != "" {
+		name = name[strings.LastIndex(name, "/")+1:]
+		buffer.WriteByte(' ')
+		x.write(buffer, colorBlue, "["+name+"]")
+	}
+
+	var fields map[string]any
+
+	switch payload := e.Payload.(type) {
+	case *loggingpb.LogEntry_TextPayload:
+		buffer.WriteString(" " + payload.TextPayload)
+	case *loggingpb.LogEntry_JsonPayload:
+		fields = payload.JsonPayload.AsMap()
+		// the message goes first
+		if message, ok := fields[FieldPayloadMessage].(string); ok {
+			buffer.WriteString(" " + message)
+			delete(fields, FieldPayloadMessage)
+		}
+	case *loggingpb.LogEntry_ProtoPayload:
+		buffer.WriteString(" " + payload.ProtoPayload.GetTypeUrl())
+	}
+
+	x.fields(buffer, "", fields)
+
+	if len(e.Labels) > 0 {
+		keys := make([]string, 0, len(e.Labels))
+		for key := range e.Labels {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for index, key := range keys {
+			keys[index] = key + "=" + consoleQuote(e.Labels[key])
+		}
+
+		x.pair(buffer, LabelKey, "{"+strings.Join(keys, " ")+"}", "")
+	}
+
+	if operation := e.Operation; operation != nil {
+		value := operation.Id
+		if operation.Producer != "" {
+			value = operation.Producer + "/" + value
+		}
+
+		switch {
+		case operation.First:
+			value += "(first)"
+		case operation.Last:
+			value += "(last)"
+		}
+
+		x.pair(buffer, OperationKey, consoleQuote(value), "")
+	}
+
+	if request := e.HttpRequest; request != nil {
+		value := request.RequestMethod + " " + request.RequestUrl
+		if request.Status != 0 {
+			value += " " + strconv.Itoa(int(request.Status))
+		}
+
+		if request.Latency != nil {
+			value += " " + request.Latency.AsDuration().String()
+		}
+
+		x.pair(buffer, RequestKey, consoleQuote(value), "")
+	}
+
+	if trace := e.Trace; trace != "" {
+		trace = trace[strings.LastIndex(trace, "/")+1:]
+		// the first bytes are enough to tell the traces apart
+		if len(trace) > 8 {
+			trace = trace[:8]
+		}
+
+		x.pair(buffer, "trace", trace, "")
+	}
+
+	buffer.WriteByte('\n')
+
+	_, err := w.Write(buffer.Bytes())
+	return err
+}
+
+// fields writes the fields of a given map as sorted key=value pairs. The keys
+// of the nested maps are prefixed by the key of their parent, as the groups of
+// slog.TextHandler.
+func (x *consoleEncoder) fields(buffer *bytes.Buffer, prefix string, fields map[string]any) {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		switch value := fields[key].(type) {
+		case map[string]any:
+			x.fields(buffer, prefix+key+".", value)
+		default:
+			color := ""
+			// the errors stand out
+			if key == ErrorKey {
+				color = colorRed
+			}
+
+			x.pair(buffer, prefix+key, consoleValue(value), color)
+		}
+	}
+}
+
+// pair writes a given key=value pair, with the value in a given color.
+func (x *consoleEncoder) pair(buffer *bytes.Buffer, key, value, color string) {
+	buffer.WriteByte(' ')
+	x.write(buffer, colorFaint, key+"=")
+	x.write(buffer, color, value)
+}
+
+// write writes a given text in a given color.
+func (x *consoleEncoder) write(buffer *bytes.Buffer, color, text string) {
+	if !x.color || color == "" {
+		buffer.WriteString(text)
+		return
+	}
+
+	buffer.WriteString(color + text + colorReset)
+}
+
+// consoleColor returns the color of a given severity.
+func consoleColor(severity ltype.LogSeverity) string {
+	switch {
+	case severity >= ltype.LogSeverity_CRITICAL:
+		return colorBoldRed
+	case severity >= ltype.LogSeverity_ERROR:
+		return colorRed
+	case severity >= ltype.LogSeverity_WARNING:
+		return colorYellow
+	case severity >= ltype.LogSeverity_NOTICE:
+		return colorCyan
+	case severity >= ltype.LogSeverity_INFO:
+		return colorGreen
+	default:
+		return colorMagenta
+	}
+}
+
+// consoleValue returns the text of a given payload value.
+func consoleValue(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "<nil>"
+	case string:
+		return consoleQuote(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return consoleQuote(fmt.Sprint(value))
+		}
+
+		return string(data)
+	}
+}
+
+// consoleQuote quotes a given string when it's empty, or when it has spaces,
+// quotes or other characters that would make the line ambiguous.
+func consoleQuote(value string) string {
+	if value == "" {
+		return `""`
+	}
+
+	for _, r := range value {
+		if unicode.IsSpace(r) || r == '"' || r == '=' || !unicode.IsPrint(r) {
+			return strconv.Quote(value)
+		}
+	}
+
+	return value
+}
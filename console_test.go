@@ -0,0 +1,135 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/slogtest"
+	"time"
+)
+
+// consoleTime matches the time of a console line.
+var consoleTime = regexp.MustCompile(`^\d\d:\d\d:\d\d\.\d{3}$`)
+
+// parseConsole returns the fields of a console line, with the groups as nested
+// maps, and the time, the severity and the message under the keys of slog.
+func parseConsole(t *testing.T, line string) map[string]any {
+	t.Helper()
+
+	var (
+		fields  = make(map[string]any)
+		message []string
+	)
+
+	for index := 0; line != ""; index++ {
+		line = strings.TrimLeft(line, " ")
+
+		token, rest, _ := strings.Cut(line, " ")
+		key, value, ok := strings.Cut(token, "=")
+		// the quoted values might have spaces
+		if ok && strings.HasPrefix(value, `"`) {
+			quoted, err := strconv.QuotedPrefix(line[len(key)+1:])
+			if err != nil {
+				t.Fatalf("invalid value of %s in %q: %v", key, line, err)
+			}
+
+			rest = line[len(key)+1+len(quoted):]
+
+			if value, err = strconv.Unquote(quoted); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		line = rest
+
+		switch {
+		case index == 0 && consoleTime.MatchString(token):
+			fields[slog.TimeKey] = token
+			index--
+		case index == 0:
+			fields[slog.LevelKey] = token
+		case !ok:
+			message = append(message, token)
+		default:
+			group := fields
+			// the keys of the groups are prefixed
+			parts := strings.Split(key, ".")
+			for _, name := range parts[:len(parts)-1] {
+				next, _ := group[name].(map[string]any)
+				if next == nil {
+					next = make(map[string]any)
+					group[name] = next
+				}
+
+				group = next
+			}
+
+			group[parts[len(parts)-1]] = value
+		}
+	}
+
+	if len(message) > 0 {
+		fields[slog.MessageKey] = strings.Join(message, " ")
+	}
+
+	return fields
+}
+
+func TestConsoleHandlerConformance(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	handler := NewConsoleHandler(buffer, &HandlerOptions{Level: slog.LevelInfo})
+
+	results := func() []map[string]any {
+		var collection []map[string]any
+
+		for _, line := range strings.Split(strings.TrimSuffix(buffer.String(), "\n"), "\n") {
+			collection = append(collection, parseConsole(t, line))
+		}
+
+		return collection
+	}
+
+	if err := slogtest.TestHandler(handler, results); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConsoleHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	logger := slog.New(NewConsoleHandler(buffer, &HandlerOptions{Level: slog.LevelInfo, ProjectID: "my-project"}))
+	ctx := context.Background()
+
+	logger.WarnContext(ctx, "disk almost full",
+		Name("storage"),
+		Label("env", "prod"),
+		OperationStart("op-1", "books"),
+		Error(errors.New("no space")),
+		slog.String("path", "/var/lib"),
+		slog.Duration("retry", time.Second),
+	)
+
+	line := strings.TrimSuffix(buffer.String(), "\n")
+	for _, want := range []string{
+		"WARNING  [storage] disk almost full",
+		`error="no space"`,
+		"path=/var/lib",
+		"retry=1s",
+		"labels={env=prod}",
+		"operation=books/op-1(first)",
+	} {
+		if !strings.Contains(line, want) {
+			t.Errorf("got %q, want %q in it", line, want)
+		}
+	}
+
+	if strings.Contains(line, "\x1b[") {
+		t.Errorf("got the colors of a terminal in %q", line)
+	}
+}
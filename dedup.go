@@ -0,0 +1,240 @@
+package slogr
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RepeatCountKey represents the key of the number of the suppressed entries
+// of a dedup summary.
+const RepeatCountKey = "repeat_count"
+
+// DedupOptions for the dedup handler. A zero DedupOptions consists entirely of
+// default values.
+type DedupOptions struct {
+	// Window is the time during which the identical entries are counted
+	// instead of written. If Window is zero, the handler assumes 1m.
+	Window time.Duration
+
+	// Exempt reports the level at or above which the entries are never
+	// suppressed. If Exempt is nil, every level can be suppressed.
+	Exempt slog.Leveler
+
+	// Volatile reports the attribute keys that are ignored when comparing
+	// entries, in addition to the time and duration attributes.
+	Volatile []string
+}
+
+// NewDedupHandler creates a [slog.Handler] that writes the first of the
+// identical entries seen within a window to a given handler, and counts the
+// others. The entries are identical when they have the same message, level
+// and attributes, except the volatile ones, and come from handlers with the
+// same attributes and groups. When the window closes, a summary entry with
+// RepeatCountKey and the time of the first and last entries is written for
+// the suppressed ones. Use Flush to write the pending summaries.
+func NewDedupHandler(inner slog.Handler, opts *DedupOptions) slog.Handler {
+	if opts == nil {
+		opts = &DedupOptions{}
+	}
+
+	window := opts.Window
+	// set the default window
+	if window <= 0 {
+		window = time.Minute
+	}
+
+	volatile := make(map[string]bool)
+	// prepare the volatile keys
+	for _, key := range opts.Volatile {
+		volatile[key] = true
+	}
+
+	return &dedupHandler{
+		inner:    inner,
+		window:   window,
+		exempt:   opts.Exempt,
+		volatile: volatile,
+		state:    &dedupState{entries: make(map[uint64]*dedupEntry)},
+	}
+}
+
+// dedupEntry represents the identical entries of a window.
+type dedupEntry struct {
+	handler slog.Handler
+	record  slog.Record
+	count   int
+	last    time.Time
+	timer   *time.Timer
+}
+
+// dedupState represents the windows shared by a handler and its clones.
+type dedupState struct {
+	mu      sync.Mutex
+	entries map[uint64]*dedupEntry
+}
+
+type dedupHandler struct {
+	inner    slog.Handler
+	window   time.Duration
+	exempt   slog.Leveler
+	volatile map[string]bool
+	state    *dedupState
+	// scope represents the attributes and the groups of the handler, so the
+	// records of the handlers with another scope never collide
+	scope []string
+	group string
+}
+
+// Enabled implements slog.Handler
+func (h *dedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *dedupHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.exempt != nil && r.Level >= h.exempt.Level() {
+		return h.inner.Handle(ctx, r)
+	}
+
+	key := h.hash(r)
+
+	h.state.mu.Lock()
+	if entry, ok := h.state.entries[key]; ok {
+		entry.count++
+		entry.last = r.Time
+		h.state.mu.Unlock()
+		return nil
+	}
+
+	h.state.entries[key] = &dedupEntry{
+		handler: h.inner,
+		record:  r.Clone(),
+		timer: time.AfterFunc(h.window, func() {
+			h.state.summarize(key)
+		}),
+	}
+	h.state.mu.Unlock()
+
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *dedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	c.scope = slices.Clip(h.scope)
+
+	for _, attr := range attrs {
+		c.scope = h.appendAttr(c.scope, h.group, attr)
+	}
+
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *dedupHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	c.scope = append(slices.Clip(h.scope), "group", h.group+name)
+	c.group = h.group + name + "."
+	return &c
+}
+
+// handlerStats implements statsHolder
+func (h *dedupHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+// Flush writes the summaries of the pending windows.
+func (h *dedupHandler) Flush() error {
+	h.state.mu.Lock()
+	keys := make([]uint64, 0, len(h.state.entries))
+	// collect the pending windows
+	for key, entry := range h.state.entries {
+		entry.timer.Stop()
+		keys = append(keys, key)
+	}
+	h.state.mu.Unlock()
+
+	for _, key := range keys {
+		h.state.summarize(key)
+	}
+
+	return nil
+}
+
+// summarize closes the window of a given key, and writes its summary when
+// entries were suppressed.
+func (s *dedupState) summarize(key uint64) {
+	s.mu.Lock()
+	entry, ok := s.entries[key]
+	delete(s.entries, key)
+	s.mu.Unlock()
+
+	if !ok || entry.count == 0 {
+		return
+	}
+
+	r := slog.NewRecord(entry.last, entry.record.Level, entry.record.Message, entry.record.PC)
+	// copy the attributes of the first entry
+	entry.record.Attrs(func(attr slog.Attr) bool {
+		r.AddAttrs(attr)
+		return true
+	})
+
+	r.AddAttrs(
+		slog.Int(RepeatCountKey, entry.count),
+		slog.Time("first_timestamp", entry.record.Time),
+		slog.Time("last_timestamp", entry.last),
+	)
+
+	_ = entry.handler.Handle(context.Background(), r)
+}
+
+// hash returns the hash of the scope of the handler, and of the message, the
+// level and the non-volatile attributes of a given record.
+func (h *dedupHandler) hash(r slog.Record) uint64 {
+	digest := fnv.New64a()
+
+	values := append(slices.Clip(h.scope), r.Message, strconv.Itoa(int(r.Level)))
+	// the attributes of the record are in the open groups
+	r.Attrs(func(a slog.Attr) bool {
+		values = h.appendAttr(values, h.group, a)
+		return true
+	})
+
+	for _, value := range values {
+		digest.Write([]byte(value))
+		digest.Write([]byte{0})
+	}
+
+	return digest.Sum64()
+}
+
+// appendAttr appends the keys and the values of a given attribute, without the
+// volatile ones, in a deterministic order.
+func (h *dedupHandler) appendAttr(values []string, prefix string, a slog.Attr) []string {
+	value := a.Value.Resolve()
+
+	switch {
+	case h.volatile[a.Key]:
+	case value.Kind() == slog.KindTime, value.Kind() == slog.KindDuration:
+	case value.Kind() == slog.KindGroup:
+		for _, item := range value.Group() {
+			values = h.appendAttr(values, prefix+a.Key+".", item)
+		}
+	default:
+		values = append(values, prefix+a.Key, value.String())
+	}
+
+	return values
+}
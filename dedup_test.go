@@ -0,0 +1,118 @@
+package slogr
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestDedupHandler(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	handler := NewDedupHandler(logger.Handler(), nil)
+	dedup := slog.New(handler)
+
+	for index := 0; index < 3; index++ {
+		dedup.Info("connection refused", "host", "db", "elapsed", time.Duration(index))
+	}
+
+	if got := len(entries()); got != 1 {
+		t.Fatalf("got %d entries before Flush, want 1", got)
+	}
+
+	if err := handler.(Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := entries()
+	if got := len(collection); got != 2 {
+		t.Fatalf("got %d entries, want 2", got)
+	}
+
+	summary := payloadOf(collection[1])
+	if got := summary[RepeatCountKey]; got != 2.0 {
+		t.Errorf("got repeat count %v, want 2", got)
+	}
+
+	if got := summary["host"]; got != "db" {
+		t.Errorf("got host %v, want db", got)
+	}
+
+	for _, key := range []string{"first_timestamp", "last_timestamp"} {
+		if _, ok := summary[key]; !ok {
+			t.Errorf("got no %s", key)
+		}
+	}
+}
+
+func TestDedupHandlerVolatile(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	dedup := slog.New(NewDedupHandler(logger.Handler(), &DedupOptions{Volatile: []string{"attempt"}}))
+	dedup.Info("retry", "attempt", 1)
+	dedup.Info("retry", "attempt", 2)
+	dedup.Info("retry", "host", "db")
+
+	if got := len(entries()); got != 2 {
+		t.Fatalf("got %d entries, want 2", got)
+	}
+}
+
+func TestDedupHandlerExempt(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	dedup := slog.New(NewDedupHandler(logger.Handler(), &DedupOptions{Exempt: slog.LevelError}))
+	dedup.Error("failed")
+	dedup.Error("failed")
+
+	if got := len(entries()); got != 2 {
+		t.Fatalf("got %d entries, want 2", got)
+	}
+}
+
+func TestDedupHandlerScope(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	handler := NewDedupHandler(logger.Handler(), nil)
+	dedup := slog.New(handler)
+
+	var (
+		call   = dedup.WithGroup("call")
+		result = dedup.WithGroup("result")
+		user   = dedup.With("user", "alice")
+	)
+
+	for index := 0; index < 2; index++ {
+		call.Info("done", "status", "ok")
+		result.Info("done", "status", "ok")
+		user.Info("done", "status", "ok")
+	}
+
+	if got := len(entries()); got != 3 {
+		t.Fatalf("got %d entries, want 3", got)
+	}
+
+	if err := handler.(Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	var groups []string
+	// every summary is written by the handler of its entries
+	for _, entry := range entries()[3:] {
+		payload := payloadOf(entry)
+
+		for _, group := range []string{"call", "result"} {
+			if item, ok := payload[group].(map[string]any); ok && item[RepeatCountKey] != nil {
+				groups = append(groups, group)
+			}
+		}
+
+		if payload["user"] == "alice" {
+			groups = append(groups, "user")
+		}
+	}
+
+	if len(groups) != 3 {
+		t.Fatalf("got the summaries of %v, want call, result and user", groups)
+	}
+}
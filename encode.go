@@ -0,0 +1,258 @@
+package slogr
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// The field names of an entry in the logging agent format.
+const (
+	FieldSeverity       = "severity"
+	FieldHTTPRequest    = "httpRequest"
+	FieldMessage        = "message"
+	FieldTime           = "time"
+	FieldInsertID       = "logging.googleapis.com/insertId"
+	FieldLabels         = "logging.googleapis.com/labels"
+	FieldOperation      = "logging.googleapis.com/operation"
+	FieldSourceLocation = "logging.googleapis.com/sourceLocation"
+	FieldSpanID         = "logging.googleapis.com/spanId"
+	FieldTrace          = "logging.googleapis.com/trace"
+	FieldTraceSampled   = "logging.googleapis.com/trace_sampled"
+	FieldPayloadMessage = "logging.googleapis.com/message"
+	FieldLogName        = "logName"
+)
+
+// Encoding reports how the entries are encoded.
+type Encoding int
+
+const (
+	// EncodingJSON encodes an entry as a single line of JSON.
+	EncodingJSON Encoding = iota
+	// EncodingProto encodes an entry as a LogEntry proto, prefixed by its
+	// length as a varint. Use ReadEntries to decode the entries.
+	EncodingProto
+)
+
+// Profile reports the field names of the JSON encoding of the entries.
+type Profile int
+
+const (
+	// ProfileGCP uses the field names of the logging agent format.
+	ProfileGCP Profile = iota
+	// ProfileGeneric uses plain field names, such as labels and trace_id, for
+	// the backends other than Cloud Logging. The payload fields are written at
+	// the top level, and the payload message is the message field.
+	ProfileGeneric
+)
+
+// genericFields are the field names of ProfileGeneric.
+var genericFields = map[string]string{
+	FieldSeverity:       "severity",
+	FieldHTTPRequest:    "http_request",
+	FieldMessage:        "message",
+	FieldTime:           "time",
+	FieldInsertID:       "insert_id",
+	FieldLabels:         "labels",
+	FieldOperation:      "operation",
+	FieldSourceLocation: "caller",
+	FieldSpanID:         "span_id",
+	FieldTrace:          "trace_id",
+	FieldTraceSampled:   "trace_sampled",
+	FieldLogName:        "log_name",
+}
+
+// Encoder encodes the entries of a handler. The handler writes the encoding of
+// an entry with a single call to its writer.
+type Encoder interface {
+	// Encode writes a given entry to w.
+	Encode(w io.Writer, e *Entry) error
+}
+
+// EncoderFunc is an adapter to use a function as an Encoder.
+type EncoderFunc func(w io.Writer, e *Entry) error
+
+// Encode implements Encoder.
+func (fn EncoderFunc) Encode(w io.Writer, e *Entry) error {
+	return fn(w, e)
+}
+
+// JSONEncoder encodes the entries as single lines of JSON in the logging agent
+// format. It's the default encoder of the handler.
+type JSONEncoder struct {
+	// When Indent is true, the encoder adds an indent to the JSON output.
+	Indent bool
+
+	// When EscapeHTML is true, the encoder escapes the HTML characters
+	// in JSON strings.
+	EscapeHTML bool
+
+	// When SeverityNumber is true, the encoder writes the severity as a
+	// number instead of its name.
+	SeverityNumber bool
+
+	// When LogName is true, the encoder writes the short log names as a
+	// top-level field. The full resource names are left out, since the
+	// logging agent derives the log name from the stream.
+	LogName bool
+
+	// Encoding reports how the entries are encoded. With EncodingProto, the
+	// JSON options are ignored. By default, the entries are encoded in JSON.
+	Encoding Encoding
+
+	// Profile reports the field names. By default, the encoder uses the
+	// field names of the logging agent format.
+	Profile Profile
+}
+
+// Encode implements Encoder.
+func (x *JSONEncoder) Encode(w io.Writer, e *Entry) error {
+	return EncodeEntry(w, e, EncodeOptions{
+		Indent:         x.Indent,
+		EscapeHTML:     x.EscapeHTML,
+		SeverityNumber: x.SeverityNumber,
+		LogName:        x.LogName && !strings.HasPrefix(e.LogName, "projects/"),
+		Encoding:       x.Encoding,
+		Profile:        x.Profile,
+	})
+}
+
+// EncodeOptions for EncodeEntry. A zero EncodeOptions encodes a compact entry
+// in the logging agent format.
+type EncodeOptions struct {
+	// When Indent is true, the encoder adds an indent to the JSON output.
+	Indent bool
+
+	// When EscapeHTML is true, the encoder escapes the HTML characters
+	// in JSON strings.
+	EscapeHTML bool
+
+	// When SeverityNumber is true, the encoder writes the severity as a
+	// number instead of its name.
+	SeverityNumber bool
+
+	// When LogName is true, the encoder writes the log name as a top-level
+	// field. The logging agent derives the log name from the stream instead.
+	LogName bool
+
+	// When ProtoFormat is true, the encoder writes the entry as a LogEntry
+	// in the protobuf JSON format instead of the logging agent format.
+	ProtoFormat bool
+
+	// When LegacyFormat is true, the encoder writes the entry in the legacy
+	// Stackdriver format, with the message as textPayload and the labels and
+	// the operation in a metadata object.
+	//
+	// Deprecated: the legacy format is kept only for the sunset of its last
+	// consumer and will be removed in the next major version.
+	LegacyFormat bool
+
+	// ServiceName is the service of the metadata serviceContext in the legacy
+	// Stackdriver format.
+	ServiceName string
+
+	// Encoding reports how the entry is encoded. With EncodingProto, the JSON
+	// options are ignored. By default, the entry is encoded in JSON.
+	Encoding Encoding
+
+	// Profile reports the field names of the JSON encoding. By default, the
+	// encoder uses the field names of the logging agent format.
+	Profile Profile
+}
+
+// EncodeEntry writes a given entry to w as a single line of JSON, or as a
+// length-delimited proto with EncodingProto.
+func EncodeEntry(w io.Writer, e *Entry, opts EncodeOptions) error {
+	var value interface{}
+
+	switch {
+	case opts.Encoding == EncodingProto:
+		_, err := protodelim.MarshalTo(w, (*loggingpb.LogEntry)(e))
+		return err
+	case opts.ProtoFormat:
+		data, err := protojson.Marshal((*loggingpb.LogEntry)(e))
+		if err != nil {
+			return err
+		}
+
+		value = json.RawMessage(data)
+	case opts.LegacyFormat:
+		attributes, err := e.legacyFields(opts)
+		if err != nil {
+			return err
+		}
+
+		value = attributes
+	default:
+		attributes, err := e.fields(opts)
+		if err != nil {
+			return err
+		}
+
+		value = attributes
+	}
+
+	return encode(w, value, opts)
+}
+
+// encode writes a given value to w as a single line of JSON.
+func encode(w io.Writer, value interface{}, opts EncodeOptions) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetEscapeHTML(opts.EscapeHTML)
+	// enables the pretty format
+	if opts.Indent {
+		encoder.SetIndent("", "  ")
+	}
+
+	return encoder.Encode(value)
+}
+
+// EntryReader reads the entries written with EncodingProto.
+type EntryReader struct {
+	reader *bufio.Reader
+	entry  *Entry
+	err    error
+}
+
+// ReadEntries returns an EntryReader of the entries of a given reader.
+func ReadEntries(r io.Reader) *EntryReader {
+	return &EntryReader{reader: bufio.NewReader(r)}
+}
+
+// Next reads the next entry, and reports whether there's one. It returns false
+// at the end of the input or on an error, see Err.
+func (r *EntryReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	entry := &loggingpb.LogEntry{}
+	// read the entry
+	if err := protodelim.UnmarshalFrom(r.reader, entry); err != nil {
+		if !errors.Is(err, io.EOF) {
+			r.err = err
+		}
+
+		r.entry = nil
+		return false
+	}
+
+	r.entry = (*Entry)(entry)
+	return true
+}
+
+// Entry returns the entry read by Next.
+func (r *EntryReader) Entry() *Entry {
+	return r.entry
+}
+
+// Err returns the error that stopped Next, if any.
+func (r *EntryReader) Err() error {
+	return r.err
+}
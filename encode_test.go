@@ -0,0 +1,373 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestJSONEncoderIsTheDefault(t *testing.T) {
+	for _, opts := range []HandlerOptions{
+		{},
+		{NameField: NameFieldTopLevel},
+		{Profile: ProfileGeneric},
+		{AddIndent: true},
+	} {
+		var (
+			defaults = &bytes.Buffer{}
+			explicit = &bytes.Buffer{}
+			record   = slog.NewRecord(time.Now(), slog.LevelInfo, "encoded", 0)
+		)
+
+		record.AddAttrs(Name("audit"), slog.Int("count", 2))
+
+		opts.Level = slog.LevelInfo
+		if err := NewHandler(defaults, &opts).Handle(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+
+		opts.Encoder = &JSONEncoder{
+			Indent:     opts.AddIndent,
+			EscapeHTML: true,
+			LogName:    opts.NameField == NameFieldTopLevel,
+			Profile:    opts.Profile,
+		}
+
+		if err := NewHandler(explicit, &opts).Handle(context.Background(), record); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := explicit.String(), defaults.String(); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+}
+
+func TestJSONEncoderLogName(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	logger := slog.New(NewHandler(buffer, &HandlerOptions{
+		Level:   slog.LevelInfo,
+		Encoder: &JSONEncoder{LogName: true},
+	}))
+
+	logger.Info("named", Name("audit"))
+
+	if got := single(t, decodeEntries(t, buffer.Bytes()))[FieldLogName]; got != "audit" {
+		t.Fatalf("got log name %v, want audit", got)
+	}
+}
+
+func TestJSONEncoderProto(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	logger := slog.New(NewHandler(buffer, &HandlerOptions{
+		Level:   slog.LevelInfo,
+		Encoder: &JSONEncoder{Encoding: EncodingProto},
+	}))
+
+	logger.Info("first")
+	logger.Info("second")
+
+	var messages []string
+
+	reader := ReadEntries(buffer)
+	for reader.Next() {
+		messages = append(messages, reader.Entry().GetPayload().(string))
+	}
+
+	if err := reader.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 || messages[0] != "first" || messages[1] != "second" {
+		t.Fatalf("got %v, want [first second]", messages)
+	}
+}
+
+func TestEncodingProto(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		opts  HandlerOptions
+		proto bool
+	}{
+		{"default", HandlerOptions{}, false},
+		{"json", HandlerOptions{Encoding: EncodingJSON}, false},
+		{"proto", HandlerOptions{Encoding: EncodingProto}, true},
+		// the JSON options are ignored
+		{"proto indent", HandlerOptions{Encoding: EncodingProto, AddIndent: true}, true},
+	} {
+		buffer := &bytes.Buffer{}
+
+		tc.opts.Level = slog.LevelInfo
+		tc.opts.ProjectID = "my-project"
+
+		handler := NewHandler(buffer, &tc.opts).(*Handler)
+
+		ctx := trace.ContextWithSpanContext(context.Background(), knownSpanContext(t))
+		record := slog.NewRecord(time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC), slog.LevelWarn, "encoded", 0)
+		record.AddAttrs(Name("audit"), slog.Int("count", 2), Label("env", "test"))
+
+		want := handler.Entry(ctx, record)
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+
+		if !tc.proto {
+			entry := single(t, decodeEntries(t, buffer.Bytes()))
+			if got := payloadOf(entry)["count"]; got != float64(2) {
+				t.Errorf("%s: got count %v, want 2", tc.name, got)
+			}
+
+			continue
+		}
+
+		var entries []*Entry
+		// the entries are read back as they were written
+		reader := ReadEntries(buffer)
+		for reader.Next() {
+			entries = append(entries, reader.Entry())
+		}
+
+		if err := reader.Err(); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if len(entries) != 1 {
+			t.Fatalf("%s: got %d entries, want 1", tc.name, len(entries))
+		}
+
+		if got := entries[0]; !proto.Equal((*loggingpb.LogEntry)(got), (*loggingpb.LogEntry)(want)) {
+			t.Errorf("%s: got entry %v, want %v", tc.name, got, want)
+		}
+	}
+}
+
+func TestReadEntriesTruncated(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	// write two entries, and cut the second one
+	for _, entry := range []*Entry{builtEntry(), builtEntry()} {
+		if err := EncodeEntry(buffer, entry, EncodeOptions{Encoding: EncodingProto}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data := buffer.Bytes()
+
+	reader := ReadEntries(bytes.NewReader(data[:len(data)-3]))
+	if !reader.Next() || reader.Entry().InsertId != "insert-42" {
+		t.Fatalf("got no first entry, want insert-42")
+	}
+
+	if reader.Next() || reader.Entry() != nil {
+		t.Errorf("got a truncated entry")
+	}
+
+	if reader.Err() == nil {
+		t.Errorf("got no error, want the truncated entry error")
+	}
+
+	// an empty input has no entries and no error
+	reader = ReadEntries(&bytes.Buffer{})
+	if reader.Next() || reader.Err() != nil {
+		t.Errorf("got an entry or an error %v of an empty input", reader.Err())
+	}
+}
+
+func TestEncoderFunc(t *testing.T) {
+	var entries []*Entry
+
+	logger := slog.New(NewHandler(io.Discard, &HandlerOptions{
+		Level: slog.LevelInfo,
+		Encoder: EncoderFunc(func(_ io.Writer, e *Entry) error {
+			entries = append(entries, e)
+			return nil
+		}),
+	}))
+
+	logger.Warn("captured")
+
+	if len(entries) != 1 || entries[0].GetPayload() != "captured" {
+		t.Fatalf("got %v, want the captured entry", entries)
+	}
+}
+
+// update makes the golden tests write the golden files.
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+func TestProfileGolden(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "https://example.com/books?id=1", nil)
+	request.Header.Set("User-Agent", "slogr")
+	request.RemoteAddr = "192.0.2.1:1234"
+
+	record := slog.NewRecord(time.Date(2023, 9, 1, 12, 30, 0, 0, time.UTC), slog.LevelWarn, "golden", 0)
+	record.AddAttrs(
+		Name("books"),
+		Label("env", "prod"),
+		Request(request, WithLatency(1500*time.Millisecond)),
+		OperationStart("op-1", "books"),
+		slog.Int("count", 2),
+		slog.Group("call", slog.String("method", "List")),
+	)
+
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContextOf(1))
+
+	for name, profile := range map[string]Profile{
+		"gcp":     ProfileGCP,
+		"generic": ProfileGeneric,
+	} {
+		buffer := &bytes.Buffer{}
+
+		handler := NewHandler(buffer, &HandlerOptions{
+			Level:     slog.LevelInfo,
+			ProjectID: "my-project",
+			AddIndent: true,
+			Profile:   profile,
+		})
+
+		if err := handler.Handle(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join("testdata", "profile_"+name+".golden")
+		if *update {
+			if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buffer.String(); got != string(want) {
+			t.Errorf("got %s profile:\n%s\nwant:\n%s", name, got, want)
+		}
+	}
+}
+
+func TestEncodeEntryLegacyGolden(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		attrs   []slog.Attr
+		service string
+	}{
+		{"text", nil, ""},
+		{"metadata", []slog.Attr{Label("env", "prod"), OperationStart("op-1", "books")}, "books"},
+		// the message of a JSON payload is the textPayload
+		{"payload", []slog.Attr{slog.Int("count", 2), OperationEnd("op-1", "books")}, "books"},
+	} {
+		record := slog.NewRecord(time.Date(2023, 9, 1, 12, 30, 0, 0, time.UTC), slog.LevelWarn, "legacy", 0)
+		record.AddAttrs(tc.attrs...)
+
+		entry, err := RecordToEntry(context.Background(), record, &HandlerOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buffer := &bytes.Buffer{}
+		if err := EncodeEntry(buffer, entry, EncodeOptions{Indent: true, LegacyFormat: true, ServiceName: tc.service}); err != nil {
+			t.Fatal(err)
+		}
+
+		path := filepath.Join("testdata", "legacy_"+tc.name+".golden")
+		if *update {
+			if err := os.WriteFile(path, buffer.Bytes(), 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		want, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := buffer.String(); got != string(want) {
+			t.Errorf("got %s entry:\n%s\nwant:\n%s", tc.name, got, want)
+		}
+	}
+}
+
+func TestEncodeEntryLegacyFormat(t *testing.T) {
+	record := slog.NewRecord(time.Date(2023, 9, 1, 12, 30, 0, 0, time.UTC), slog.LevelWarn, "legacy", 0)
+	record.AddAttrs(Label("env", "prod"), OperationStart("op-1", "books"), slog.Int("count", 2))
+
+	entry, err := RecordToEntry(context.Background(), record, &HandlerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	modern := &bytes.Buffer{}
+	if err := EncodeEntry(modern, entry, EncodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	legacy := &bytes.Buffer{}
+	if err := EncodeEntry(legacy, entry, EncodeOptions{LegacyFormat: true, ServiceName: "books"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := single(t, decodeEntries(t, modern.Bytes()))[FieldLabels]; got == nil {
+		t.Fatalf("got no labels in the modern format %s", modern)
+	}
+
+	got := single(t, decodeEntries(t, legacy.Bytes()))
+	for key, want := range map[string]any{
+		"textPayload": "legacy",
+		"severity":    "WARNING",
+		"timestamp":   "2023-09-01T12:30:00Z",
+	} {
+		if got[key] != want {
+			t.Errorf("got %s %v, want %v", key, got[key], want)
+		}
+	}
+
+	metadata, _ := got["metadata"].(map[string]any)
+	if labels, _ := metadata["labels"].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("got metadata %v, want the labels", metadata)
+	}
+
+	if operation, _ := metadata["operation"].(map[string]any); operation["id"] != "op-1" || operation["first"] != true {
+		t.Errorf("got metadata %v, want the operation", metadata)
+	}
+
+	if service, _ := metadata["serviceContext"].(map[string]any); service["service"] != "books" {
+		t.Errorf("got metadata %v, want the service", metadata)
+	}
+
+	for _, key := range []string{"message", FieldLabels, FieldOperation} {
+		if _, ok := got[key]; ok {
+			t.Errorf("got the modern field %s in the legacy format", key)
+		}
+	}
+}
+
+func TestEncodeEntryLegacyFormatText(t *testing.T) {
+	entry, err := RecordToEntry(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "plain", 0), &HandlerOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := EncodeEntry(buffer, entry, EncodeOptions{LegacyFormat: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	got := single(t, decodeEntries(t, buffer.Bytes()))
+	if got["textPayload"] != "plain" || got["severity"] != "INFO" {
+		t.Fatalf("got %v, want the text payload", got)
+	}
+}
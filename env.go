@@ -0,0 +1,80 @@
+package slogr
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// EnvLevel returns a [slog.Leveler] for the level named by the environment
+// variable name. The variable is parsed with ParseLevel and re-read once the
+// ttl elapses, so the variable can change at runtime. An unset variable, or an
+// invalid level, is a given default level. An invalid level is reported once
+// per distinct value with the default logger.
+func EnvLevel(name string, def slog.Level, ttl time.Duration) slog.Leveler {
+	return &envLevel{
+		name:    name,
+		def:     def,
+		ttl:     ttl,
+		invalid: make(map[string]bool),
+	}
+}
+
+type envLevel struct {
+	name string
+	def  slog.Level
+	ttl  time.Duration
+
+	mu      sync.Mutex
+	level   slog.Level
+	expires time.Time
+	invalid map[string]bool
+}
+
+// Level implements [slog.Leveler].
+func (v *envLevel) Level() slog.Level {
+	level, value, invalid := v.load()
+	// the default logger can use this leveler, so the warning is logged
+	// outside of the lock
+	if invalid {
+		slog.Warn("invalid log level in the environment",
+			slog.String("variable", v.name),
+			slog.String("value", value),
+			slog.String("level", levelString(level)),
+		)
+	}
+
+	return level
+}
+
+// load returns the cached level, or reads it from the environment when the
+// ttl elapsed. It reports an invalid value the first time it's read.
+func (v *envLevel) load() (slog.Level, string, bool) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	// the level is cached for the ttl
+	if now.Before(v.expires) {
+		return v.level, "", false
+	}
+
+	v.level = v.def
+	v.expires = now.Add(v.ttl)
+
+	value, ok := os.LookupEnv(v.name)
+	if !ok || value == "" {
+		return v.level, "", false
+	}
+
+	level, err := ParseLevel(value)
+	if err != nil {
+		reported := v.invalid[value]
+		v.invalid[value] = true
+		return v.level, value, !reported
+	}
+
+	v.level = level
+	return v.level, "", false
+}
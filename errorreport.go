@@ -0,0 +1,364 @@
+package slogr
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
+	"time"
+
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+)
+
+// ReportedErrorEventType is the @type of the entries that Error Reporting
+// takes as reported error events.
+const ReportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// ServiceContext identifies the service and the version of the reported
+// error events.
+type ServiceContext struct {
+	// Service is the name of the service.
+	Service string
+
+	// Version is the version of the service, e.g. a release or a commit.
+	Version string
+}
+
+// ErrorReportingOptions for the Error Reporting handler. A zero
+// ErrorReportingOptions consists entirely of default values.
+type ErrorReportingOptions struct {
+	// Level reports the minimum level of the reported records.
+	// If Level is nil, the handler assumes LevelError.
+	Level slog.Leveler
+
+	// Writer is the writer of the reported error events, e.g. a dedicated
+	// stream of the logging agent. If Writer is nil, or is the writer of the
+	// handler, the event fields are merged into the entry of the handler, so
+	// every error is written once.
+	Writer io.Writer
+
+	// Service is the service of the serviceContext of the events.
+	// If Service is empty, the handler assumes the name of the binary.
+	Service string
+
+	// Version is the version of the serviceContext of the events.
+	Version string
+
+	// StackDepth is the maximum number of frames of the stack traces.
+	// If StackDepth is zero, the handler assumes DefaultStackDepth.
+	StackDepth int
+}
+
+// NewErrorReportingHandler creates a [slog.Handler] that reports the records
+// at or above a level to Cloud Error Reporting, in addition to writing them to
+// a given handler. A report is a ReportedErrorEvent with the stack trace of
+// the log statement in the message, the serviceContext, and the HTTP request
+// and the source location of the record in the context.
+func NewErrorReportingHandler(inner slog.Handler, opts *ErrorReportingOptions) slog.Handler {
+	if opts == nil {
+		opts = &ErrorReportingOptions{}
+	}
+
+	h := &errorReportingHandler{
+		inner:   inner,
+		level:   opts.Level,
+		writer:  opts.Writer,
+		mu:      &sync.Mutex{},
+		service: opts.Service,
+		version: opts.Version,
+		depth:   opts.StackDepth,
+	}
+
+	if h.depth <= 0 {
+		h.depth = DefaultStackDepth
+	}
+
+	if h.level == nil {
+		h.level = slog.LevelError
+	}
+
+	if h.service == "" {
+		h.service = filepath.Base(os.Args[0])
+	}
+
+	// the same stream gets a single merged entry
+	if handler, ok := inner.(*Handler); ok && handler.writer == h.writer {
+		h.writer = nil
+	}
+
+	return h
+}
+
+// errorReportingHandler implements a [slog.Handler] that reports the errors
+// of another handler. The groups are applied by the reporting handler itself,
+// so the merged event fields always stay at the top level.
+type errorReportingHandler struct {
+	inner   slog.Handler
+	level   slog.Leveler
+	writer  io.Writer
+	mu      *sync.Mutex
+	service string
+	version string
+	depth   int
+	groups  []group
+}
+
+// Enabled implements slog.Handler
+func (h *errorReportingHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *errorReportingHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.level.Level() {
+		return h.inner.Handle(ctx, h.record(r))
+	}
+
+	event := h.event(r)
+
+	if h.writer == nil {
+		// the record of the caller is left as it is
+		if r = h.record(r); len(h.groups) == 0 {
+			r = r.Clone()
+		}
+
+		// merge the event into the entry
+		r.AddAttrs(event.attrs()...)
+		return h.inner.Handle(ctx, r)
+	}
+
+	err := h.inner.Handle(ctx, h.record(r))
+
+	data, merr := json.Marshal(event)
+	if merr != nil {
+		return merr
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, werr := h.writer.Write(append(data, '\n')); werr != nil && err == nil {
+		err = werr
+	}
+
+	return err
+}
+
+// WithAttrs implements slog.Handler
+func (h *errorReportingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+
+	if count := len(h.groups); count > 0 {
+		c.groups = slices.Clone(h.groups)
+		c.groups[count-1] = c.groups[count-1].with(attrs)
+	} else {
+		c.inner = h.inner.WithAttrs(attrs)
+	}
+
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *errorReportingHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	c := *h
+	c.groups = append(slices.Clip(h.groups), group{name: name})
+	return &c
+}
+
+// record returns a given record with its attributes nested in the groups of
+// the handler, or the record itself when there's no group.
+func (h *errorReportingHandler) record(r slog.Record) slog.Record {
+	if len(h.groups) == 0 {
+		return r
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	// collect the attributes
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	record.AddAttrs(nest(h.groups, attrs)...)
+	return record
+}
+
+// handlerStats implements statsHolder
+func (h *errorReportingHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+// reportedErrorEvent represents a ReportedErrorEvent in JSON.
+type reportedErrorEvent struct {
+	Type           string            `json:"@type"`
+	EventTime      string            `json:"eventTime"`
+	Severity       string            `json:"severity"`
+	Message        string            `json:"message"`
+	ServiceContext map[string]string `json:"serviceContext"`
+	Context        *errorContext     `json:"context,omitempty"`
+}
+
+// errorContext represents the context of a ReportedErrorEvent.
+type errorContext struct {
+	HTTPRequest    *errorHTTPRequest    `json:"httpRequest,omitempty"`
+	ReportLocation *errorReportLocation `json:"reportLocation,omitempty"`
+}
+
+// errorHTTPRequest represents the HTTP request of a ReportedErrorEvent.
+type errorHTTPRequest struct {
+	Method             string `json:"method,omitempty"`
+	URL                string `json:"url,omitempty"`
+	UserAgent          string `json:"userAgent,omitempty"`
+	Referrer           string `json:"referrer,omitempty"`
+	ResponseStatusCode int32  `json:"responseStatusCode,omitempty"`
+	RemoteIP           string `json:"remoteIp,omitempty"`
+}
+
+// errorReportLocation represents the report location of a ReportedErrorEvent.
+type errorReportLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+// event returns the reported error event of a given record.
+func (h *errorReportingHandler) event(r slog.Record) *reportedErrorEvent {
+	return newReportedErrorEvent(r, ServiceContext{Service: h.service, Version: h.version}, h.depth)
+}
+
+// newReportedErrorEvent returns the reported error event of a given record
+// for a given service, with the stack trace up to a given depth.
+func newReportedErrorEvent(r slog.Record, service ServiceContext, depth int) *reportedErrorEvent {
+	var (
+		message = r.Message
+		stack   string
+		request *ltype.HttpRequest
+		status  int32
+	)
+
+	visit := func(attr slog.Attr) {
+		switch attr.Key {
+		case ErrorKey:
+			message += ": " + errorString(attr.Value)
+		case StackTraceKey:
+			if trace, ok := attr.Value.Any().(stackTrace); ok {
+				stack = trace.format(depth)
+			}
+		case RequestKey:
+			request, _ = attr.Value.Any().(*ltype.HttpRequest)
+		case ResponseKey:
+			if response, ok := attr.Value.Any().(*ltype.HttpRequest); ok {
+				status = response.Status
+			}
+		}
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		// the attributes of ErrorWithStack are inlined
+		if isErrorWithStack(attr) {
+			for _, item := range attr.Value.Group() {
+				visit(item)
+			}
+
+			return true
+		}
+
+		visit(attr)
+		return true
+	})
+
+	if stack == "" {
+		// the stack trace follows the message
+		message += "\n\n" + formatStack(callers(r.PC, depth))
+	} else {
+		// the stack of an error follows its string already
+		message = r.Message + ": " + stack
+	}
+
+	timestamp := r.Time
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+
+	event := &reportedErrorEvent{
+		Type:           ReportedErrorEventType,
+		EventTime:      timestamp.UTC().Format(time.RFC3339Nano),
+		Severity:       severityOf(r.Level).String(),
+		Message:        message,
+		ServiceContext: map[string]string{"service": service.Service},
+		Context:        &errorContext{},
+	}
+
+	if service.Version != "" {
+		event.ServiceContext["version"] = service.Version
+	}
+
+	if r.PC != 0 {
+		frame, _ := runtime.CallersFrames([]uintptr{r.PC}).Next()
+		event.Context.ReportLocation = &errorReportLocation{
+			FilePath:     frame.File,
+			LineNumber:   frame.Line,
+			FunctionName: frame.Function,
+		}
+	}
+
+	if request != nil {
+		event.Context.HTTPRequest = &errorHTTPRequest{
+			Method:             request.RequestMethod,
+			URL:                request.RequestUrl,
+			UserAgent:          request.UserAgent,
+			Referrer:           request.Referer,
+			ResponseStatusCode: max(request.Status, status),
+			RemoteIP:           request.RemoteIp,
+		}
+	}
+
+	return event
+}
+
+// attrs returns the fields of the event that are merged into an entry. The
+// message of the entry is kept, and the stack trace is its stack_trace.
+func (x *reportedErrorEvent) attrs() []slog.Attr {
+	service := []any{slog.String("service", x.ServiceContext["service"])}
+	if version, ok := x.ServiceContext["version"]; ok {
+		service = append(service, slog.String("version", version))
+	}
+
+	var context []any
+
+	if request := x.Context.HTTPRequest; request != nil {
+		context = append(context, slog.Group("httpRequest",
+			slog.String("method", request.Method),
+			slog.String("url", request.URL),
+			slog.String("userAgent", request.UserAgent),
+			slog.String("referrer", request.Referrer),
+			slog.Int("responseStatusCode", int(request.ResponseStatusCode)),
+			slog.String("remoteIp", request.RemoteIP),
+		))
+	}
+
+	if location := x.Context.ReportLocation; location != nil {
+		context = append(context, slog.Group("reportLocation",
+			slog.String("filePath", location.FilePath),
+			slog.Int("lineNumber", location.LineNumber),
+			slog.String("functionName", location.FunctionName),
+		))
+	}
+
+	return []slog.Attr{
+		slog.String("@type", x.Type),
+		slog.Group("serviceContext", service...),
+		slog.Group("context", context...),
+		slog.String(StackTraceKey, x.Message),
+	}
+}
@@ -0,0 +1,172 @@
+package slogr
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestErrorReportingHandlerMerged(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	reporting := slog.New(NewErrorReportingHandler(logger.Handler(), &ErrorReportingOptions{
+		Service: "api",
+		Version: "v1",
+	}))
+
+	reporting.Info("not reported")
+	reporting.Error("failed", Error(errors.New("boom")))
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	if _, ok := collection[0]["message"].(string); !ok {
+		t.Errorf("got payload %v, want the message of the record below the level", collection[0]["message"])
+	}
+
+	payload := payloadOf(collection[1])
+	if got := payload["@type"]; got != ReportedErrorEventType {
+		t.Errorf("got @type %v, want %s", got, ReportedErrorEventType)
+	}
+
+	service, _ := payload["serviceContext"].(map[string]any)
+	if service["service"] != "api" || service["version"] != "v1" {
+		t.Errorf("got serviceContext %v, want api v1", service)
+	}
+
+	if stack, _ := payload[StackTraceKey].(string); !strings.HasPrefix(stack, "failed: boom\n") {
+		t.Errorf("got stack %q, want the message of the record with its stack", stack)
+	}
+}
+
+func TestErrorReportingHandlerGroups(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	reporting := slog.New(NewErrorReportingHandler(logger.Handler(), nil)).
+		With("user", "alice").
+		WithGroup("call").
+		With("method", "GET")
+
+	reporting.Error("failed", slog.Int("attempt", 3))
+
+	payload := payloadOf(single(t, entries()))
+	// the event stays at the top level
+	if got := payload["@type"]; got != ReportedErrorEventType {
+		t.Fatalf("got @type %v at the top level, want %s", got, ReportedErrorEventType)
+	}
+
+	if _, ok := payload["serviceContext"]; !ok {
+		t.Error("got no serviceContext at the top level")
+	}
+
+	if got := payload["user"]; got != "alice" {
+		t.Errorf("got user %v, want alice", got)
+	}
+
+	call, _ := payload["call"].(map[string]any)
+	if call["method"] != "GET" || call["attempt"] != 3.0 {
+		t.Errorf("got call %v, want the method and the attempt", call)
+	}
+
+	if _, ok := call["@type"]; ok {
+		t.Error("got the event in the group")
+	}
+}
+
+func TestErrorReportingHandlerWriter(t *testing.T) {
+	var (
+		logger, entries = capture(t, nil)
+		reports         = &bytes.Buffer{}
+	)
+
+	reporting := slog.New(NewErrorReportingHandler(logger.Handler(), &ErrorReportingOptions{Writer: reports}))
+	reporting.Warn("not reported")
+	reporting.Error("failed")
+
+	if got := len(entries()); got != 2 {
+		t.Fatalf("got %d entries, want 2", got)
+	}
+
+	event := single(t, decodeEntries(t, reports.Bytes()))
+	if got := event["@type"]; got != ReportedErrorEventType {
+		t.Fatalf("got @type %v, want %s", got, ReportedErrorEventType)
+	}
+}
+
+func TestHandlerErrorReporting(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{
+		ErrorReporting: true,
+		ServiceContext: ServiceContext{Service: "api", Version: "v1"},
+	})
+
+	logger.Info("not reported", Error(errors.New("boom")))
+	logger.Error("not reported")
+	logger.Error("failed", Error(errors.New("boom")))
+
+	collection := entries()
+	if len(collection) != 3 {
+		t.Fatalf("got %d entries, want 3", len(collection))
+	}
+
+	// only the errors at LevelError are reported
+	for _, entry := range collection[:2] {
+		if got := payloadOf(entry)["@type"]; got != nil {
+			t.Errorf("got @type %v of %v, want none", got, entry)
+		}
+	}
+
+	payload := payloadOf(collection[2])
+	if got := payload["@type"]; got != ReportedErrorEventType {
+		t.Errorf("got @type %v, want %s", got, ReportedErrorEventType)
+	}
+
+	service, _ := payload["serviceContext"].(map[string]any)
+	if service["service"] != "api" || service["version"] != "v1" {
+		t.Errorf("got serviceContext %v, want api v1", service)
+	}
+
+	if stack, _ := payload[StackTraceKey].(string); !strings.HasPrefix(stack, "failed: boom\n") {
+		t.Errorf("got stack %q, want the message of the record with its stack", stack)
+	}
+}
+
+func TestHandlerErrorReportingService(t *testing.T) {
+	for _, tc := range []struct {
+		opts *HandlerOptions
+		want string
+	}{
+		{&HandlerOptions{ServiceContext: ServiceContext{Service: "api"}, ServiceName: "audit"}, "api"},
+		{&HandlerOptions{ServiceName: "audit"}, "audit"},
+		// the name of the binary is the last fallback
+		{&HandlerOptions{}, filepath.Base(os.Args[0])},
+	} {
+		tc.opts.ErrorReporting = true
+
+		logger, entries := capture(t, tc.opts)
+		logger.Error("failed", Error(errors.New("boom")))
+
+		service, _ := payloadOf(single(t, entries()))["serviceContext"].(map[string]any)
+		if service["service"] != tc.want {
+			t.Errorf("got serviceContext %v, want %s", service, tc.want)
+		}
+
+		if _, ok := service["version"]; ok {
+			t.Errorf("got serviceContext %v, want no version", service)
+		}
+	}
+}
+
+func TestHandlerWithoutErrorReporting(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Error("failed", Error(errors.New("boom")))
+
+	if got := payloadOf(single(t, entries()))["@type"]; got != nil {
+		t.Fatalf("got @type %v, want none", got)
+	}
+}
@@ -0,0 +1,280 @@
+package slogr
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const (
+	// errorDepth is the maximum number of causes of an error attribute.
+	errorDepth = 16
+	// errorLimit is the maximum number of errors of a multi-error.
+	errorLimit = 100
+)
+
+// AttrError is implemented by the errors that carry their own attributes,
+// such as the ID of an entity, or whether the failure is retryable. The
+// attributes of the errors of a chain are added to the group of the error
+// attribute.
+type AttrError interface {
+	LogAttrs() []slog.Attr
+}
+
+// WrapError returns an error that wraps a given error with given
+// attributes, which the error attribute adds to its group. The wrapper has
+// the message of the error, and isn't one of its causes. WrapError returns
+// nil for a nil error.
+func WrapError(err error, attrs ...slog.Attr) error {
+	if err == nil {
+		return nil
+	}
+
+	return &attrError{err: err, attrs: attrs}
+}
+
+type attrError struct {
+	err   error
+	attrs []slog.Attr
+}
+
+// Error implements error
+func (x *attrError) Error() string {
+	return x.err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (x *attrError) Unwrap() error {
+	return x.err
+}
+
+// LogAttrs implements AttrError
+func (x *attrError) LogAttrs() []slog.Attr {
+	return x.attrs
+}
+
+// errorValue returns the value of the error attribute of a given error. The
+// errors that wrap no other error are their error string, so the queries on
+// the error key keep working. The other errors are a group of their message,
+// the causes of the unwrap chain from the error itself, each with its type and
+// its message, and the root cause, which is the innermost error. The errors
+// that wrap several errors, such as the ones of errors.Join, branch into the
+// chains of the wrapped errors, and the root cause is the one of the first
+// branch.
+//
+// The first multi-error of the chain is flattened into an errors array with
+// the type and the message of its errors, the nested multi-errors included,
+// with their count, and the message joins their messages with semicolons
+// instead of newlines.
+//
+// The attributes of the AttrError errors of the chain follow. The fields of
+// the group win over them, and the ones of the outer errors win over the ones
+// of the errors they wrap.
+func errorValue(err error) slog.Value {
+	var (
+		count  int
+		causes = errorCauses(err, &count)
+	)
+
+	var (
+		multi  = multiError(err)
+		extras = errorAttrs(err)
+	)
+
+	// the chain of an error that wraps nothing is the error itself
+	if len(causes) < 2 && multi == nil && len(extras) == 0 {
+		return slog.StringValue(err.Error())
+	}
+
+	var (
+		attrs = []slog.Attr{slog.String("message", err.Error())}
+		root  = errorRoot(err)
+	)
+
+	if multi != nil {
+		var errs []any
+		errorLeaves(multi, &errs)
+
+		attrs[0] = slog.String("message", joinErrors(err))
+
+		if value, err := structpb.NewList(errs[:min(len(errs), errorLimit)]); err == nil {
+			attrs = append(attrs, slog.Any("errors", value))
+		}
+
+		attrs = append(attrs, slog.Int("count", len(errs)))
+	}
+
+	attrs = append(attrs, extras...)
+
+	// the attributes alone don't make a chain
+	if len(causes) > 1 || multi != nil {
+		if value, err := structpb.NewList(causes); err == nil {
+			attrs = append(attrs, slog.Any("causes", value))
+		}
+
+		if value, err := structpb.NewStruct(root); err == nil {
+			attrs = append(attrs, slog.Any("root", value))
+		}
+	}
+
+	return slog.GroupValue(attrs...)
+}
+
+// errorCauses returns the unwrap chain of a given error, up to errorDepth
+// errors in total for a given count of the errors so far.
+func errorCauses(err error, count *int) []any {
+	var causes []any
+
+	for err != nil && *count < errorDepth {
+		// the wrappers of the attributes are transparent
+		if value, ok := err.(*attrError); ok {
+			err = value.err
+			continue
+		}
+
+		*count++
+
+		cause := map[string]any{
+			"type":    fmt.Sprintf("%T", err),
+			"message": err.Error(),
+		}
+
+		causes = append(causes, cause)
+
+		switch value := err.(type) {
+		case interface{ Unwrap() []error }:
+			var branches []any
+
+			for _, item := range value.Unwrap() {
+				if chain := errorCauses(item, count); len(chain) > 0 {
+					branches = append(branches, chain)
+				}
+			}
+
+			if len(branches) > 0 {
+				cause["branches"] = branches
+			}
+
+			return causes
+		case interface{ Unwrap() error }:
+			err = value.Unwrap()
+		default:
+			return causes
+		}
+	}
+
+	return causes
+}
+
+// errorRoot returns the type and the message of the innermost error of a
+// given error, following the first branches, beyond errorDepth.
+func errorRoot(err error) map[string]any {
+	for {
+		switch value := err.(type) {
+		case interface{ Unwrap() []error }:
+			if errs := value.Unwrap(); len(errs) > 0 && errs[0] != nil {
+				err = errs[0]
+				continue
+			}
+		case interface{ Unwrap() error }:
+			if inner := value.Unwrap(); inner != nil {
+				err = inner
+				continue
+			}
+		}
+
+		return map[string]any{
+			"type":    fmt.Sprintf("%T", err),
+			"message": err.Error(),
+		}
+	}
+}
+
+// errorAttrs returns the attributes of the AttrError errors of the unwrap
+// chain of a given error. The first attribute of a key wins, and the keys of
+// the fields of the error group are skipped.
+func errorAttrs(err error) []slog.Attr {
+	var (
+		attrs []slog.Attr
+		seen  = map[string]bool{
+			"message":  true,
+			"causes":   true,
+			"root":     true,
+			"errors":   true,
+			"count":    true,
+			"branches": true,
+		}
+	)
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		value, ok := err.(AttrError)
+		if !ok {
+			continue
+		}
+
+		for _, attr := range value.LogAttrs() {
+			if attr.Key != "" && !seen[attr.Key] {
+				seen[attr.Key] = true
+				attrs = append(attrs, attr)
+			}
+		}
+	}
+
+	return attrs
+}
+
+// multiError returns the first error of the unwrap chain of a given error
+// that wraps several errors, or nil.
+func multiError(err error) interface{ Unwrap() []error } {
+	for err != nil {
+		switch value := err.(type) {
+		case interface{ Unwrap() []error }:
+			return value
+		case interface{ Unwrap() error }:
+			err = value.Unwrap()
+		default:
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// errorLeaves adds the errors of a given multi-error to a given list, and
+// flattens the nested multi-errors.
+func errorLeaves(multi interface{ Unwrap() []error }, errs *[]any) {
+	for _, err := range multi.Unwrap() {
+		switch value := err.(type) {
+		case nil:
+		case interface{ Unwrap() []error }:
+			errorLeaves(value, errs)
+		default:
+			*errs = append(*errs, map[string]any{
+				"type":    fmt.Sprintf("%T", err),
+				"message": err.Error(),
+			})
+		}
+	}
+}
+
+// joinErrors returns the error string of a given error with semicolons
+// instead of the newlines of the multi-errors.
+func joinErrors(err error) string {
+	return strings.ReplaceAll(err.Error(), "\n", "; ")
+}
+
+// errorString returns the message of a given value of an error attribute.
+func errorString(value slog.Value) string {
+	if value.Kind() == slog.KindGroup {
+		for _, attr := range value.Group() {
+			if attr.Key == "message" {
+				return attr.Value.String()
+			}
+		}
+	}
+
+	return value.String()
+}
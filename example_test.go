@@ -0,0 +1,50 @@
+package slogr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+)
+
+// printPayload prints the JSON payload of the entry of a given handler for a
+// record with given attributes.
+func printPayload(handler slog.Handler, attrs ...slog.Attr) {
+	r := slog.NewRecord(time.Time{}, slog.LevelInfo, "produced", 0)
+	r.AddAttrs(attrs...)
+
+	entry := handler.(*Handler).Entry(context.Background(), r)
+
+	data, err := json.Marshal(entry.GetJsonPayload().AsMap())
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Println(string(data))
+}
+
+// The groups of a third-party helper are written to the payload as they are,
+// even when their members have the names of the reserved keys.
+func ExampleHandler_WithGroup() {
+	logger := slog.New(NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo})).
+		With(slog.Group("db", slog.String("operation", "SELECT"), slog.Int("rows", 3))).
+		WithGroup("call")
+
+	printPayload(logger.Handler(), slog.String("operation", "List"))
+	// Output: {"call":{"operation":"List"},"db":{"operation":"SELECT","rows":3},"logging.googleapis.com/message":"produced"}
+}
+
+// The reserved keys are only recognized with the values of their attribute
+// functions. The request group of an HTTP middleware is a payload field, not
+// the httpRequest of the entry.
+func ExampleHandler_reservedKeys() {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo})
+
+	printPayload(handler,
+		slog.Group(RequestKey, slog.String("method", "GET")),
+		slog.String(SpanKey, "db.query"),
+	)
+	// Output: {"logging.googleapis.com/message":"produced","request":{"method":"GET"},"span":"db.query"}
+}
@@ -0,0 +1,194 @@
+package slogr
+
+import (
+	"bytes"
+	"io"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// FailoverOptions for the failover writer. A zero FailoverOptions consists
+// entirely of default values.
+type FailoverOptions struct {
+	// Threshold is the number of consecutive failed writes to the primary
+	// writer after which the writer switches to the secondary one.
+	// If Threshold is zero, the writer assumes 3.
+	Threshold int
+
+	// ProbeInterval is the time before the first probe of the primary writer
+	// after a switch. If ProbeInterval is zero, the writer assumes 5s.
+	ProbeInterval time.Duration
+
+	// Backoff is the delay between the probes of the primary writer, which is
+	// reset once the primary writer is back. If Backoff is nil, the writer
+	// uses an exponential backoff from ProbeInterval to six times it, with a
+	// 20% jitter.
+	Backoff *Backoff
+
+	// Probe reports whether the primary writer is back, e.g. by dialing the
+	// socket behind it. It's called in the background after every delay of
+	// the backoff. If Probe is nil, the first write after the delay is tried
+	// on the primary writer instead.
+	Probe func() error
+}
+
+// Failover creates an [io.Writer] that writes to the primary writer, and
+// switches to the secondary writer, such as os.Stderr, after consecutive
+// failed writes. The buffer of a failed write is written to the secondary
+// writer too. It switches back once the primary writer works again. An
+// entry is written to the secondary writer on the switch, and to the primary
+// writer on the switch back.
+//
+// A write to the primary writer fails when it returns an error, such as
+// EPIPE, or when it's short. The whole buffer is written to the secondary
+// writer then, so the part already written to the primary writer might be
+// duplicated.
+func Failover(primary, secondary io.Writer, opts *FailoverOptions) io.Writer {
+	if opts == nil {
+		opts = &FailoverOptions{}
+	}
+
+	w := &failoverWriter{
+		primary:   primary,
+		secondary: secondary,
+		threshold: opts.Threshold,
+		interval:  opts.ProbeInterval,
+		backoff:   opts.Backoff,
+		probe:     opts.Probe,
+	}
+
+	if w.threshold <= 0 {
+		w.threshold = 3
+	}
+
+	if w.interval <= 0 {
+		w.interval = 5 * time.Second
+	}
+
+	if w.backoff == nil {
+		w.backoff = &Backoff{Initial: w.interval, Max: 6 * w.interval, Jitter: 0.2}
+	}
+
+	return w
+}
+
+type failoverWriter struct {
+	primary   io.Writer
+	secondary io.Writer
+	threshold int
+	interval  time.Duration
+	backoff   *Backoff
+	probe     func() error
+
+	mu       sync.Mutex
+	failures int
+	failed   bool
+	// probed is the time of the last probe, and delay the backoff of its
+	// attempt until the next one
+	probed  time.Time
+	attempt int
+	delay   time.Duration
+	// recovered is set by the background probe
+	recovered bool
+}
+
+// Write implements io.Writer
+func (w *failoverWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.failed && !w.recovered && (w.probe != nil || time.Since(w.probed) < w.delay) {
+		return w.secondary.Write(p)
+	}
+
+	err := w.writePrimary(p)
+	switch {
+	case err == nil && w.failed:
+		w.failed, w.recovered, w.attempt = false, false, 0
+		// report the switch back
+		_ = w.writePrimary(w.meta(ltype.LogSeverity_NOTICE, "slogr: switched back to the primary writer"))
+		return len(p), nil
+	case err == nil:
+		w.failures = 0
+		return len(p), nil
+	case w.failed:
+		// the primary writer isn't back yet
+		w.attempt++
+		w.probed, w.delay, w.recovered = time.Now(), w.backoff.Delay(w.attempt), false
+		if w.probe != nil {
+			go w.run(w.attempt)
+		}
+
+		return w.secondary.Write(p)
+	}
+
+	// the entry isn't lost before the switch either
+	if w.failures++; w.failures < w.threshold {
+		return w.secondary.Write(p)
+	}
+
+	w.failed, w.failures, w.attempt = true, 0, 0
+	w.probed, w.delay = time.Now(), w.backoff.Delay(0)
+	// report the switch
+	_, _ = w.secondary.Write(w.meta(ltype.LogSeverity_WARNING, "slogr: switched to the secondary writer: "+err.Error()))
+
+	if w.probe != nil {
+		go w.run(0)
+	}
+
+	return w.secondary.Write(p)
+}
+
+// writePrimary writes a given buffer to the primary writer. A short write is
+// a failure.
+func (w *failoverWriter) writePrimary(p []byte) error {
+	n, err := w.primary.Write(p)
+	if err == nil && n < len(p) {
+		err = io.ErrShortWrite
+	}
+
+	return err
+}
+
+// run probes the primary writer from a given attempt of the backoff until
+// it's back, or until a write switched back to it.
+func (w *failoverWriter) run(attempt int) {
+	for ; ; attempt++ {
+		time.Sleep(w.backoff.Delay(attempt))
+
+		ok := w.probe() == nil
+
+		w.mu.Lock()
+		if !w.failed {
+			w.mu.Unlock()
+			return
+		}
+
+		if ok {
+			w.recovered = true
+			w.mu.Unlock()
+			return
+		}
+		w.mu.Unlock()
+	}
+}
+
+// meta returns a meta entry in the logging agent format.
+func (w *failoverWriter) meta(severity ltype.LogSeverity, message string) []byte {
+	entry := &Entry{
+		Severity:  severity,
+		Timestamp: timestamppb.Now(),
+		Payload: &loggingpb.LogEntry_TextPayload{
+			TextPayload: message,
+		},
+	}
+
+	buffer := &bytes.Buffer{}
+	// the entry is always valid
+	_ = EncodeEntry(buffer, entry, EncodeOptions{EscapeHTML: true})
+	return buffer.Bytes()
+}
@@ -0,0 +1,291 @@
+package slogr
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// brokenWriter is a writer that fails mid-write once it's broken: it writes
+// the first limit bytes of a buffer, and returns err, or a short write when
+// err is nil.
+type brokenWriter struct {
+	mu     sync.Mutex
+	buffer bytes.Buffer
+	broken bool
+	limit  int
+	err    error
+	calls  int
+}
+
+// Write implements io.Writer
+func (w *brokenWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.calls++
+
+	if !w.broken {
+		return w.buffer.Write(p)
+	}
+
+	n := min(w.limit, len(p))
+	w.buffer.Write(p[:n])
+	// done!
+	return n, w.err
+}
+
+func (w *brokenWriter) set(broken bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.broken = broken
+}
+
+func (w *brokenWriter) count() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.calls
+}
+
+func (w *brokenWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.buffer.String()
+}
+
+func TestFailoverEPIPE(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, limit: 3, err: syscall.EPIPE}
+		secondary = &bytes.Buffer{}
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{Threshold: 2, ProbeInterval: time.Hour})
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		n, err := w.Write([]byte(line))
+		if err != nil || n != len(line) {
+			t.Fatalf("got %d, %v, want the line written to the secondary writer", n, err)
+		}
+	}
+
+	// the third write goes to the secondary writer only
+	if got := primary.count(); got != 2 {
+		t.Fatalf("got %d writes to the primary writer, want 2", got)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(secondary.String(), "\n"), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got lines %q, want 4", lines)
+	}
+
+	if lines[0] != "first" || lines[2] != "second" || lines[3] != "third" {
+		t.Errorf("got lines %q, want every line in order", lines)
+	}
+
+	entry := single(t, decodeEntries(t, []byte(lines[1])))
+	if message, _ := entry["message"].(string); !strings.Contains(message, "switched to the secondary writer: broken pipe") {
+		t.Errorf("got switch entry %v, want the error", entry)
+	}
+}
+
+func TestFailoverShortWrite(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, limit: 2}
+		secondary = &bytes.Buffer{}
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{Threshold: 1, ProbeInterval: time.Hour})
+
+	if _, err := w.Write([]byte("partial\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the part written to the primary writer is duplicated
+	if got := primary.String(); got != "pa" {
+		t.Fatalf("got %q on the primary writer, want the partial write", got)
+	}
+
+	if got := secondary.String(); !strings.Contains(got, io.ErrShortWrite.Error()) || !strings.HasSuffix(got, "\npartial\n") {
+		t.Fatalf("got %q on the secondary writer, want the switch and the whole buffer", got)
+	}
+}
+
+func TestFailoverSwitchBack(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, err: errors.New("unavailable")}
+		secondary = &bytes.Buffer{}
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{Threshold: 1, ProbeInterval: time.Millisecond})
+
+	if _, err := w.Write([]byte("lost\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	primary.set(false)
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := w.Write([]byte("back\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(primary.String(), "\n"), "\n")
+	if len(lines) != 2 || lines[0] != "back" {
+		t.Fatalf("got lines %q on the primary writer, want the line and the switch back", lines)
+	}
+
+	entry := single(t, decodeEntries(t, []byte(lines[1])))
+	if entry["severity"] != "NOTICE" {
+		t.Errorf("got switch back entry %v, want a notice", entry)
+	}
+}
+
+func TestFailoverProbe(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, err: syscall.EPIPE}
+		secondary = &bytes.Buffer{}
+		probed    = make(chan struct{}, 1)
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{
+		Threshold:     1,
+		ProbeInterval: time.Millisecond,
+		Probe: func() error {
+			primary.set(false)
+
+			select {
+			case probed <- struct{}{}:
+			default:
+			}
+
+			return nil
+		},
+	})
+
+	if _, err := w.Write([]byte("lost\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the writes go to the secondary writer until the probe succeeds
+	<-probed
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := w.Write([]byte("back\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := primary.String(); !strings.HasPrefix(got, "back\n") {
+		t.Fatalf("got %q on the primary writer, want the line after the probe", got)
+	}
+}
+
+func TestFailoverBackoff(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, err: syscall.EPIPE}
+		secondary = &bytes.Buffer{}
+		backoff   = &Backoff{Initial: time.Millisecond, Max: 8 * time.Millisecond}
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{Threshold: 1, Backoff: backoff}).(*failoverWriter)
+
+	write := func(line string) {
+		t.Helper()
+
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("lost\n")
+
+	// every failed retry of the primary writer waits longer
+	for _, want := range []time.Duration{2, 4, 8, 8} {
+		w.probed = time.Time{}
+		write("lost\n")
+
+		if w.delay != want*time.Millisecond {
+			t.Fatalf("got delay %v, want %v", w.delay, want*time.Millisecond)
+		}
+	}
+
+	primary.set(false)
+	w.probed = time.Time{}
+	write("back\n")
+
+	if w.attempt != 0 || w.failed {
+		t.Fatalf("got attempt %d after the switch back, want the backoff reset", w.attempt)
+	}
+
+	// the backoff starts over on the next switch
+	primary.set(true)
+	write("lost\n")
+
+	if w.delay != time.Millisecond {
+		t.Fatalf("got delay %v after the next switch, want %v", w.delay, time.Millisecond)
+	}
+}
+
+func TestFailoverProbeBackoff(t *testing.T) {
+	var (
+		primary   = &brokenWriter{broken: true, err: syscall.EPIPE}
+		secondary = &bytes.Buffer{}
+		probes    = make(chan time.Time, 10)
+		count     int
+	)
+
+	w := Failover(primary, secondary, &FailoverOptions{
+		Threshold: 1,
+		Backoff:   &Backoff{Initial: time.Millisecond, Max: 16 * time.Millisecond},
+		Probe: func() error {
+			// the primary writer is back on the fourth probe
+			if count++; count == 4 {
+				primary.set(false)
+			}
+
+			probes <- time.Now()
+
+			if count < 4 {
+				return syscall.ECONNREFUSED
+			}
+
+			return nil
+		},
+	})
+
+	started := time.Now()
+	if _, err := w.Write([]byte("lost\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	var last time.Time
+	for index := 0; index < 4; index++ {
+		select {
+		case last = <-probes:
+		case <-time.After(5 * time.Second):
+			t.Fatal("got no probe")
+		}
+	}
+
+	// the probes wait 1ms, 2ms, 4ms and 8ms
+	if elapsed := last.Sub(started); elapsed < 15*time.Millisecond {
+		t.Fatalf("got the fourth probe after %v, want the delays of the backoff", elapsed)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if _, err := w.Write([]byte("back\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := primary.String(); !strings.HasPrefix(got, "back\n") {
+		t.Fatalf("got %q on the primary writer, want the line after the probe", got)
+	}
+}
@@ -0,0 +1,114 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// FanoutOptions for the fanout handler. A zero FanoutOptions consists
+// entirely of default values.
+type FanoutOptions struct {
+	// When StopOnError is true, the handler stops at the first handler that
+	// returns an error. By default, every handler is called and the errors are
+	// joined.
+	StopOnError bool
+}
+
+// Fanout creates a [slog.Handler] that writes every record to the given
+// handlers, using the default options.
+func Fanout(handlers ...slog.Handler) slog.Handler {
+	return NewFanoutHandler(nil, handlers...)
+}
+
+// NewFanoutHandler creates a [slog.Handler] that writes every record to the
+// given handlers that are enabled for it, in order. It's enabled when any of
+// the handlers is.
+func NewFanoutHandler(opts *FanoutOptions, handlers ...slog.Handler) slog.Handler {
+	if opts == nil {
+		opts = &FanoutOptions{}
+	}
+
+	return &fanoutHandler{
+		handlers: handlers,
+		stop:     opts.StopOnError,
+	}
+}
+
+type fanoutHandler struct {
+	handlers []slog.Handler
+	stop     bool
+}
+
+// Enabled implements slog.Handler
+func (h *fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, handler := range h.handlers {
+		if handler.Enabled(ctx, level) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handle implements slog.Handler
+func (h *fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+
+	for _, handler := range h.handlers {
+		if !handler.Enabled(ctx, r.Level) {
+			continue
+		}
+
+		// every handler gets its own copy of the record
+		if err := handler.Handle(ctx, r.Clone()); err != nil {
+			if h.stop {
+				return err
+			}
+
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// WithAttrs implements slog.Handler
+func (h *fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+// WithGroup implements slog.Handler
+func (h *fanoutHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+func (h *fanoutHandler) with(fn func(slog.Handler) slog.Handler) slog.Handler {
+	handlers := make([]slog.Handler, len(h.handlers))
+	// clone every handler
+	for index, handler := range h.handlers {
+		handlers[index] = fn(handler)
+	}
+
+	return &fanoutHandler{
+		handlers: handlers,
+		stop:     h.stop,
+	}
+}
+
+// Flush flushes the handlers that implement Flusher.
+func (h *fanoutHandler) Flush() error {
+	var errs []error
+
+	for _, handler := range h.handlers {
+		if flusher, ok := handler.(Flusher); ok {
+			errs = append(errs, flusher.Flush())
+		}
+	}
+
+	return errors.Join(errs...)
+}
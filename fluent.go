@@ -0,0 +1,389 @@
+package slogr
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// FluentOptions for a FluentWriter. A zero FluentOptions writes to a local
+// forward input on the default port.
+type FluentOptions struct {
+	// Network is the network of the forward input, "tcp" or "unix".
+	// If Network is empty, the writer assumes "tcp".
+	Network string
+
+	// Address is the address of the forward input.
+	// If Address is empty, the writer assumes "127.0.0.1:24224".
+	Address string
+
+	// Tag is the tag of the entries without a log name. The log name of an
+	// entry is appended to it, with the slashes replaced by dots.
+	// If Tag is empty, the writer assumes "slogr".
+	Tag string
+
+	// When Packed is true, the writer batches the records of a tag, and sends
+	// them in the PackedForward mode. By default, every record is sent in the
+	// Message mode.
+	Packed bool
+
+	// MaxEntries is the maximum number of records of a batch in the
+	// PackedForward mode. If MaxEntries is zero, the writer assumes 100.
+	MaxEntries int
+
+	// FlushInterval is the maximum time a record is kept in a batch in the
+	// PackedForward mode. If FlushInterval is zero, the writer assumes 1s.
+	FlushInterval time.Duration
+
+	// When RequireAck is true, the writer waits for the ack of every message,
+	// and retries the message once on a new connection when there's none.
+	RequireAck bool
+
+	// Timeout is the time limit to connect, write and wait for an ack.
+	// If Timeout is zero, the writer assumes 5s.
+	Timeout time.Duration
+}
+
+// FluentWriter writes the entries to a Fluent Forward input, such as the one
+// of fluent-bit or fluentd. A record is the entry in the logging agent format,
+// with the payload fields at the top level. It reconnects when a write fails,
+// and it's safe for concurrent use.
+type FluentWriter struct {
+	network  string
+	address  string
+	tag      string
+	packed   bool
+	entries  int
+	ack      bool
+	timeout  time.Duration
+	interval time.Duration
+
+	mu      sync.Mutex
+	conn    net.Conn
+	reader  *bufio.Reader
+	batches map[string][]byte
+	counts  map[string]int
+	done    chan struct{}
+	closed  bool
+}
+
+// NewFluentWriter creates a FluentWriter, and connects to the forward input.
+// Call Close to send the pending batches.
+func NewFluentWriter(opts *FluentOptions) (*FluentWriter, error) {
+	w := &FluentWriter{
+		network:  opts.Network,
+		address:  opts.Address,
+		tag:      opts.Tag,
+		packed:   opts.Packed,
+		entries:  opts.MaxEntries,
+		ack:      opts.RequireAck,
+		timeout:  opts.Timeout,
+		interval: opts.FlushInterval,
+		batches:  make(map[string][]byte),
+		counts:   make(map[string]int),
+		done:     make(chan struct{}),
+	}
+
+	if w.network == "" {
+		w.network = "tcp"
+	}
+
+	if w.address == "" {
+		w.address = "127.0.0.1:24224"
+	}
+
+	if w.tag == "" {
+		w.tag = "slogr"
+	}
+
+	if w.entries <= 0 {
+		w.entries = 100
+	}
+
+	if w.timeout <= 0 {
+		w.timeout = 5 * time.Second
+	}
+
+	if w.interval <= 0 {
+		w.interval = time.Second
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	if w.packed {
+		go w.run()
+	}
+
+	return w, nil
+}
+
+// NewFluentHandler creates a [slog.Handler] that writes the entries to a given
+// FluentWriter instead of an io.Writer. The options are the ones of
+// NewHandler.
+func NewFluentHandler(w *FluentWriter, opts *HandlerOptions) slog.Handler {
+	return NewEntryHandler(w, opts)
+}
+
+// WriteEntry writes a given entry as a Fluent Forward record.
+func (w *FluentWriter) WriteEntry(e *Entry) error {
+	record, err := fluentRecord(e)
+	if err != nil {
+		return err
+	}
+
+	var (
+		tag       = w.tagOf(e.LogName)
+		timestamp = time.Now()
+	)
+
+	if e.Timestamp != nil {
+		timestamp = e.Timestamp.AsTime()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrClosed
+	}
+
+	if !w.packed {
+		// [tag, time, record, option]
+		message := w.message(3)
+		message = msgpackString(message, tag)
+		message = msgpackTime(message, timestamp)
+		message = msgpackValue(message, record)
+		return w.send(message)
+	}
+
+	// the entries of a batch are [time, record]
+	batch := msgpackArray(w.batches[tag], 2)
+	batch = msgpackTime(batch, timestamp)
+	batch = msgpackValue(batch, record)
+
+	w.batches[tag] = batch
+	w.counts[tag]++
+
+	if w.counts[tag] >= w.entries {
+		return w.flushTag(tag)
+	}
+
+	return nil
+}
+
+// Flush sends the pending batches of the PackedForward mode.
+func (w *FluentWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.flush()
+}
+
+// Close sends the pending batches, and closes the connection.
+func (w *FluentWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return nil
+	}
+
+	w.closed = true
+	close(w.done)
+
+	err := w.flush()
+	// close the connection
+	if w.conn != nil {
+		err = errors.Join(err, w.conn.Close())
+		w.conn = nil
+	}
+
+	return err
+}
+
+// run sends the pending batches every interval until the writer is closed.
+func (w *FluentWriter) run() {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+			_ = w.Flush()
+		}
+	}
+}
+
+func (w *FluentWriter) flush() error {
+	var errs []error
+
+	for tag := range w.batches {
+		errs = append(errs, w.flushTag(tag))
+	}
+
+	return errors.Join(errs...)
+}
+
+// flushTag sends the batch of a given tag in the PackedForward mode.
+func (w *FluentWriter) flushTag(tag string) error {
+	batch := w.batches[tag]
+	delete(w.batches, tag)
+	delete(w.counts, tag)
+
+	// [tag, entries, option]
+	message := w.message(2)
+	message = msgpackString(message, tag)
+	message = msgpackBinary(message, batch)
+	return w.send(message)
+}
+
+// message returns the array header of a message of n elements, and of the
+// option when the ack is required.
+func (w *FluentWriter) message(n int) []byte {
+	if w.ack {
+		n++
+	}
+
+	return msgpackArray(nil, n)
+}
+
+// send writes a given message, and waits for its ack when it's required. The
+// option with the chunk id is appended to the message then. It reconnects once
+// when the write fails.
+func (w *FluentWriter) send(message []byte) error {
+	var chunk string
+
+	if w.ack {
+		id := make([]byte, 16)
+		if _, err := rand.Read(id); err != nil {
+			return err
+		}
+
+		chunk = base64.StdEncoding.EncodeToString(id)
+		// the option is the last element of the message
+		message = msgpackMap(message, 1)
+		message = msgpackString(message, "chunk")
+		message = msgpackString(message, chunk)
+	}
+
+	err := w.write(message, chunk)
+	if err == nil {
+		return nil
+	}
+
+	// reconnect and try again
+	if err := w.connect(); err != nil {
+		return err
+	}
+
+	return w.write(message, chunk)
+}
+
+func (w *FluentWriter) write(message []byte, chunk string) error {
+	if w.conn == nil {
+		return net.ErrClosed
+	}
+
+	if err := w.conn.SetDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+
+	if _, err := w.conn.Write(message); err != nil {
+		return err
+	}
+
+	if chunk == "" {
+		return nil
+	}
+
+	value, err := msgpackRead(w.reader)
+	if err != nil {
+		return err
+	}
+
+	if response, ok := value.(map[string]any); !ok || response["ack"] != chunk {
+		return fmt.Errorf("slogr: unexpected fluent ack %v", value)
+	}
+
+	return nil
+}
+
+// connect dials the forward input.
+func (w *FluentWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	conn, err := net.DialTimeout(w.network, w.address, w.timeout)
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	w.reader = bufio.NewReader(conn)
+	// done!
+	return nil
+}
+
+// tagOf returns the tag of a given log name.
+func (w *FluentWriter) tagOf(name string) string {
+	if name == "" {
+		return w.tag
+	}
+
+	name = name[strings.LastIndex(name, "/")+1:]
+	// the log name is escaped
+	if value, err := url.PathUnescape(name); err == nil {
+		name = value
+	}
+
+	return w.tag + "." + strings.ReplaceAll(name, "/", ".")
+}
+
+// fluentRecord returns the record of a given entry: the entry in the logging
+// agent format, with the payload fields at the top level. The values keep
+// their types, and msgpackValue writes the ones it doesn't support as strings.
+func fluentRecord(e *Entry) (map[string]any, error) {
+	record, err := e.fields(EncodeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	delete(record, FieldTime)
+
+	if len(e.Labels) == 0 {
+		delete(record, FieldLabels)
+	}
+
+	if payload, ok := e.Payload.(*loggingpb.LogEntry_JsonPayload); ok {
+		delete(record, FieldMessage)
+		// flatten the payload fields
+		for key, value := range payload.JsonPayload.AsMap() {
+			if _, ok := record[key]; !ok {
+				record[key] = value
+			}
+		}
+
+		if message, ok := record[FieldPayloadMessage]; ok {
+			delete(record, FieldPayloadMessage)
+			record[FieldMessage] = message
+		}
+	}
+
+	return record, nil
+}
@@ -0,0 +1,387 @@
+package slogr
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// forwardServer is a fake Fluent Forward input that decodes the messages it
+// receives, and acks the ones that ask for it.
+type forwardServer struct {
+	listener net.Listener
+	messages chan []any
+	// drop closes the connection after the given number of messages
+	drop int
+
+	mu    sync.Mutex
+	conns int
+}
+
+func newForwardServer(t *testing.T) *forwardServer {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := &forwardServer{
+		listener: listener,
+		messages: make(chan []any, 100),
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go server.serve()
+	return server
+}
+
+func (s *forwardServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+
+		go s.handle(conn)
+	}
+}
+
+func (s *forwardServer) handle(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+
+	for count := 1; ; count++ {
+		value, err := readMsgpack(reader)
+		if err != nil {
+			return
+		}
+
+		message, ok := value.([]any)
+		if !ok || len(message) == 0 {
+			return
+		}
+
+		s.messages <- message
+
+		if option, ok := message[len(message)-1].(map[string]any); ok && option["chunk"] != nil {
+			ack := msgpackMap(nil, 1)
+			ack = msgpackString(ack, "ack")
+			ack = msgpackString(ack, option["chunk"].(string))
+
+			if _, err := conn.Write(ack); err != nil {
+				return
+			}
+		}
+
+		if count == s.drop {
+			return
+		}
+	}
+}
+
+// next returns the next message of the server.
+func (s *forwardServer) next(t *testing.T) []any {
+	t.Helper()
+
+	select {
+	case message := <-s.messages:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no message")
+		return nil
+	}
+}
+
+func (s *forwardServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.conns
+}
+
+// readMsgpack reads a value of the types the writer sends. The EventTime
+// extension is read as a time.Time.
+func readMsgpack(r *bufio.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	read := func(n int) ([]byte, error) {
+		data := make([]byte, n)
+		_, err := io.ReadFull(r, data)
+		return data, err
+	}
+
+	length := func(n int) (int, error) {
+		data, err := read(n)
+		if err != nil {
+			return 0, err
+		}
+
+		switch n {
+		case 1:
+			return int(data[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(data)), nil
+		default:
+			return int(binary.BigEndian.Uint32(data)), nil
+		}
+	}
+
+	array := func(n int) (any, error) {
+		value := make([]any, n)
+		for index := range value {
+			if value[index], err = readMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, nil
+	}
+
+	object := func(n int) (any, error) {
+		value := make(map[string]any, n)
+		for index := 0; index < n; index++ {
+			key, err := readMsgpack(r)
+			if err != nil {
+				return nil, err
+			}
+
+			if value[fmt.Sprint(key)], err = readMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+
+		return value, nil
+	}
+
+	var n int
+
+	switch {
+	case code <= 0x7f:
+		return int64(code), nil
+	case code >= 0xe0:
+		return int64(int8(code)), nil
+	case code == 0xd3, code == 0xcf, code == 0xcb:
+		data, err := read(8)
+		if err != nil {
+			return nil, err
+		}
+
+		switch value := binary.BigEndian.Uint64(data); code {
+		case 0xd3:
+			return int64(value), nil
+		case 0xcf:
+			return value, nil
+		default:
+			return math.Float64frombits(value), nil
+		}
+	case code == 0xd7:
+		// the type, the seconds and the nanoseconds
+		data, err := read(9)
+		if err != nil {
+			return nil, err
+		}
+
+		return time.Unix(int64(binary.BigEndian.Uint32(data[1:])), int64(binary.BigEndian.Uint32(data[5:]))), nil
+	case code == 0xc4, code == 0xc5, code == 0xc6:
+		if n, err = length(1 << (code - 0xc4)); err != nil {
+			return nil, err
+		}
+
+		return read(n)
+	case code&0xf0 == 0x90:
+		return array(int(code & 0x0f))
+	case code == 0xdc, code == 0xdd:
+		if n, err = length(2 << (code - 0xdc)); err != nil {
+			return nil, err
+		}
+
+		return array(n)
+	case code&0xf0 == 0x80:
+		return object(int(code & 0x0f))
+	case code == 0xde, code == 0xdf:
+		if n, err = length(2 << (code - 0xde)); err != nil {
+			return nil, err
+		}
+
+		return object(n)
+	}
+
+	// the strings, the booleans and nil are the types of an ack
+	if err := r.UnreadByte(); err != nil {
+		return nil, err
+	}
+
+	return msgpackRead(r)
+}
+
+func TestFluentWriterMessage(t *testing.T) {
+	server := newForwardServer(t)
+
+	writer, err := NewFluentWriter(&FluentOptions{Address: server.listener.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer writer.Close()
+
+	logger := slog.New(NewFluentHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+	logger.Info("forwarded",
+		Name("audit"),
+		slog.Int("count", 2),
+		slog.Float64("ratio", 0.5),
+		slog.Bool("ok", true),
+		slog.Any("tags", []string{"a", "b"}),
+		slog.Any("missing", structpb.NewNullValue()),
+		slog.Group("call", slog.String("method", "GET")),
+	)
+
+	message := server.next(t)
+	if len(message) != 3 {
+		t.Fatalf("got %d elements, want [tag, time, record]", len(message))
+	}
+
+	if got := message[0]; got != "slogr.audit" {
+		t.Errorf("got tag %v, want slogr.audit", got)
+	}
+
+	if _, ok := message[1].(time.Time); !ok {
+		t.Errorf("got time %T, want an EventTime", message[1])
+	}
+
+	record, _ := message[2].(map[string]any)
+
+	for key, want := range map[string]any{
+		"message":  "forwarded",
+		"severity": "INFO",
+		"count":    int64(2),
+		"ratio":    0.5,
+		"ok":       true,
+		"missing":  nil,
+	} {
+		if got, ok := record[key]; !ok || got != want {
+			t.Errorf("got %s %#v, want %#v", key, got, want)
+		}
+	}
+
+	if tags, _ := record["tags"].([]any); len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		t.Errorf("got tags %#v, want [a b]", record["tags"])
+	}
+
+	if call, _ := record["call"].(map[string]any); call["method"] != "GET" {
+		t.Errorf("got call %#v, want the method", record["call"])
+	}
+}
+
+func TestFluentWriterPackedAck(t *testing.T) {
+	server := newForwardServer(t)
+
+	writer, err := NewFluentWriter(&FluentOptions{
+		Address:       server.listener.Addr().String(),
+		Packed:        true,
+		MaxEntries:    3,
+		FlushInterval: time.Hour,
+		RequireAck:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := slog.New(NewFluentHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+	for index := 0; index < 4; index++ {
+		logger.Info("packed", "index", index)
+	}
+
+	// the fourth record is sent by Close
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []int{3, 1} {
+		message := server.next(t)
+		if len(message) != 3 {
+			t.Fatalf("got %d elements, want [tag, entries, option]", len(message))
+		}
+
+		if got := message[0]; got != "slogr" {
+			t.Errorf("got tag %v, want slogr", got)
+		}
+
+		reader := bufio.NewReader(bytes.NewReader(message[1].([]byte)))
+
+		var count int
+		for ; ; count++ {
+			value, err := readMsgpack(reader)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if entry, _ := value.([]any); len(entry) != 2 {
+				t.Fatalf("got entry %#v, want [time, record]", value)
+			}
+		}
+
+		if count != want {
+			t.Errorf("got %d entries, want %d", count, want)
+		}
+	}
+}
+
+func TestFluentWriterReconnect(t *testing.T) {
+	server := newForwardServer(t)
+	server.drop = 1
+
+	writer, err := NewFluentWriter(&FluentOptions{
+		Address:    server.listener.Addr().String(),
+		RequireAck: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer writer.Close()
+
+	handler := NewFluentHandler(writer, &HandlerOptions{Level: slog.LevelInfo})
+	for index, message := range []string{"first", "second"} {
+		// the server closes the connection after the first ack
+		if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, message, 0)); err != nil {
+			t.Fatal(err)
+		}
+
+		if index == 0 {
+			server.next(t)
+		}
+	}
+
+	if record, _ := server.next(t)[2].(map[string]any); record["message"] != "second" {
+		t.Fatalf("got %#v, want the second record", record)
+	}
+
+	if got := server.connections(); got != 2 {
+		t.Fatalf("got %d connections, want 2", got)
+	}
+}
@@ -0,0 +1,146 @@
+package slogr
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// GCTraceKey represents the key of the gctrace group.
+const GCTraceKey = "gctrace"
+
+// RuntimeTraceOptions for the runtime trace logger. A zero RuntimeTraceOptions
+// consists entirely of default values.
+type RuntimeTraceOptions struct {
+	// Name is the log name of the runtime trace entries.
+	// If Name is empty, the logger assumes "runtime_trace".
+	Name string
+
+	// Level reports the level of the runtime trace entries.
+	// If Level is nil, the logger assumes LevelDebug.
+	Level slog.Leveler
+
+	// Interval is the minimum time between two entries. The lines read in
+	// between are dropped and counted on the next entry.
+	// If Interval is zero, the logger assumes 1s.
+	Interval time.Duration
+}
+
+// gcTracePattern matches the lines printed by GODEBUG=gctrace=1, e.g.
+// gc 1 @0.012s 2%: 0.026+0.39+0.010 ms clock, 0.21+0.19/0.32/0.10+0.086 ms cpu, 4->4->0 MB, 4 MB goal, 0 MB stacks, 0 MB globals, 8 P
+var gcTracePattern = regexp.MustCompile(`^gc (\d+) @([\d.]+)s (\d+)%: ([\d.]+)\+([\d.]+)\+([\d.]+) ms clock, \S+ ms cpu, (\d+)->(\d+)->(\d+) MB, (\d+) MB goal, .*?(\d+) P( \(forced\))?$`)
+
+// RuntimeTraceLogger logs the runtime trace lines read from r, such as the
+// stderr of a process run with GODEBUG=gctrace=1, until r is exhausted or the
+// context is cancelled. The gctrace lines are logged as a GCTraceKey group,
+// and the other lines are logged as text.
+//
+// The runtime writes its traces straight to the standard error file, so the
+// process stderr has to be piped into r, e.g. by the process supervisor.
+func RuntimeTraceLogger(ctx context.Context, logger *slog.Logger, r io.Reader, opts *RuntimeTraceOptions) error {
+	if opts == nil {
+		opts = &RuntimeTraceOptions{}
+	}
+
+	name := opts.Name
+	// set the default name
+	if name == "" {
+		name = "runtime_trace"
+	}
+
+	level := slog.LevelDebug
+	// set the configured level
+	if opts.Level != nil {
+		level = opts.Level.Level()
+	}
+
+	interval := opts.Interval
+	// set the default interval
+	if interval <= 0 {
+		interval = time.Second
+	}
+
+	var (
+		last    time.Time
+		dropped int
+	)
+
+	scanner := bufio.NewScanner(r)
+
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		now := time.Now()
+		// rate limit the entries
+		if now.Sub(last) < interval {
+			dropped++
+			continue
+		}
+
+		last = now
+
+		attrs := []slog.Attr{Name(name)}
+		// report the dropped lines
+		if dropped > 0 {
+			attrs = append(attrs, slog.Int("dropped", dropped))
+			dropped = 0
+		}
+
+		if attr, ok := ParseGCTrace(line); ok {
+			logger.LogAttrs(ctx, level, "gc trace", append(attrs, attr)...)
+		} else {
+			logger.LogAttrs(ctx, level, line, attrs...)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// ParseGCTrace returns an Attr for a given gctrace line. It returns false when
+// the line has an unknown format.
+func ParseGCTrace(line string) (slog.Attr, bool) {
+	match := gcTracePattern.FindStringSubmatch(line)
+	if match == nil {
+		return slog.Attr{}, false
+	}
+
+	integer := func(value string) int64 {
+		n, _ := strconv.ParseInt(value, 10, 64)
+		return n
+	}
+
+	duration := func(value string, unit time.Duration) time.Duration {
+		n, _ := strconv.ParseFloat(value, 64)
+		return time.Duration(n * float64(unit))
+	}
+
+	const mb = 1 << 20
+
+	attr := slog.Group(GCTraceKey,
+		slog.Int64("cycle", integer(match[1])),
+		slog.Duration("uptime", duration(match[2], time.Second)),
+		slog.Int64("cpu_percent", integer(match[3])),
+		slog.Duration("sweep_termination_pause", duration(match[4], time.Millisecond)),
+		slog.Duration("concurrent_mark", duration(match[5], time.Millisecond)),
+		slog.Duration("mark_termination_pause", duration(match[6], time.Millisecond)),
+		slog.Int64("heap_start_bytes", integer(match[7])*mb),
+		slog.Int64("heap_end_bytes", integer(match[8])*mb),
+		slog.Int64("heap_live_bytes", integer(match[9])*mb),
+		slog.Int64("heap_goal_bytes", integer(match[10])*mb),
+		slog.Int64("procs", integer(match[11])),
+		slog.Bool("forced", match[12] != ""),
+	)
+
+	return attr, true
+}
@@ -0,0 +1,171 @@
+package slogr
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+const (
+	gcTraceLine   = "gc 7 @1.500s 2%: 0.026+1.5+0.010 ms clock, 0.21+0.19/0.32/0.10+0.086 ms cpu, 4->6->2 MB, 8 MB goal, 0 MB stacks, 0 MB globals, 8 P"
+	gcForcedLine  = "gc 8 @2.000s 3%: 0.5+2+1 ms clock, 0.21+0.19/0.32/0.10+0.086 ms cpu, 12->12->3 MB, 16 MB goal, 0 MB stacks, 0 MB globals, 4 P (forced)"
+	gcUnknownLine = "scvg: 0 MB released"
+)
+
+func TestParseGCTrace(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		line string
+		want map[string]any
+	}{
+		{"gc", gcTraceLine, map[string]any{
+			"cycle":                   float64(7),
+			"uptime":                  (1500 * time.Millisecond).String(),
+			"cpu_percent":             float64(2),
+			"sweep_termination_pause": (26 * time.Microsecond).String(),
+			"concurrent_mark":         (1500 * time.Microsecond).String(),
+			"mark_termination_pause":  (10 * time.Microsecond).String(),
+			"heap_start_bytes":        float64(4 << 20),
+			"heap_end_bytes":          float64(6 << 20),
+			"heap_live_bytes":         float64(2 << 20),
+			"heap_goal_bytes":         float64(8 << 20),
+			"procs":                   float64(8),
+			"forced":                  false,
+		}},
+		{"forced", gcForcedLine, map[string]any{
+			"cycle":                   float64(8),
+			"uptime":                  (2 * time.Second).String(),
+			"cpu_percent":             float64(3),
+			"sweep_termination_pause": (500 * time.Microsecond).String(),
+			"concurrent_mark":         (2 * time.Millisecond).String(),
+			"mark_termination_pause":  time.Millisecond.String(),
+			"heap_start_bytes":        float64(12 << 20),
+			"heap_end_bytes":          float64(12 << 20),
+			"heap_live_bytes":         float64(3 << 20),
+			"heap_goal_bytes":         float64(16 << 20),
+			"procs":                   float64(4),
+			"forced":                  true,
+		}},
+	} {
+		logger, entries := capture(t, nil)
+
+		attr, ok := ParseGCTrace(tc.line)
+		if !ok {
+			t.Fatalf("%s: got no attr", tc.name)
+		}
+
+		logger.Info("gc trace", attr)
+
+		group, _ := payloadOf(single(t, entries()))[GCTraceKey].(map[string]any)
+		if len(group) != len(tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, group, tc.want)
+		}
+
+		for key, want := range tc.want {
+			if got := group[key]; got != want {
+				t.Errorf("%s: got %s %v, want %v", tc.name, key, got, want)
+			}
+		}
+	}
+
+	if _, ok := ParseGCTrace(gcUnknownLine); ok {
+		t.Errorf("got an attr of %q", gcUnknownLine)
+	}
+}
+
+func TestRuntimeTraceLogger(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	input := strings.Join([]string{gcTraceLine, "", gcUnknownLine}, "\n")
+	opts := &RuntimeTraceOptions{Name: "gc", Level: slog.LevelInfo, Interval: time.Nanosecond}
+
+	if err := RuntimeTraceLogger(context.Background(), logger, strings.NewReader(input), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	for index, tc := range []struct {
+		message string
+		gc      bool
+	}{
+		{"gc trace", true},
+		// the unknown lines pass through as text
+		{gcUnknownLine, false},
+	} {
+		payload := payloadOf(got[index])
+		if message := payload[FieldPayloadMessage]; message != tc.message {
+			t.Errorf("entry %d: got message %v, want %v", index, message, tc.message)
+		}
+
+		if _, ok := payload[GCTraceKey]; ok != tc.gc {
+			t.Errorf("entry %d: got gctrace %v, want %v", index, ok, tc.gc)
+		}
+
+		if name := payload[FieldLogName]; name != "gc" {
+			t.Errorf("entry %d: got name %v, want gc", index, name)
+		}
+	}
+}
+
+func TestRuntimeTraceLoggerRateLimit(t *testing.T) {
+	logger, entries := capture(t, nil)
+	reader, writer := io.Pipe()
+
+	opts := &RuntimeTraceOptions{Level: slog.LevelInfo, Interval: 100 * time.Millisecond}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- RuntimeTraceLogger(context.Background(), logger, reader, opts)
+	}()
+
+	// the lines within the interval are dropped
+	io.WriteString(writer, gcTraceLine+"\n"+gcForcedLine+"\n"+gcUnknownLine+"\n")
+	time.Sleep(150 * time.Millisecond)
+	// the next line reports the dropped ones
+	io.WriteString(writer, gcForcedLine+"\n")
+	writer.Close()
+
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	if _, ok := payloadOf(got[0])["dropped"]; ok {
+		t.Errorf("got dropped lines in the first entry")
+	}
+
+	if dropped := payloadOf(got[1])["dropped"]; dropped != float64(2) {
+		t.Errorf("got dropped %v, want 2", dropped)
+	}
+
+	if name := payloadOf(got[1])[FieldLogName]; name != "runtime_trace" {
+		t.Errorf("got name %v, want runtime_trace", name)
+	}
+}
+
+func TestRuntimeTraceLoggerCancel(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := RuntimeTraceLogger(ctx, logger, strings.NewReader(gcTraceLine), &RuntimeTraceOptions{Level: slog.LevelInfo})
+	if err != context.Canceled {
+		t.Errorf("got error %v, want %v", err, context.Canceled)
+	}
+
+	if got := entries(); len(got) != 0 {
+		t.Errorf("got %d entries, want none", len(got))
+	}
+}
@@ -1,36 +1,239 @@
 package slogr
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"path"
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"cloud.google.com/go/logging/apiv2/loggingpb"
 	"go.opentelemetry.io/otel/trace"
 	ltype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
 const (
-	NameKey      = "name"
-	ErrorKey     = "error"
-	LabelKey     = "labels"
-	RequestKey   = "request"
-	ResponseKey  = "response"
-	OperationKey = "operation"
+	NameKey          = "name"
+	ErrorKey         = "error"
+	LabelKey         = "labels"
+	RequestKey       = "request"
+	ResponseKey      = "response"
+	OperationKey     = "operation"
+	AuditKey         = "audit"
+	SpanKey          = "span"
+	DeadlineKey      = "timeout"
+	QueryKey         = "query"
+	RawEntryKey      = "raw_entry"
+	ProtoKey         = "proto"
+	OriginalLevelKey = "original_level"
 )
 
+// ElapsedKey is the payload key of the time elapsed since StartOperation,
+// added to the entry of EndOperation. An attribute of the record with the same
+// key wins over the elapsed time.
+const ElapsedKey = "elapsed"
+
+// spanContextLookups are consulted in order when the context has no
+// OpenTelemetry span. Build tags register optional lookups.
+var spanContextLookups []func(context.Context) trace.SpanContext
+
+// group represents an open group of a handler with its attributes.
+type group struct {
+	name  string
+	attrs []slog.Attr
+}
+
+// with returns the group with the given attributes added.
+func (g group) with(attrs []slog.Attr) group {
+	g.attrs = append(slices.Clip(g.attrs), attrs...)
+	return g
+}
+
+// nest returns the attributes nested in the given groups. The groups without
+// attributes are omitted.
+func nest(groups []group, attrs []slog.Attr) []slog.Attr {
+	for index := len(groups) - 1; index >= 0; index-- {
+		group := groups[index]
+		// nest the attributes in the group
+		attrs = append(slices.Clip(group.attrs), attrs...)
+
+		if len(attrs) > 0 {
+			attrs = []slog.Attr{
+				{Key: group.name, Value: slog.GroupValue(attrs...)},
+			}
+		}
+	}
+
+	return attrs
+}
+
+// reservedKey reports the reserved keys present in a record.
+type reservedKey uint8
+
+const (
+	keyName reservedKey = 1 << iota
+	keyRequest
+	keyOperation
+	keyAudit
+	keyRawEntry
+	keyError
+)
+
+// reservedKeys returns the reserved keys at the top level of a given record
+// in a single pass over its attributes. The error of ErrorWithStack, which is
+// in a group without a key, is an ErrorKey too.
+func reservedKeys(r slog.Record) reservedKey {
+	var keys reservedKey
+
+	r.Attrs(func(attr slog.Attr) bool {
+		switch attr.Key {
+		case NameKey:
+			keys |= keyName
+		case RequestKey, ResponseKey:
+			if isReserved(attr) {
+				keys |= keyRequest
+			}
+		case OperationKey:
+			if isReserved(attr) {
+				keys |= keyOperation
+			}
+		case AuditKey:
+			if isReserved(attr) {
+				keys |= keyAudit
+			}
+		case RawEntryKey:
+			if isReserved(attr) {
+				keys |= keyRawEntry
+			}
+		case ErrorKey:
+			keys |= keyError
+		case "":
+			if isErrorWithStack(attr) {
+				keys |= keyError
+			}
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// isReserved reports whether a given attribute is one of the reserved keys
+// with a value of its attribute function, e.g. a RequestKey attribute of
+// Request. The other values of the reserved keys, such as the groups of the
+// third-party HTTP middlewares, are attributes of the payload.
+func isReserved(attr slog.Attr) bool {
+	switch attr.Key {
+	case NameKey:
+		return true
+	case LabelKey:
+		return attr.Value.Kind() == slog.KindGroup
+	case RequestKey, ResponseKey:
+		value, ok := attr.Value.Any().(*ltype.HttpRequest)
+		return ok && value != nil
+	case OperationKey:
+		switch value := attr.Value.Any().(type) {
+		case *loggingpb.LogEntryOperation:
+			return value != nil
+		case *operationValue:
+			return value != nil
+		}
+	case AuditKey:
+		return isAuditLog(attr)
+	case SpanKey:
+		_, ok := spanContextOfAttr(attr)
+		return ok
+	case RawEntryKey:
+		return isRawEntry(attr)
+	}
+
+	return false
+}
+
+// isRawEntry reports whether a given attribute holds the entry of RawEntry.
+func isRawEntry(attr slog.Attr) bool {
+	entry, ok := attr.Value.Any().(*Entry)
+	return ok && entry != nil
+}
+
+// isErrorWithStack reports whether a given attribute is the one of
+// ErrorWithStack.
+func isErrorWithStack(attr slog.Attr) bool {
+	if attr.Key != "" || attr.Value.Kind() != slog.KindGroup {
+		return false
+	}
+
+	for _, item := range attr.Value.Group() {
+		if _, ok := item.Value.Any().(stackTrace); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// NameField reports where the name of the entries is written when there's no
+// project to build the log name.
+type NameField int
+
+const (
+	// NameFieldPayload writes the name as a logName payload field.
+	NameFieldPayload NameField = iota
+	// NameFieldTopLevel writes the name as a top-level logName field.
+	NameFieldTopLevel
+)
+
+// TracePriority reports how HandlerOptions.TraceFromContext is combined with
+// the span lookup.
+type TracePriority int
+
+const (
+	// TraceReplace uses TraceFromContext instead of the span lookup.
+	TraceReplace TracePriority = iota
+	// TraceBefore uses TraceFromContext and falls back to the span lookup.
+	TraceBefore
+	// TraceAfter uses the span lookup and falls back to TraceFromContext.
+	TraceAfter
+)
+
+// traceInfo represents the trace identity of an entry.
+type traceInfo struct {
+	traceID string
+	spanID  string
+	sampled bool
+}
+
+func newTraceInfo(sctx trace.SpanContext) *traceInfo {
+	return &traceInfo{
+		traceID: sctx.TraceID().String(),
+		spanID:  sctx.SpanID().String(),
+		sampled: sctx.IsSampled(),
+	}
+}
+
 // HandlerOptions for a slog.Handler that writes tinted logs. A zero HandlerOptions consists
 // entirely of default values.
 type HandlerOptions struct {
@@ -40,6 +243,7 @@ type HandlerOptions struct {
 	ProjectID string
 
 	// When AddIndent is true, the handler adds an ident to the JSON output.
+	// It's the Indent of the JSON encoder, when there's no Encoder.
 	AddIndent bool
 
 	// When AddSource is true, the handler adds a ("source", "file:line")
@@ -48,58 +252,621 @@ type HandlerOptions struct {
 	// this information.
 	AddSource bool
 
+	// When AddDeadline is true, the handler adds a Deadline attribute to
+	// every entry whose context carries a deadline.
+	AddDeadline bool
+
 	// Level reports the minimum record level that will be logged.
 	// The handler discards records with lower levels.
 	// If Level is nil, the handler assumes LevelInfo.
 	// The handler calls Level.Level for each record processed;
 	// to adjust the minimum level dynamically, use a LevelVar.
 	Level slog.Leveler
+
+	// ServiceName is the name of the service used in audit entries.
+	ServiceName string
+
+	// ServiceContext is the serviceContext of the reported error events.
+	// If its Service is empty, the handler assumes ServiceName, or the name
+	// of the binary.
+	ServiceContext ServiceContext
+
+	// ElevateOnError is the minimum level of the records with an ErrorKey
+	// attribute at the top level. The handler raises the severity of such
+	// entries below it, and adds their original level as OriginalLevelKey. Only the
+	// enabled records are raised. If ElevateOnError is nil, the levels are
+	// kept.
+	ElevateOnError slog.Leveler
+
+	// StackDepth is the maximum number of frames of the stack traces, such as
+	// the ones of ErrorWithStack and of the reported error events. If
+	// StackDepth is zero, the handler assumes DefaultStackDepth.
+	StackDepth int
+
+	// When ErrorReporting is true, the entries at LevelError or above with an
+	// ErrorKey attribute at the top level are written as Cloud Error Reporting
+	// events: the payload gets the ReportedErrorEvent @type, the
+	// serviceContext, the context with the reportLocation of the source, and
+	// the stack_trace of the log statement after the message.
+	ErrorReporting bool
+
+	// When TraceAlwaysSampled is true, the handler marks every entry with a
+	// trace as sampled, regardless of the sampling decision of the span.
+	TraceAlwaysSampled bool
+
+	// When AnnotateSpans is true, the handler adds the entries at LevelError
+	// or above as events to the recording span of the context.
+	AnnotateSpans bool
+
+	// EmitVendorTraceKeys reports the vendor-neutral trace keys that the
+	// handler adds to the payload in addition to the trace fields.
+	EmitVendorTraceKeys TraceKeys
+
+	// TraceFromContext returns the trace identity from a given context. The
+	// handler formats the trace id into the full trace resource name. When
+	// TraceFromContext is nil, the handler only uses the span lookup.
+	TraceFromContext func(ctx context.Context) (traceID, spanID string, sampled bool, ok bool)
+
+	// TracePriority reports how TraceFromContext is combined with the span
+	// lookup. By default, TraceFromContext replaces the span lookup.
+	TracePriority TracePriority
+
+	// NameField reports where the handler writes the name of the entries when
+	// there's no project. By default, the name is part of the payload.
+	NameField NameField
+
+	// When StrictNames is true, the handler reroutes the entries whose name
+	// was never registered with RegisterLogName to FallbackName, and adds the
+	// original name to the payload.
+	StrictNames bool
+
+	// FallbackName is the log name of the rerouted entries.
+	// If FallbackName is empty, the handler assumes "unregistered".
+	FallbackName string
+
+	// LevelByName reports the minimum level of the entries per log name, as
+	// given to Name. The entries without a name, or with a name that isn't in
+	// LevelByName, use Level.
+	LevelByName map[string]slog.Leveler
+
+	// NameWriters reports the writers of the entries per log name, as given
+	// to Name. A name that ends with "*" is a prefix, and the longest prefix
+	// wins over the shorter ones. An exact name wins over the prefixes. The
+	// entries without a matching name are written to the writer of the
+	// handler. Close closes the writers that implement io.Closer.
+	NameWriters map[string]io.Writer
+
+	// ContextAttrs returns the attributes of a given context, such as the
+	// values of application context keys. The handler calls it once per
+	// entry, and treats its attributes as record attributes, so the reserved
+	// keys like NameKey and LabelKey work too.
+	ContextAttrs func(ctx context.Context) []slog.Attr
+
+	// Labels are added to every entry. The Label attributes and the context
+	// labels win over them.
+	Labels map[string]string
+
+	// LabelOverflowKey is the label that holds the labels beyond the per-entry
+	// label limit of Cloud Logging, as a compact JSON object. If
+	// LabelOverflowKey is empty, the labels are written as is.
+	LabelOverflowKey string
+
+	// BaggageLabels reports the OpenTelemetry baggage members of the context
+	// that the handler adds as labels. The BaggageWildcard item adds every
+	// member, with a cap on the count and the value length. The explicit
+	// Label attributes win over the baggage members.
+	BaggageLabels []string
+
+	// AdaptiveLevel enables the adaptive level, which temporarily lowers the
+	// minimum level after a burst of error entries. If AdaptiveLevel is nil,
+	// the feature is disabled.
+	AdaptiveLevel *AdaptiveLevelOptions
+
+	// SampledLevel is the minimum level of the entries whose context carries
+	// a sampled trace, so that the sampled requests are logged verbosely. If
+	// SampledLevel is nil, the sampling decision doesn't change the level.
+	SampledLevel slog.Leveler
+
+	// Encoding reports how the entries are encoded.
+	// By default, the entries are encoded in JSON.
+	Encoding Encoding
+
+	// Profile reports the field names of the JSON output. By default, the
+	// handler uses the field names of the logging agent format.
+	Profile Profile
+
+	// Encoder encodes the entries instead of the built-in encodings, e.g. in
+	// another format, or to capture the entries in tests. If Encoder is nil,
+	// the handler uses a JSONEncoder with AddIndent, Encoding, Profile and
+	// the top-level log name of NameField. The payloads of a Renderer are
+	// always encoded in JSON.
+	Encoder Encoder
+
+	// ErrorWriter is the writer of the entries at or above ErrorLevel, e.g.
+	// os.Stderr. If ErrorWriter is nil, every entry is written to the writer
+	// of the handler.
+	ErrorWriter io.Writer
+
+	// ErrorLevel reports the minimum level of the entries written to
+	// ErrorWriter. If ErrorLevel is nil, the handler assumes LevelError.
+	ErrorLevel slog.Leveler
+
+	// MaxEntrySize is the maximum estimated size of an entry in bytes. The
+	// largest string values of the payload of a bigger entry are truncated,
+	// and its largest fields are dropped as a last resort, and the payload
+	// gets a TruncatedKey flag. The message, the severity, the trace and the
+	// HTTP request are kept. If MaxEntrySize is zero, the handler assumes
+	// DefaultMaxEntrySize. A negative MaxEntrySize disables the limit.
+	MaxEntrySize int
+
+	// AuditAllowedKeys reports the audit metadata keys that are logged even
+	// though they look like personal data. The handler drops such keys by
+	// default.
+	AuditAllowedKeys []string
+}
+
+// EntryWriter is implemented by the destinations that take the entries
+// instead of their encoding, such as the APIWriter.
+type EntryWriter interface {
+	// WriteEntry writes a given entry.
+	WriteEntry(e *Entry) error
+}
+
+// countingWriter is implemented by the entry writers that queue the entries,
+// and count them by the stats of the handler once they're written or dropped.
+type countingWriter interface {
+	writeEntry(e *Entry, s *stats) error
 }
 
 // Handler implements a [slog.Handler].
+//
+// The reserved keys, such as NameKey, LabelKey and OperationKey, are only
+// recognized at the top level of a record, and only with the values of their
+// attribute functions; any other value of a reserved key is written to the
+// payload. The attributes of a group, either
+// from slog.Group or from WithGroup, are always written to the payload as is,
+// so the groups of third-party attribute producers never collide with them.
+// The empty attributes and groups are ignored, and the attributes of a group
+// without a key are inlined.
+//
+// A Handler and its clones are safe for concurrent use. The clones share the
+// writer, and every entry is written with a single call to it. The options
+// are copied by NewHandler, so changing them afterwards has no effect on the
+// handler. The attributes and labels of a context are scoped to it.
 type Handler struct {
-	leveler slog.Leveler
-	writer  io.Writer
-	project string
-	source  bool
-	indent  bool
-	attr    []slog.Attr
+	leveler  slog.Leveler
+	writer   io.Writer
+	mu       *sync.Mutex
+	errOut   io.Writer
+	errLevel slog.Leveler
+	stats    *stats
+	project  string
+	source   bool
+	indent   bool
+	deadline bool
+	service  string
+	allowed  map[string]bool
+	maxSize  int
+	attr     []slog.Attr
+	groups   []group
+
+	lookupTrace   func(context.Context) (string, string, bool, bool)
+	tracePriority TracePriority
+	traceSampled  bool
+	annotate      bool
+	traceKeys     TraceKeys
+
+	strict       bool
+	fallback     string
+	nameField    NameField
+	levelByName  map[string]slog.Leveler
+	routes       []route
+	labels       map[string]string
+	overflow     string
+	contextAttrs func(context.Context) []slog.Attr
+	baggage      []string
+	reporting    bool
+	serviceCtx   ServiceContext
+	stackDepth   int
+	elevate      slog.Leveler
+	adaptive     *adaptive
+	sampled      slog.Leveler
+	render       Renderer
+	encoding     Encoding
+	encoder      Encoder
+	profile      Profile
+	sink         EntryWriter
+	// overridden reports the clones of route for a project override
+	overridden bool
 }
 
 // NewHandler creates a [slog.Handler] that writes tinted logs to w, using the default
 // options.
 func NewHandler(w io.Writer, opts *HandlerOptions) slog.Handler {
+	h := newEntryHandler(opts)
+	// the state of the output
+	h.writer = w
+	h.mu = &sync.Mutex{}
+	h.errOut = opts.ErrorWriter
+	h.errLevel = opts.ErrorLevel
+	h.stats = &stats{}
+	h.leveler = opts.Level
+	h.levelByName = maps.Clone(opts.LevelByName)
+	h.routes = newRoutes(opts.NameWriters)
+	h.adaptive = newAdaptive(opts.AdaptiveLevel)
+	h.sampled = opts.SampledLevel
+
+	if h.errLevel == nil {
+		h.errLevel = slog.LevelError
+	}
+
+	return h
+}
+
+// NewEntryHandler creates a [slog.Handler] that writes the entries to a given
+// EntryWriter instead of an io.Writer. The options are the ones of NewHandler.
+// The records go through the levels and the stats of Handle as they do with
+// NewHandler.
+func NewEntryHandler(w EntryWriter, opts *HandlerOptions) slog.Handler {
+	h := NewHandler(nil, opts).(*Handler)
+	h.sink = w
+	// done!
+	return h
+}
+
+// newEntryHandler creates a Handler with the state that builds the entries,
+// without the writers, the levels and the stats of NewHandler.
+func newEntryHandler(opts *HandlerOptions) *Handler {
 	h := &Handler{
-		writer:  w,
-		leveler: opts.Level,
-		source:  opts.AddSource,
-		indent:  opts.AddIndent,
-		project: opts.ProjectID,
+		source:   opts.AddSource,
+		indent:   opts.AddIndent,
+		deadline: opts.AddDeadline,
+		project:  opts.ProjectID,
+		service:  opts.ServiceName,
+		allowed:  make(map[string]bool),
+		maxSize:  opts.MaxEntrySize,
+
+		lookupTrace:   opts.TraceFromContext,
+		tracePriority: opts.TracePriority,
+		traceSampled:  opts.TraceAlwaysSampled,
+		annotate:      opts.AnnotateSpans,
+		traceKeys:     opts.EmitVendorTraceKeys,
+
+		strict:       opts.StrictNames,
+		fallback:     opts.FallbackName,
+		nameField:    opts.NameField,
+		labels:       maps.Clone(opts.Labels),
+		overflow:     opts.LabelOverflowKey,
+		contextAttrs: opts.ContextAttrs,
+		baggage:      slices.Clone(opts.BaggageLabels),
+		reporting:    opts.ErrorReporting,
+		serviceCtx:   opts.ServiceContext,
+		stackDepth:   opts.StackDepth,
+		elevate:      opts.ElevateOnError,
+		encoding:     opts.Encoding,
+		encoder:      opts.Encoder,
+		profile:      opts.Profile,
+	}
+
+	if h.fallback == "" {
+		h.fallback = "unregistered"
+	}
+
+	if h.encoder == nil {
+		h.encoder = &JSONEncoder{
+			Indent:     h.indent,
+			EscapeHTML: true,
+			LogName:    h.nameField == NameFieldTopLevel,
+			Encoding:   h.encoding,
+			Profile:    h.profile,
+		}
+	}
+
+	if h.maxSize == 0 {
+		h.maxSize = DefaultMaxEntrySize
+	}
+
+	if h.stackDepth <= 0 {
+		h.stackDepth = DefaultStackDepth
+	}
+
+	if h.serviceCtx.Service == "" {
+		h.serviceCtx.Service = h.service
+	}
+
+	if h.serviceCtx.Service == "" {
+		h.serviceCtx.Service = filepath.Base(os.Args[0])
+	}
+
+	for _, key := range opts.AuditAllowedKeys {
+		h.allowed[key] = true
 	}
 
 	return h
 }
 
 // Enabled implements slog.Handler
-func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
-	return level >= h.leveler.Level()
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.enabled(ctx, level, h.leveler) {
+		return true
+	}
+
+	// the record name is only known by Handle
+	return len(h.levelByName) > 0 && level >= h.minNameLevel()
+}
+
+// enabled reports whether a given level is enabled with a given leveler.
+func (h *Handler) enabled(ctx context.Context, level slog.Level, leveler slog.Leveler) bool {
+	threshold := leveler.Level()
+	// the context level takes precedence
+	if level := levelFromContext(ctx); level != nil {
+		threshold = level.Level()
+	}
+
+	if h.adaptive != nil && h.adaptive.active() {
+		threshold = min(threshold, h.adaptive.level.Level())
+	}
+
+	if level >= threshold {
+		return true
+	}
+
+	// the sampled traces are logged verbosely
+	return h.sampled != nil && level >= h.sampled.Level() && h.isSampled(ctx)
 }
 
 // Handle implements slog.Handler
 func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	if len(h.levelByName) > 0 && !h.enabledByName(ctx, r) {
+		h.stats.drop()
+		return nil
+	}
+
+	if h.adaptive != nil {
+		defer h.adapt(ctx, r)
+	}
+
+	if h.annotate && r.Level >= slog.LevelError {
+		h.annotateSpan(ctx, r)
+	}
+
+	entry, err := h.entry(ctx, r)
+	if err != nil {
+		h.stats.count(severityOf(r.Level), err)
+		return err
+	}
+
+	switch {
+	case h.sink != nil:
+		return h.writeSink(entry)
+	case h.render != nil:
+		// the payload is rendered by another handler
+		err = h.writeRendered(ctx, entry, r)
+	default:
+		err = h.write(entry)
+	}
+
+	h.stats.count(entry.Severity, err)
+	return err
+}
+
+// WriteEntry writes a given entry verbatim, without the record enrichment of
+// Handle. Use it to emit entries that were already built, so their insert id,
+// trace and operation fields are kept as they are.
+func (h *Handler) WriteEntry(e *Entry) error {
+	if h.sink != nil {
+		return h.writeSink(e)
+	}
+
+	err := h.write(e)
+
+	h.stats.count(e.Severity, err)
+	return err
+}
+
+// writeSink writes a given entry to the entry writer of the handler, and
+// counts it unless the writer counts it.
+func (h *Handler) writeSink(e *Entry) error {
+	if w, ok := h.sink.(countingWriter); ok {
+		return w.writeEntry(e, h.stats)
+	}
+
+	err := h.sink.WriteEntry(e)
+	h.stats.count(e.Severity, err)
+	return err
+}
+
+// write encodes a given entry, and writes it with a single call to the
+// writer. The handler and its clones serialize the writes.
+func (h *Handler) write(e *Entry) error {
+	buffer := &bytes.Buffer{}
+	// encode the entry
+	if err := h.encoder.Encode(buffer, e); err != nil {
+		return err
+	}
+
+	return h.flush(buffer, e)
+}
+
+// flush writes the content of a given buffer with a single call to the
+// writer of its entry. That's the route of the entry name, or the error writer
+// for the entries at or above its level, or the writer of the handler.
+func (h *Handler) flush(buffer *bytes.Buffer, e *Entry) error {
+	writer := h.writer
+	// split the output by severity
+	if h.errOut != nil && e.Severity >= severityOf(h.errLevel.Level()) {
+		writer = h.errOut
+	}
+
+	if w := h.writerByName(e.LogName); w != nil {
+		writer = w
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	_, err := writer.Write(buffer.Bytes())
+	return err
+}
+
+// RecordToEntry returns the entry for a given record the way the Handler of
+// the given options builds it in Handle, without encoding it. It returns an
+// error when the payload can't be converted, e.g. with a string that isn't
+// valid UTF-8. It only prepares the state that builds the entry, so the
+// writers, the levels and the stats of the options are ignored.
+func RecordToEntry(ctx context.Context, r slog.Record, opts *HandlerOptions) (*Entry, error) {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+
+	return newEntryHandler(opts).entry(ctx, r)
+}
+
+// Entry returns the entry for a given record the way Handle builds it, without
+// encoding it. When the payload can't be converted, the entry has a text
+// payload with the message instead; use RecordToEntry to get the error.
+// Entry never returns nil.
+func (h *Handler) Entry(ctx context.Context, r slog.Record) *Entry {
+	entry, err := h.entry(ctx, r)
+	if err != nil {
+		entry = h.textEntry(ctx, r)
+	}
+
+	return entry
+}
+
+// textEntry returns the entry of a given record with a text payload of its
+// message. The attributes of the record, of the handler and of the context
+// are all left out, and the invalid UTF-8 is replaced, so it can always be
+// encoded.
+func (h *Handler) textEntry(ctx context.Context, r slog.Record) *Entry {
+	valid := func(value string) string {
+		return strings.ToValidUTF8(value, "\uFFFD")
+	}
+
+	h, failed := h.route(ctx)
+	// drop the attributes
+	r = slog.NewRecord(r.Time, r.Level, valid(r.Message), r.PC)
+
+	entry := &Entry{
+		Severity:       h.severity(ctx, r),
+		Timestamp:      timestamppb.New(r.Time),
+		Labels:         make(map[string]string),
+		SourceLocation: h.location(ctx, r),
+		Payload:        &loggingpb.LogEntry_TextPayload{TextPayload: r.Message},
+	}
+
+	for key, value := range h.label(ctx, r) {
+		entry.Labels[valid(key)] = valid(value)
+	}
+
+	if span := h.trace(ctx, r); span != nil {
+		entry.Trace = h.path("traces", span.traceID)
+		entry.TraceSampled = span.sampled || h.traceSampled
+		entry.SpanId = span.spanID
+	}
+
+	if failed {
+		entry.Labels[RouteFailedLabel] = "true"
+	}
+
+	h.qualify(entry)
+
+	if h.maxSize > 0 {
+		fit(entry, h.maxSize)
+	}
+
+	return entry
+}
+
+// entry builds the entry for a given record. Handle and RecordToEntry both
+// use it.
+func (h *Handler) entry(ctx context.Context, r slog.Record) (*Entry, error) {
+	if reservedKeys(r)&keyRawEntry != 0 {
+		return h.rawEntry(ctx, r)
+	}
+
+	h, failed := h.route(ctx)
+	// prepare the record
 	r = h.record(r)
+	// the record of the caller is cloned before the first attribute is added
+	owned := len(h.attr) > 0 || len(h.groups) > 0
+	add := func(attrs ...slog.Attr) {
+		if len(attrs) == 0 {
+			return
+		}
+
+		if !owned {
+			r = r.Clone()
+			owned = true
+		}
+
+		r.AddAttrs(attrs...)
+	}
+
+	// add the attributes accumulated by the context
+	add(attrsFromContext(ctx)...)
+
+	if h.contextAttrs != nil {
+		add(h.contextAttrs(ctx)...)
+	}
+
+	if h.strict {
+		r = h.reroute(r)
+	}
+
+	if h.deadline {
+		if _, ok := ctx.Deadline(); ok {
+			add(Deadline(ctx))
+		}
+	}
+
+	span := h.trace(ctx, r)
+	// the vendor keys are part of the payload
+	if span != nil && h.traceKeys != 0 {
+		add(h.vendorTraceKeys(span)...)
+	}
+
+	keys := reservedKeys(r)
+	// the record is a copy, so the level of the caller is kept
+	if h.elevate != nil && keys&keyError != 0 && r.Level < h.elevate.Level() {
+		add(slog.String(OriginalLevelKey, r.Level.String()))
+		r.Level = h.elevate.Level()
+	}
 
 	var (
-		name      = h.name(ctx, r)
+		name      string
 		labels    = h.label(ctx, r)
 		severity  = h.severity(ctx, r)
 		location  = h.location(ctx, r)
-		request   = h.request(ctx, r)
-		payload   = h.payload(ctx, r)
-		operation = h.operation(ctx, r)
+		request   *ltype.HttpRequest
+		operation *loggingpb.LogEntryOperation
 		timestamp = timestamppb.New(r.Time)
 	)
 
+	// the errors are reported in the shape of a ReportedErrorEvent
+	if h.reporting && keys&keyError != 0 && r.Level >= slog.LevelError {
+		add(newReportedErrorEvent(r, h.serviceCtx, h.stackDepth).attrs()...)
+	}
+
+	payload, err := h.payload(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	// most records have no reserved keys, so the stages are skipped
+	if keys&(keyName|keyAudit) != 0 {
+		name = h.name(ctx, r)
+	}
+
+	if keys&keyRequest != 0 {
+		request = h.request(ctx, r)
+	}
+
+	if keys&keyOperation != 0 {
+		operation = h.operation(ctx, r)
+	}
+
 	entry := &Entry{
 		LogName:        name,
 		Severity:       severity,
@@ -115,105 +882,251 @@ func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
 		entry.Payload = value
 	case *loggingpb.LogEntry_TextPayload:
 		entry.Payload = value
+	case *loggingpb.LogEntry_ProtoPayload:
+		entry.Payload = value
 	}
 
-	if span := h.trace(ctx, r); span != nil {
-		entry.Trace = h.path("traces", span.TraceID().String())
-		entry.TraceSampled = span.IsSampled()
-		entry.SpanId = span.SpanID().String()
+	if span != nil {
+		entry.Trace = h.path("traces", span.traceID)
+		entry.TraceSampled = span.sampled || h.traceSampled
+		entry.SpanId = span.spanID
 	}
 
-	encoder := json.NewEncoder(h.writer)
-	// enables the pretty format
-	if h.indent {
-		encoder.SetIndent("", "  ")
+	if failed {
+		entry.Labels[RouteFailedLabel] = "true"
 	}
 
-	return encoder.Encode(entry)
+	h.qualify(entry)
+
+	if h.maxSize > 0 {
+		fit(entry, h.maxSize)
+	}
+
+	return entry, nil
+}
+
+// rawEntry returns a copy of the entry of the RawEntry attribute of a given
+// record, with the trace of the context when it has none.
+func (h *Handler) rawEntry(ctx context.Context, r slog.Record) (*Entry, error) {
+	var raw *Entry
+
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == RawEntryKey && isRawEntry(attr) {
+			raw = attr.Value.Any().(*Entry)
+			return false
+		}
+
+		return true
+	})
+
+	switch {
+	case raw.Severity == ltype.LogSeverity_DEFAULT:
+		return nil, errors.New("slogr: the raw entry has no severity")
+	case raw.Timestamp == nil:
+		return nil, errors.New("slogr: the raw entry has no timestamp")
+	}
+
+	entry := EntryFromProto(raw.ToProto())
+	// merge the trace of the context
+	if entry.Trace == "" {
+		if span := h.trace(ctx, r); span != nil {
+			entry.Trace = h.path("traces", span.traceID)
+			entry.TraceSampled = span.sampled || h.traceSampled
+			entry.SpanId = span.spanID
+		}
+	}
+
+	if h.maxSize > 0 {
+		fit(entry, h.maxSize)
+	}
+
+	return entry, nil
 }
 
 // WithAttrs implements slog.Handler
 func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
 	c := h.clone()
-	c.attr = append(c.attr, attrs...)
+
+	if count := len(c.groups); count > 0 {
+		c.groups[count-1] = c.groups[count-1].with(attrs)
+	} else {
+		c.attr = append(slices.Clip(c.attr), attrs...)
+	}
+
 	return c
 }
 
 // WithGroup implements slog.Handler
 func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
 	c := h.clone()
+	c.groups = append(c.groups, group{name: name})
 	return c
 }
 
 func (h *Handler) severity(_ context.Context, r slog.Record) ltype.LogSeverity {
-	switch r.Level {
-	case slog.LevelDebug:
+	level := r.Level
+	// audit entries are at least notice
+	if h.audit(r) != nil {
+		level = max(level, LevelNotice)
+	}
+
+	return severityOf(level)
+}
+
+// severityOf returns the severity of a given level.
+func severityOf(level slog.Level) ltype.LogSeverity {
+	switch {
+	case level < slog.LevelInfo:
 		return ltype.LogSeverity_DEBUG
-	case slog.LevelInfo:
+	case level < LevelNotice:
 		return ltype.LogSeverity_INFO
-	case slog.LevelWarn:
+	case level < slog.LevelWarn:
+		return ltype.LogSeverity_NOTICE
+	case level < slog.LevelError:
 		return ltype.LogSeverity_WARNING
-	case slog.LevelError:
+	case level < LevelCritical:
 		return ltype.LogSeverity_ERROR
+	case level < LevelAlert:
+		return ltype.LogSeverity_CRITICAL
+	case level < LevelEmergency:
+		return ltype.LogSeverity_ALERT
 	default:
-		return ltype.LogSeverity_DEFAULT
+		return ltype.LogSeverity_EMERGENCY
 	}
 }
 
 func (h *Handler) name(_ context.Context, r slog.Record) string {
+	name := h.shortName(r)
+
+	if h.project != "" && name != "" {
+		return h.path("logs", url.PathEscape(name))
+	}
+
+	return name
+}
+
+// shortName returns the unescaped name of a given record.
+func (h *Handler) shortName(r slog.Record) string {
 	var name string
 
-	if h.project != "" {
-		r.Attrs(func(attr slog.Attr) bool {
-			if attr.Key == NameKey {
-				name = h.path("logs", url.PathEscape(attr.Value.String()))
-				return false
-			}
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == NameKey {
+			name = attr.Value.String()
+			return false
+		}
 
-			return true
-		})
+		return true
+	})
+
+	// the name attribute value is escaped
+	if value, err := url.PathUnescape(name); err == nil {
+		name = value
+	}
+
+	// audit entries have a dedicated log name
+	if name == "" && h.audit(r) != nil {
+		name = AuditKey
 	}
 
 	return name
 }
 
-func (h *Handler) payload(_ context.Context, r slog.Record) interface{} {
-	props := make(map[string]interface{})
+func (h *Handler) payload(_ context.Context, r slog.Record) (interface{}, error) {
+	var (
+		props   = make(map[string]interface{})
+		message proto.Message
+		elapsed *operationValue
+	)
 
 	r.Attrs(func(attr slog.Attr) bool {
 		switch attr.Key {
-		case NameKey:
-			return true
-		case LabelKey:
-			return true
-		case RequestKey:
+		case "":
+			// the attributes of a group without a key are inlined
+			h.set(props, attr)
 			return true
-		case ResponseKey:
+		case NameKey, LabelKey, RequestKey, ResponseKey, AuditKey, SpanKey:
+			// any other value is an attribute of the payload
+			if !isReserved(attr) {
+				h.set(props, attr)
+			}
+
 			return true
 		case OperationKey:
+			if value, ok := attr.Value.Any().(*operationValue); ok && value != nil {
+				elapsed = value
+			}
+
+			// any other value is an attribute of the payload
+			if !isReserved(attr) {
+				h.set(props, attr)
+			}
+
+			return true
+		case ProtoKey:
+			value, ok := attr.Value.Any().(proto.Message)
+			// any other value is an attribute of the payload
+			switch {
+			case !ok:
+				h.set(props, attr)
+			case message == nil:
+				message = value
+			}
+
 			return true
 		default:
-			props[attr.Key] = h.value(attr.Value)
+			h.set(props, attr)
 			return true
 		}
 	})
 
+	// the elapsed time doesn't overwrite an attribute
+	if _, ok := props[ElapsedKey]; !ok && elapsed != nil {
+		props[ElapsedKey] = elapsed.elapsed.String()
+	}
+
+	// the name is part of the payload without a project
+	if h.project == "" && h.nameField == NameFieldPayload {
+		if name := h.shortName(r); name != "" {
+			props[FieldLogName] = name
+		}
+	}
+
+	if audit := h.audit(r); audit != nil {
+		return h.auditPayload(audit, r.Message, props)
+	}
+
+	// the proto wins over the other payload attributes
+	if message != nil {
+		value, err := anypb.New(message)
+		if err != nil {
+			return nil, err
+		}
+
+		return &loggingpb.LogEntry_ProtoPayload{
+			ProtoPayload: value,
+		}, nil
+	}
+
 	if count := len(props); count == 0 {
 		return &loggingpb.LogEntry_TextPayload{
 			TextPayload: r.Message,
-		}
+		}, nil
 	}
 
-	props["logging.googleapis.com/message"] = r.Message
+	props[FieldPayloadMessage] = r.Message
 	// construct the payload
 	value, err := structpb.NewStruct(props)
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	return &loggingpb.LogEntry_JsonPayload{
 		JsonPayload: value,
-	}
+	}, nil
 }
 
 func (h *Handler) location(_ context.Context, r slog.Record) *loggingpb.LogEntrySourceLocation {
@@ -238,8 +1151,8 @@ func (h *Handler) request(_ context.Context, r slog.Record) *ltype.HttpRequest {
 	)
 
 	r.Attrs(func(attr slog.Attr) bool {
-		if attr.Key == RequestKey {
-			request, _ = attr.Value.Any().(*ltype.HttpRequest)
+		if attr.Key == RequestKey && isReserved(attr) {
+			request = attr.Value.Any().(*ltype.HttpRequest)
 			// done!
 			count++
 			return false
@@ -249,8 +1162,8 @@ func (h *Handler) request(_ context.Context, r slog.Record) *ltype.HttpRequest {
 	})
 
 	r.Attrs(func(attr slog.Attr) bool {
-		if attr.Key == ResponseKey {
-			response, _ := attr.Value.Any().(*ltype.HttpRequest)
+		if attr.Key == ResponseKey && isReserved(attr) {
+			response := attr.Value.Any().(*ltype.HttpRequest)
 			// merge the request and response
 			request.Status = response.Status
 			request.ResponseSize = response.ResponseSize
@@ -277,8 +1190,14 @@ func (h *Handler) operation(_ context.Context, r slog.Record) *loggingpb.LogEntr
 	var operation *loggingpb.LogEntryOperation
 
 	r.Attrs(func(attr slog.Attr) bool {
-		if attr.Key == OperationKey {
-			operation, _ = attr.Value.Any().(*loggingpb.LogEntryOperation)
+		if attr.Key == OperationKey && isReserved(attr) {
+			switch value := attr.Value.Any().(type) {
+			case *loggingpb.LogEntryOperation:
+				operation = value
+			case *operationValue:
+				operation = value.operation
+			}
+
 			return false
 		}
 
@@ -288,23 +1207,111 @@ func (h *Handler) operation(_ context.Context, r slog.Record) *loggingpb.LogEntr
 	return operation
 }
 
-func (h *Handler) trace(ctx context.Context, _ slog.Record) *trace.SpanContext {
+func (h *Handler) trace(ctx context.Context, r slog.Record) *traceInfo {
 	if h.project != "" {
-		if span := trace.SpanFromContext(ctx); span != nil {
-			if sctx := span.SpanContext(); sctx.IsValid() {
-				return &sctx
+		var sctx trace.SpanContext
+		// the span attribute wins over the context
+		r.Attrs(func(attr slog.Attr) bool {
+			value, ok := spanContextOfAttr(attr)
+			if ok {
+				sctx = value
 			}
+
+			return !ok
+		})
+
+		if sctx.IsValid() {
+			return newTraceInfo(sctx)
+		}
+
+		if h.lookupTrace == nil {
+			return h.span(ctx)
+		}
+
+		switch h.tracePriority {
+		case TraceBefore:
+			if info := h.lookup(ctx); info != nil {
+				return info
+			}
+
+			return h.span(ctx)
+		case TraceAfter:
+			if info := h.span(ctx); info != nil {
+				return info
+			}
+
+			return h.lookup(ctx)
+		default:
+			if info := h.lookup(ctx); info != nil {
+				return info
+			}
+
+			// a trace from WithNewTrace is used when the lookup has none
+			if sctx := syntheticSpanContext(ctx); sctx.IsValid() {
+				return newTraceInfo(sctx)
+			}
+
+			return nil
 		}
 	}
 
 	return nil
 }
 
-func (h *Handler) label(_ context.Context, r slog.Record) map[string]string {
+func (h *Handler) span(ctx context.Context) *traceInfo {
+	if span := trace.SpanFromContext(ctx); span != nil {
+		if sctx := span.SpanContext(); sctx.IsValid() {
+			return newTraceInfo(sctx)
+		}
+	}
+
+	for _, lookup := range spanContextLookups {
+		if sctx := lookup(ctx); sctx.IsValid() {
+			return newTraceInfo(sctx)
+		}
+	}
+
+	if sctx := syntheticSpanContext(ctx); sctx.IsValid() {
+		return newTraceInfo(sctx)
+	}
+
+	return nil
+}
+
+// isSampled reports whether the trace of a given context is sampled.
+func (h *Handler) isSampled(ctx context.Context) bool {
+	if h.lookupTrace != nil {
+		if _, _, sampled, ok := h.lookupTrace(ctx); ok {
+			return sampled
+		}
+	}
+
+	if span := h.span(ctx); span != nil {
+		return span.sampled
+	}
+
+	return false
+}
+
+func (h *Handler) lookup(ctx context.Context) *traceInfo {
+	traceID, spanID, sampled, ok := h.lookupTrace(ctx)
+	// the trace id is mandatory
+	if !ok || strings.TrimSpace(traceID) == "" {
+		return nil
+	}
+
+	return &traceInfo{
+		traceID: url.PathEscape(traceID),
+		spanID:  spanID,
+		sampled: sampled,
+	}
+}
+
+func (h *Handler) label(ctx context.Context, r slog.Record) map[string]string {
 	kv := make(map[string]string)
 
 	r.Attrs(func(attr slog.Attr) bool {
-		if attr.Key == LabelKey {
+		if attr.Key == LabelKey && isReserved(attr) {
 			for _, item := range attr.Value.Group() {
 				for _, label := range h.flatten(item) {
 					kv[label.Key] = label.Value.String()
@@ -317,16 +1324,25 @@ func (h *Handler) label(_ context.Context, r slog.Record) map[string]string {
 		return true
 	})
 
+	// the explicit labels win over the context and the baggage
+	merge(kv, labelsFromContext(ctx))
+	h.baggageLabels(ctx, kv)
+	merge(kv, h.labels)
+
+	if h.overflow != "" {
+		h.fold(kv)
+	}
+
 	return kv
 }
 
 func (h *Handler) path(key ...string) string {
-	path := []string{}
-	path = append(path, "projects")
-	path = append(path, h.project)
-	path = append(path, key...)
-
-	return filepath.Join(path...)
+	elem := []string{}
+	elem = append(elem, "projects")
+	elem = append(elem, h.project)
+	elem = append(elem, key...)
+	// resource names use forward slashes on every platform
+	return path.Join(elem...)
 }
 
 func (h *Handler) value(v slog.Value) interface{} {
@@ -346,6 +1362,18 @@ func (h *Handler) value(v slog.Value) interface{} {
 	case slog.KindTime:
 		return v.Time().String()
 	case slog.KindAny:
+		// the JSON values of the structpb types are kept as they are
+		switch value := v.Any().(type) {
+		case *structpb.Struct:
+			return value.AsMap()
+		case *structpb.ListValue:
+			return value.AsSlice()
+		case *structpb.Value:
+			return value.AsInterface()
+		case stackTrace:
+			return value.format(h.stackDepth)
+		}
+
 		return h.transform(v.Any())
 	case slog.KindLogValuer:
 		return h.value(v.LogValuer().LogValue())
@@ -353,7 +1381,7 @@ func (h *Handler) value(v slog.Value) interface{} {
 		kv := make(map[string]interface{})
 
 		for _, attr := range v.Group() {
-			kv[attr.Key] = h.value(attr.Value)
+			h.set(kv, attr)
 		}
 
 		return kv
@@ -362,6 +1390,33 @@ func (h *Handler) value(v slog.Value) interface{} {
 	}
 }
 
+// set adds a given attribute to kv. The empty attributes and groups are
+// ignored, and the attributes of a group without a key are inlined.
+func (h *Handler) set(kv map[string]interface{}, attr slog.Attr) {
+	attr.Value = attr.Value.Resolve()
+
+	switch {
+	case attr.Equal(slog.Attr{}):
+		return
+	case attr.Value.Kind() == slog.KindGroup:
+		group := attr.Value.Group()
+		// empty groups are ignored
+		if len(group) == 0 {
+			return
+		}
+
+		if attr.Key == "" {
+			for _, item := range group {
+				h.set(kv, item)
+			}
+
+			return
+		}
+	}
+
+	kv[attr.Key] = h.value(attr.Value)
+}
+
 func (h *Handler) transform(v any) any {
 	value := reflect.ValueOf(v)
 
@@ -404,19 +1459,76 @@ func (h *Handler) flatten(attr slog.Attr) []slog.Attr {
 
 func (h *Handler) clone() *Handler {
 	return &Handler{
-		leveler: h.leveler,
-		writer:  h.writer,
-		project: h.project,
-		source:  h.source,
-		indent:  h.indent,
-		attr:    h.attr,
+		leveler:  h.leveler,
+		writer:   h.writer,
+		mu:       h.mu,
+		errOut:   h.errOut,
+		errLevel: h.errLevel,
+		stats:    h.stats,
+		project:  h.project,
+		source:   h.source,
+		indent:   h.indent,
+		deadline: h.deadline,
+		service:  h.service,
+		allowed:  h.allowed,
+		maxSize:  h.maxSize,
+		attr:     h.attr,
+		groups:   slices.Clone(h.groups),
+
+		lookupTrace:   h.lookupTrace,
+		tracePriority: h.tracePriority,
+		traceSampled:  h.traceSampled,
+		annotate:      h.annotate,
+		traceKeys:     h.traceKeys,
+
+		strict:       h.strict,
+		fallback:     h.fallback,
+		nameField:    h.nameField,
+		levelByName:  h.levelByName,
+		routes:       h.routes,
+		labels:       h.labels,
+		overflow:     h.overflow,
+		contextAttrs: h.contextAttrs,
+		baggage:      h.baggage,
+		reporting:    h.reporting,
+		serviceCtx:   h.serviceCtx,
+		stackDepth:   h.stackDepth,
+		elevate:      h.elevate,
+		adaptive:     h.adaptive,
+		sampled:      h.sampled,
+		render:       h.render,
+		encoding:     h.encoding,
+		encoder:      h.encoder,
+		profile:      h.profile,
+		sink:         h.sink,
 	}
 }
 
+// record returns a given record with the attributes and the groups of the
+// handler. The record is copied only when the handler has any.
 func (h *Handler) record(r slog.Record) slog.Record {
-	r = r.Clone()
-	r.AddAttrs(h.attr...)
-	return r
+	if len(h.groups) == 0 {
+		if len(h.attr) > 0 {
+			r = r.Clone()
+			r.AddAttrs(h.attr...)
+		}
+
+		return r
+	}
+
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	// collect the attributes
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	// the reserved keys are only recognized at the top level, so the grouped
+	// attributes never collide with them
+	record.AddAttrs(nest(h.groups, attrs)...)
+	record.AddAttrs(h.attr...)
+	return record
 }
 
 // Name returns an Attr for a log name.
@@ -652,10 +1764,137 @@ func OperationEnd(id, producer string) slog.Attr {
 	}
 }
 
-// Error returns an error attribute
+// Deadline returns an Attr for the deadline of a given context. The group
+// contains the deadline in RFC3339 format and the time remaining until it.
+// An empty Attr is returned when the context has no deadline.
+func Deadline(ctx context.Context) slog.Attr {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return slog.Attr{}
+	}
+
+	return slog.Group(DeadlineKey,
+		slog.String("deadline", deadline.Format(time.RFC3339)),
+		slog.Duration("remaining", time.Until(deadline)),
+	)
+}
+
+// Span returns an Attr for the span context of a given span.
+//
+// Use Span when the span is not present in the context of the log line. The
+// Attr of a nil span is empty, so the handler ignores it.
+func Span(span trace.Span) slog.Attr {
+	if span == nil {
+		return slog.Attr{}
+	}
+
+	return SpanContext(span.SpanContext())
+}
+
+// spanContextOfAttr returns the span context of a given SpanKey attribute. It
+// reports false for the other attributes, and for the values of SpanKey that
+// are neither a span context nor a span.
+func spanContextOfAttr(attr slog.Attr) (trace.SpanContext, bool) {
+	if attr.Key != SpanKey {
+		return trace.SpanContext{}, false
+	}
+
+	switch value := attr.Value.Any().(type) {
+	case trace.SpanContext:
+		return value, true
+	case trace.Span:
+		return value.SpanContext(), true
+	}
+
+	return trace.SpanContext{}, false
+}
+
+// SpanContext returns an Attr for a given span context.
+//
+// Use SpanContext when the span is not present in the context of the log line.
+func SpanContext(sc trace.SpanContext) slog.Attr {
+	return slog.Attr{
+		Key:   SpanKey,
+		Value: slog.AnyValue(sc),
+	}
+}
+
+// Proto returns an attribute that makes a given message the proto payload of
+// the entry, packed in an Any, so its type is kept. The proto payload wins over
+// the other payload attributes and the message of the record, which are left
+// out of the entry. The audit attribute wins over it.
+func Proto(msg proto.Message) slog.Attr {
+	return slog.Attr{
+		Key:   ProtoKey,
+		Value: slog.AnyValue(msg),
+	}
+}
+
+// RawEntry returns an attribute that makes the handler write a given entry
+// instead of the one of the record, e.g. to replay the entries of a dead-letter
+// queue through the routing and the sinks of the handler. The entry is copied,
+// and only its trace is taken from the context when it has none. The entry
+// must have a severity and a timestamp. A nil entry, like any other value of
+// RawEntryKey, is an attribute of the payload.
+func RawEntry(e *Entry) slog.Attr {
+	return slog.Attr{
+		Key:   RawEntryKey,
+		Value: slog.AnyValue(e),
+	}
+}
+
+// Error returns an error attribute. An error that wraps other errors is a
+// group of its message, its causes array with the type and the message of
+// every unwrap step, and its root cause, instead of its string. Use
+// ErrorWithStack for its stack trace.
 func Error(err error) slog.Attr {
 	return slog.Attr{
 		Key:   ErrorKey,
-		Value: slog.StringValue(err.Error()),
+		Value: errorValue(err),
+	}
+}
+
+// Status returns an error attribute with the google.rpc.Status of a given
+// error: a group of its code, its message, and its details in the protobuf
+// JSON format, so the fields of the details, such as the field violations of
+// a BadRequest, can be queried. The errors without a status are the ones of
+// Error. A nil error is an empty attribute, which the handler ignores.
+func Status(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+
+	value, ok := status.FromError(err)
+	if !ok {
+		return Error(err)
+	}
+
+	attrs := []any{
+		slog.Int("code", int(value.Code())),
+		slog.String("message", value.Message()),
+	}
+
+	var details []any
+
+	for _, detail := range value.Proto().GetDetails() {
+		data, err := protojson.Marshal(detail)
+		if err != nil {
+			// the type of the detail is unknown
+			details = append(details, map[string]any{"@type": detail.GetTypeUrl()})
+			continue
+		}
+
+		var item any
+		if err := json.Unmarshal(data, &item); err == nil {
+			details = append(details, item)
+		}
+	}
+
+	if len(details) > 0 {
+		if list, err := structpb.NewList(details); err == nil {
+			attrs = append(attrs, slog.Any("details", list))
+		}
 	}
+
+	return slog.Group(ErrorKey, attrs...)
 }
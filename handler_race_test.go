@@ -0,0 +1,190 @@
+package slogr
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// The tests of this file encode the ownership rules of the handlers, and fail
+// under -race when one of them is broken.
+
+// parallel runs a given function from a number of goroutines, and waits for
+// them.
+func parallel(count int, fn func(index int)) {
+	var group sync.WaitGroup
+
+	for index := 0; index < count; index++ {
+		group.Add(1)
+
+		go func(index int) {
+			defer group.Done()
+			fn(index)
+		}(index)
+	}
+
+	group.Wait()
+}
+
+// the handlers may be cloned and used concurrently
+func TestRaceWithAttrs(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	parallel(50, func(index int) {
+		if index%2 == 0 {
+			logger.Info("direct", "index", index)
+			return
+		}
+
+		logger.With("index", index).WithGroup("call").With("method", "GET").Info("cloned", "attempt", 1)
+	})
+
+	if got := len(entries()); got != 50 {
+		t.Fatalf("got %d entries, want 50", got)
+	}
+}
+
+// the level may be set while the handler checks it
+func TestRaceLevelVar(t *testing.T) {
+	level := &slog.LevelVar{}
+	logger, _ := capture(t, &HandlerOptions{Level: level})
+
+	parallel(50, func(index int) {
+		if index%5 == 0 {
+			level.Set(slog.Level(index % 8))
+			return
+		}
+
+		logger.Info("leveled")
+		logger.Enabled(context.Background(), slog.LevelDebug)
+	})
+}
+
+// the options are immutable after the construction, and the stats may be
+// read while the handler counts
+func TestRaceOptionsAndStats(t *testing.T) {
+	opts := &HandlerOptions{Labels: map[string]string{"env": "prod"}}
+	logger, _ := capture(t, opts)
+
+	handler := logger.Handler().(*Handler)
+
+	parallel(50, func(index int) {
+		switch {
+		case index == 0:
+			// a single writer of the options, which the handler copied
+			for key := 0; key < 100; key++ {
+				opts.Labels[fmt.Sprint("key", key)] = "changed"
+			}
+		case index%2 == 0:
+			handler.Stats()
+		default:
+			logger.Info("labeled")
+		}
+	})
+}
+
+// the context accumulators are scoped to the request
+func TestRaceContextAccumulators(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	ctx := Append(context.Background(), slog.String("service", "books"))
+	ctx = WithLabels(ctx, map[string]string{"env": "prod"})
+
+	parallel(50, func(index int) {
+		worker := fmt.Sprint(index)
+
+		child := Append(ctx, slog.String("worker", worker))
+		child = WithLabels(child, map[string]string{"worker": worker})
+
+		logger.InfoContext(child, "scoped")
+	})
+
+	collection := entries()
+	if len(collection) != 50 {
+		t.Fatalf("got %d entries, want 50", len(collection))
+	}
+
+	// the siblings don't share their attributes and labels
+	for _, entry := range collection {
+		payload := payloadOf(entry)
+		labels, _ := entry[FieldLabels].(map[string]any)
+
+		if len(labels) != 2 || labels["env"] != "prod" || labels["worker"] != payload["worker"] {
+			t.Errorf("got labels %v and payload %v, want the ones of the worker", labels, payload)
+		}
+	}
+}
+
+// the adaptive level may change while the handler checks it
+func TestRaceAdaptiveLevel(t *testing.T) {
+	logger, _ := capture(t, &HandlerOptions{AdaptiveLevel: &AdaptiveLevelOptions{Threshold: 5}})
+
+	parallel(50, func(index int) {
+		if index%2 == 0 {
+			logger.Error("failed")
+			return
+		}
+
+		logger.Debug("verbose")
+	})
+}
+
+// the queue of an async handler is shared by its clones, and may be flushed
+// while they log
+func TestRaceAsyncHandler(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	handler := NewAsyncHandler(logger.Handler(), AsyncOptions{QueuePolicy: QueueBlock})
+
+	parallel(50, func(index int) {
+		if index%10 == 0 {
+			if err := handler.Flush(); err != nil {
+				t.Error(err)
+			}
+
+			return
+		}
+
+		slog.New(handler).With("index", index).Info("queued")
+	})
+
+	if err := handler.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(entries()); got != 45 {
+		t.Fatalf("got %d entries, want 45", got)
+	}
+}
+
+// the sinks own the entries passed to them
+func TestRaceEntryWriter(t *testing.T) {
+	client := &loggingClient{}
+	writer := newTestAPIWriter(t, client, APIWriterOptions{MaxEntries: 7})
+
+	logger := slog.New(NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+
+	parallel(50, func(index int) {
+		ctx := WithLabels(context.Background(), map[string]string{"worker": fmt.Sprint(index)})
+		if index%2 == 0 {
+			ctx = WithProjectOverride(ctx, "tenant-alpha")
+		}
+
+		logger.InfoContext(ctx, "sunk", Name("audit"))
+	})
+
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	for _, size := range client.sizes() {
+		count += size
+	}
+
+	if count != 50 {
+		t.Fatalf("got %d entries, want 50", count)
+	}
+}
@@ -0,0 +1,626 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"go.opentelemetry.io/otel/trace"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// capture returns a logger of a handler with given options that writes to a
+// buffer, and a function that returns the entries written so far. The level
+// of the options defaults to LevelInfo.
+func capture(t *testing.T, opts *HandlerOptions) (*slog.Logger, func() []map[string]any) {
+	t.Helper()
+
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+
+	if opts.Level == nil {
+		opts.Level = slog.LevelInfo
+	}
+
+	buffer := &bytes.Buffer{}
+	logger := NewLogger(buffer, opts)
+
+	return logger, func() []map[string]any {
+		t.Helper()
+		return decodeEntries(t, buffer.Bytes())
+	}
+}
+
+// decodeEntries returns the JSON entries of given data.
+func decodeEntries(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+
+	var entries []map[string]any
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	for decoder.More() {
+		entry := map[string]any{}
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries
+}
+
+// payloadOf returns the JSON payload of a given entry.
+func payloadOf(entry map[string]any) map[string]any {
+	payload, _ := entry["message"].(map[string]any)
+	return payload
+}
+
+// single returns the only entry of given entries.
+func single(t *testing.T, entries []map[string]any) map[string]any {
+	t.Helper()
+
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1", len(entries))
+	}
+
+	return entries[0]
+}
+
+func TestRecordToEntry(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "converted", 0)
+	r.AddAttrs(Name("audit"), Label("env", "test"), slog.Int("count", 2))
+
+	entry, err := RecordToEntry(context.Background(), r, &HandlerOptions{Level: slog.LevelInfo})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Severity != ltype.LogSeverity_WARNING {
+		t.Errorf("got severity %v, want WARNING", entry.Severity)
+	}
+
+	if entry.LogName != "audit" {
+		t.Errorf("got log name %q, want audit", entry.LogName)
+	}
+
+	if got := entry.Labels["env"]; got != "test" {
+		t.Errorf("got label env %q, want test", got)
+	}
+
+	payload := (*loggingpb.LogEntry)(entry).GetJsonPayload().AsMap()
+	if got := payload["count"]; got != 2.0 {
+		t.Errorf("got count %v, want 2", got)
+	}
+
+	if got := payload["logging.googleapis.com/message"]; got != "converted" {
+		t.Errorf("got message %v, want converted", got)
+	}
+}
+
+func TestRecordToEntryWithoutOptions(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "converted", 0)
+
+	entry, err := RecordToEntry(context.Background(), r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := (*loggingpb.LogEntry)(entry).GetTextPayload(); got != "converted" {
+		t.Fatalf("got payload %q, want converted", got)
+	}
+}
+
+func TestRecordToEntryMatchesHandle(t *testing.T) {
+	var (
+		buffer = &bytes.Buffer{}
+		opts   = &HandlerOptions{Level: slog.LevelInfo, Labels: map[string]string{"env": "test"}}
+	)
+
+	r := slog.NewRecord(time.Now(), slog.LevelError, "converted", 0)
+	r.AddAttrs(slog.String("user", "alice"), slog.Group("call", slog.Int("attempt", 3)))
+
+	if err := NewHandler(buffer, opts).Handle(context.Background(), r); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := RecordToEntry(context.Background(), r, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encoded := &bytes.Buffer{}
+	if err := EncodeEntry(encoded, entry, EncodeOptions{EscapeHTML: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := encoded.String(), buffer.String(); got != want {
+		t.Fatalf("got %s, want %s", got, want)
+	}
+}
+
+func TestRecordToEntryInvalidUTF8(t *testing.T) {
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "converted", 0)
+	r.AddAttrs(slog.String("user", "\xff"))
+
+	if _, err := RecordToEntry(context.Background(), r, &HandlerOptions{Level: slog.LevelInfo}); err == nil {
+		t.Fatal("got no error, want one")
+	}
+}
+
+func TestHandlerEntryFallback(t *testing.T) {
+	ctx := Append(context.Background(), slog.String("session", "\xfe"))
+
+	handler := NewHandler(io.Discard, &HandlerOptions{
+		Level:  slog.LevelInfo,
+		Labels: map[string]string{"env": "\xfd"},
+	})
+
+	// the attributes of the handler can't be converted either
+	handler = handler.WithAttrs([]slog.Attr{slog.String("user", "\xff")})
+
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, "invalid \xfc", 0)
+	r.AddAttrs(slog.String("value", "\xfb"))
+
+	entry := handler.(*Handler).Entry(ctx, r)
+	if entry == nil {
+		t.Fatal("got no entry")
+	}
+
+	if got, want := (*loggingpb.LogEntry)(entry).GetTextPayload(), "invalid �"; got != want {
+		t.Errorf("got payload %q, want %q", got, want)
+	}
+
+	if entry.Severity != ltype.LogSeverity_WARNING {
+		t.Errorf("got severity %v, want WARNING", entry.Severity)
+	}
+
+	if err := EncodeEntry(io.Discard, entry, EncodeOptions{}); err != nil {
+		t.Fatalf("the fallback entry can't be encoded: %v", err)
+	}
+}
+
+func TestProto(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("typed", Proto(durationpb.New(time.Second)))
+
+	payload := payloadOf(single(t, entries()))
+	if got, want := payload["@type"], "type.googleapis.com/google.protobuf.Duration"; got != want {
+		t.Errorf("got type %v, want %v", got, want)
+	}
+
+	if got := payload["value"]; got != "1s" {
+		t.Errorf("got value %v, want 1s", got)
+	}
+}
+
+func TestProtoWinsOverAttrs(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("typed", slog.Int("count", 2), Proto(durationpb.New(time.Second)))
+
+	payload := payloadOf(single(t, entries()))
+	if _, ok := payload["count"]; ok {
+		t.Error("got the count attribute in the proto payload")
+	}
+
+	if _, ok := payload["logging.googleapis.com/message"]; ok {
+		t.Error("got the message in the proto payload")
+	}
+}
+
+func TestProtoKeyWithoutMessage(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("plain", slog.String(ProtoKey, "text"))
+
+	payload := payloadOf(single(t, entries()))
+	if got := payload[ProtoKey]; got != "text" {
+		t.Fatalf("got %v, want text", got)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+
+	span := trace.SpanFromContext(trace.ContextWithSpanContext(context.Background(), spanContextOf(1)))
+	logger.Info("span", Span(span))
+	logger.Info("no span", Span(nil))
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	want := "projects/my-project/traces/" + spanContextOf(1).TraceID().String()
+	if got := collection[0][FieldTrace]; got != want {
+		t.Errorf("got trace %v, want %s", got, want)
+	}
+
+	if got, ok := collection[1][FieldTrace]; ok {
+		t.Errorf("got trace %v of a nil span, want none", got)
+	}
+
+	if got := collection[1]["message"]; got != "no span" {
+		t.Errorf("got message %v, want the text of the record", got)
+	}
+}
+
+func TestSpanOfOtherValues(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContextOf(2))
+	// any other value is an attribute of the payload
+	logger.InfoContext(ctx, "kept", slog.String(SpanKey, "db"))
+
+	entry := single(t, entries())
+	if got := payloadOf(entry)[SpanKey]; got != "db" {
+		t.Errorf("got span %v, want db", got)
+	}
+
+	want := "projects/my-project/traces/" + spanContextOf(2).TraceID().String()
+	if got := entry[FieldTrace]; got != want {
+		t.Errorf("got trace %v, want the one of the context %s", got, want)
+	}
+}
+
+// statusRecorder is a ResponseWriter that reports its status and size.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int32
+	size   int64
+}
+
+func (w *statusRecorder) GetStatusCode() int32 { return w.status }
+
+func (w *statusRecorder) GetContentLength() int64 { return w.size }
+
+func TestRequestAndResponse(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	request := httptest.NewRequest(http.MethodPost, "http://internal/books", nil)
+	request.Header.Set("X-Forwarded-Proto", "https")
+	request.Header.Set("X-Forwarded-Host", "example.com")
+	request.Header.Set("X-Forwarded-For", "192.0.2.1")
+	request.Header.Set("Referer", "https://example.com/")
+
+	response := &http.Response{StatusCode: http.StatusCreated, ContentLength: 42}
+
+	logger.Info("served",
+		Request(request, WithLatency(250*time.Millisecond)),
+		Response(response),
+	)
+
+	got, _ := single(t, entries())[FieldHTTPRequest].(map[string]any)
+	for key, want := range map[string]any{
+		"requestMethod": "POST",
+		"requestUrl":    "https://example.com/books",
+		"remoteIp":      "192.0.2.1",
+		"referer":       "https://example.com/",
+		"status":        201.0,
+		"responseSize":  "42",
+		"latency":       "0.250s",
+	} {
+		if got[key] != want {
+			t.Errorf("got %s %#v, want %#v", key, got[key], want)
+		}
+	}
+}
+
+func TestResponseWriter(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	logger.Info("recorded", ResponseWriter(&statusRecorder{status: http.StatusNotFound, size: 9}))
+	logger.Info("plain", ResponseWriter(httptest.NewRecorder(), WithLatency(time.Second)))
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	if got, _ := collection[0][FieldHTTPRequest].(map[string]any); got["status"] != 404.0 || got["responseSize"] != "9" {
+		t.Errorf("got %v, want the status and the size of the writer", got)
+	}
+
+	// the writers without a status only get the options
+	if got, _ := collection[1][FieldHTTPRequest].(map[string]any); got["status"] != nil || got["latency"] != "1s" {
+		t.Errorf("got %v, want the latency only", got)
+	}
+}
+
+// lineWriter records the calls of Write, and whether two of them overlapped.
+type lineWriter struct {
+	active  atomic.Int32
+	overlap atomic.Bool
+
+	mu    sync.Mutex
+	calls [][]byte
+}
+
+// Write implements io.Writer
+func (w *lineWriter) Write(data []byte) (int, error) {
+	if w.active.Add(1) > 1 {
+		w.overlap.Store(true)
+	}
+
+	defer w.active.Add(-1)
+	// give the other writers the time to overlap
+	time.Sleep(10 * time.Microsecond)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.calls = append(w.calls, bytes.Clone(data))
+	return len(data), nil
+}
+
+func TestHandlerConcurrentWrites(t *testing.T) {
+	writer := &lineWriter{}
+	logger := NewLogger(writer, &HandlerOptions{Level: slog.LevelInfo})
+
+	const count = 50
+
+	var group sync.WaitGroup
+	for index := 0; index < count; index++ {
+		group.Add(1)
+
+		// the clones share the writer of the handler
+		go func(logger *slog.Logger) {
+			defer group.Done()
+			logger.Info("concurrent", "payload", strings.Repeat("x", 512))
+		}(logger.With("index", index))
+	}
+
+	group.Wait()
+
+	if writer.overlap.Load() {
+		t.Fatal("got overlapping writes")
+	}
+
+	if len(writer.calls) != count {
+		t.Fatalf("got %d writes, want one per entry", len(writer.calls))
+	}
+
+	for _, data := range writer.calls {
+		single(t, decodeEntries(t, data))
+	}
+}
+
+func TestNewHandlerCopiesOptions(t *testing.T) {
+	opts := &HandlerOptions{
+		Level:         slog.LevelInfo,
+		Labels:        map[string]string{"env": "prod"},
+		BaggageLabels: []string{"tenant"},
+	}
+
+	logger, entries := capture(t, opts)
+	// changing the options has no effect on the handler
+	opts.Labels["env"] = "dev"
+	opts.Labels["region"] = "eu"
+	opts.BaggageLabels[0] = "user"
+
+	logger.Info("copied")
+
+	if labels, _ := single(t, entries())[FieldLabels].(map[string]any); len(labels) != 1 || labels["env"] != "prod" {
+		t.Fatalf("got labels %v, want the labels of the construction", labels)
+	}
+
+	if got := logger.Handler().(*Handler).baggage; got[0] != "tenant" {
+		t.Fatalf("got baggage labels %v, want the labels of the construction", got)
+	}
+}
+
+func TestReservedKeys(t *testing.T) {
+	for _, tc := range []struct {
+		attrs []slog.Attr
+		want  reservedKey
+	}{
+		{nil, 0},
+		{[]slog.Attr{slog.String("user", "ana"), slog.Int("count", 2)}, 0},
+		{[]slog.Attr{Name("audit")}, keyName},
+		{[]slog.Attr{Request(httptest.NewRequest(http.MethodGet, "/", nil))}, keyRequest},
+		{[]slog.Attr{ResponseWriter(httptest.NewRecorder())}, keyRequest},
+		{[]slog.Attr{OperationStart("id", "producer")}, keyOperation},
+		{[]slog.Attr{Error(io.EOF), ErrorWithStack(io.EOF)}, keyError},
+		{[]slog.Attr{Name("audit"), OperationEnd("id", "producer")}, keyName | keyOperation},
+		// the keys of a group are not at the top level
+		{[]slog.Attr{slog.Group("call", Name("audit"), OperationStart("id", "producer"))}, 0},
+	} {
+		r := slog.NewRecord(time.Now(), slog.LevelInfo, "reserved", 0)
+		r.AddAttrs(tc.attrs...)
+
+		if got := reservedKeys(r); got != tc.want {
+			t.Errorf("attrs %v: got keys %b, want %b", tc.attrs, got, tc.want)
+		}
+	}
+}
+
+func TestReservedKeysOfLogger(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	logger.Info("plain", "index", 1)
+	// the attributes of the logger are part of the record
+	logger.With(Name("audit"), OperationStart("id", "producer")).Info("reserved")
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	for _, key := range []string{FieldHTTPRequest, FieldOperation} {
+		if got, ok := collection[0][key]; ok {
+			t.Errorf("got %s %v for a plain record, want none", key, got)
+		}
+	}
+
+	if got, ok := payloadOf(collection[0])[FieldLogName]; ok {
+		t.Errorf("got log name %v for a plain record, want none", got)
+	}
+
+	if got := payloadOf(collection[1])[FieldLogName]; got != "audit" {
+		t.Errorf("got log name %v, want audit", got)
+	}
+
+	if got, _ := collection[1][FieldOperation].(map[string]any); got["id"] != "id" || got["first"] != true {
+		t.Errorf("got operation %v, want the start of id", got)
+	}
+}
+
+func TestRawEntry(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	logger.Info("replayed", RawEntry(&Entry{
+		Severity:  ltype.LogSeverity_WARNING,
+		Timestamp: timestamppb.Now(),
+		Payload:   &loggingpb.LogEntry_TextPayload{TextPayload: "raw"},
+	}))
+
+	entry := single(t, entries())
+	if entry["message"] != "raw" || entry["severity"] != "WARNING" {
+		t.Fatalf("got %v, want the raw entry", entry)
+	}
+}
+
+func TestRawEntryOfOtherValues(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	// any other value is an attribute of the payload
+	logger.Info("kept", slog.String(RawEntryKey, "x"), slog.Int("x", 1))
+	logger.Info("kept", RawEntry(nil))
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	if payload := payloadOf(collection[0]); payload[RawEntryKey] != "x" || payload["x"] != 1.0 {
+		t.Errorf("got payload %v, want the attributes", payload)
+	}
+
+	if payload := payloadOf(collection[1]); payload[FieldPayloadMessage] != "kept" {
+		t.Errorf("got payload %v, want the message of the record", payload)
+	}
+}
+
+func BenchmarkHandlerHandle(b *testing.B) {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo, ProjectID: "my-project"})
+
+	for _, bc := range []struct {
+		name  string
+		attrs []slog.Attr
+	}{
+		{"Plain", []slog.Attr{slog.String("user", "ana"), slog.Int("count", 2), slog.Bool("ok", true)}},
+		{"Reserved", []slog.Attr{Name("audit"), OperationStart("id", "producer"), slog.Int("count", 2)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "benchmark", 0)
+			r.AddAttrs(bc.attrs...)
+
+			b.ReportAllocs()
+			for index := 0; index < b.N; index++ {
+				if err := handler.Handle(context.Background(), r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// entrySink records the entries written to it.
+type entrySink struct {
+	entries []*Entry
+}
+
+// WriteEntry implements EntryWriter
+func (s *entrySink) WriteEntry(e *Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+// builtEntry returns an entry that was built before it reached the handler.
+func builtEntry() *Entry {
+	return &Entry{
+		InsertId:     "insert-42",
+		Severity:     ltype.LogSeverity_WARNING,
+		Timestamp:    timestamppb.New(time.Date(2023, 9, 1, 12, 0, 0, 0, time.UTC)),
+		Trace:        "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		SpanId:       "00f067aa0ba902b7",
+		TraceSampled: true,
+		Operation: &loggingpb.LogEntryOperation{
+			Id:       "job-1",
+			Producer: "books",
+			First:    true,
+			Last:     true,
+		},
+		Payload: &loggingpb.LogEntry_TextPayload{TextPayload: "built"},
+	}
+}
+
+func TestWriteEntry(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	// the handler has everything that Handle would add
+	handler := NewHandler(buffer, &HandlerOptions{
+		ProjectID:          "other-project",
+		Level:              slog.LevelInfo,
+		TraceAlwaysSampled: true,
+		FallbackName:       "handler",
+		Labels:             map[string]string{"env": "test"},
+	}).(*Handler)
+
+	if err := handler.WriteEntry(builtEntry()); err != nil {
+		t.Fatal(err)
+	}
+
+	// the entry is encoded as it is, without the labels, the name or the
+	// project of the handler
+	want := `{"logging.googleapis.com/insertId":"insert-42",` +
+		`"logging.googleapis.com/operation":{"first":true,"id":"job-1","last":true,"producer":"books"},` +
+		`"logging.googleapis.com/spanId":"00f067aa0ba902b7",` +
+		`"logging.googleapis.com/trace":"projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",` +
+		`"logging.googleapis.com/trace_sampled":true,` +
+		`"message":"built","severity":"WARNING","time":"2023-09-01T12:00:00Z"}` + "\n"
+
+	if got := buffer.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestWriteEntrySink(t *testing.T) {
+	sink := &entrySink{}
+
+	handler := NewEntryHandler(sink, &HandlerOptions{
+		ProjectID: "other-project",
+		Level:     slog.LevelInfo,
+		Labels:    map[string]string{"env": "test"},
+	}).(*Handler)
+
+	entry := builtEntry()
+	if err := handler.WriteEntry(entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.entries) != 1 {
+		t.Fatalf("got %d entries in the sink, want 1", len(sink.entries))
+	}
+
+	// the sink takes the entry itself
+	if got := sink.entries[0]; got != entry || !reflect.DeepEqual(got, builtEntry()) {
+		t.Errorf("got entry %v, want %v", got, builtEntry())
+	}
+
+	if got := handler.Stats().Emitted["WARNING"]; got != 1 {
+		t.Errorf("got %d entries in the stats, want 1", got)
+	}
+}
@@ -0,0 +1,268 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+// journalSocket is the path of the native socket of systemd-journald.
+const journalSocket = "/run/systemd/journal/socket"
+
+// JournalOptions for a JournalWriter. A zero JournalOptions consists entirely
+// of default values.
+type JournalOptions struct {
+	// Identifier is the SYSLOG_IDENTIFIER of the entries.
+	// If Identifier is empty, the writer assumes the name of the binary.
+	Identifier string
+
+	// Fallback is the writer of the entries when the journal socket is
+	// absent. The entries are written in the logging agent format then.
+	// If Fallback is nil, the writer assumes os.Stderr.
+	Fallback io.Writer
+}
+
+// JournalWriter writes the entries to systemd-journald with its native
+// protocol. The severity maps to the PRIORITY field, the message is the
+// MESSAGE field, and the payload fields and the labels are uppercase fields,
+// with the invalid characters replaced by underscores. The labels are prefixed
+// by LABEL_. The entries too large for a datagram are passed in a sealed
+// memfd.
+//
+// It degrades to the fallback writer when the journal socket is absent, e.g.
+// on the systems other than Linux, so the binaries stay portable. It's safe
+// for concurrent use.
+type JournalWriter struct {
+	identifier string
+	fallback   io.Writer
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+	err  error
+}
+
+// NewJournalWriter creates a JournalWriter, and connects to the journal
+// socket when it's present.
+func NewJournalWriter(opts *JournalOptions) *JournalWriter {
+	if opts == nil {
+		opts = &JournalOptions{}
+	}
+
+	w := &JournalWriter{
+		identifier: opts.Identifier,
+		fallback:   opts.Fallback,
+	}
+
+	if w.identifier == "" {
+		w.identifier = filepath.Base(os.Args[0])
+	}
+
+	if w.fallback == nil {
+		w.fallback = os.Stderr
+	}
+
+	// the fallback writer is used without the socket
+	w.conn, w.err = journalConnect()
+	// done!
+	return w
+}
+
+// NewJournalHandler creates a [slog.Handler] that writes the entries to a
+// given JournalWriter instead of an io.Writer. The options are the ones of
+// NewHandler.
+func NewJournalHandler(w *JournalWriter, opts *HandlerOptions) slog.Handler {
+	h := NewHandler(nil, opts).(*Handler)
+	h.sink = w
+	// done!
+	return h
+}
+
+// Journal reports whether the writer writes to the journal socket instead of
+// the fallback writer.
+func (w *JournalWriter) Journal() bool {
+	return w.conn != nil
+}
+
+// Err returns the reason why the writer writes to the fallback writer instead
+// of the journal socket: ErrUnsupportedPlatform off linux, or the error of the
+// connection. Err returns nil when the writer writes to the journal.
+func (w *JournalWriter) Err() error {
+	return w.err
+}
+
+// WriteEntry writes a given entry to the journal.
+func (w *JournalWriter) WriteEntry(e *Entry) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		buffer := &bytes.Buffer{}
+		// the fallback is the logging agent format
+		if err := EncodeEntry(buffer, e, EncodeOptions{EscapeHTML: true}); err != nil {
+			return err
+		}
+
+		_, err := w.fallback.Write(buffer.Bytes())
+		return err
+	}
+
+	data, err := w.format(e)
+	if err != nil {
+		return err
+	}
+
+	return journalSend(w.conn, data)
+}
+
+// Close closes the connection to the journal socket.
+func (w *JournalWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// format returns the native protocol message of a given entry.
+func (w *JournalWriter) format(e *Entry) ([]byte, error) {
+	var (
+		buffer  = &bytes.Buffer{}
+		message string
+		fields  = make(map[string]string)
+	)
+
+	switch payload := e.Payload.(type) {
+	case *loggingpb.LogEntry_TextPayload:
+		message = payload.TextPayload
+	case *loggingpb.LogEntry_JsonPayload:
+		for key, value := range payload.JsonPayload.AsMap() {
+			if key == FieldPayloadMessage {
+				message, _ = value.(string)
+				continue
+			}
+
+			if err := journalFlatten(fields, journalName(key), value); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for key, value := range e.Labels {
+		fields["LABEL_"+journalName(key)] = value
+	}
+
+	if location := e.SourceLocation; location != nil {
+		fields["CODE_FILE"] = location.File
+		fields["CODE_LINE"] = strconv.FormatInt(location.Line, 10)
+		fields["CODE_FUNC"] = location.Function
+	}
+
+	if e.Trace != "" {
+		fields["TRACE"] = e.Trace
+		fields["SPAN_ID"] = e.SpanId
+	}
+
+	if e.LogName != "" {
+		fields["LOG_NAME"] = e.LogName
+	}
+
+	// the well-known fields win over the payload fields
+	fields["MESSAGE"] = message
+	fields["PRIORITY"] = strconv.Itoa(syslogSeverity(e.Severity))
+	fields["SYSLOG_IDENTIFIER"] = w.identifier
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		journalField(buffer, key, fields[key])
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// journalField writes a given field. The values with a newline are prefixed
+// by their length instead of terminated by a newline.
+func journalField(buffer *bytes.Buffer, key, value string) {
+	if !strings.Contains(value, "\n") {
+		buffer.WriteString(key + "=" + value + "\n")
+		return
+	}
+
+	buffer.WriteString(key + "\n")
+	buffer.Write(binary.LittleEndian.AppendUint64(nil, uint64(len(value))))
+	buffer.WriteString(value + "\n")
+}
+
+// journalFlatten adds a given value to the fields. The nested fields are
+// joined with underscores, and the other values are JSON.
+func journalFlatten(fields map[string]string, key string, value any) error {
+	switch value := value.(type) {
+	case map[string]any:
+		for name, item := range value {
+			if err := journalFlatten(fields, key+"_"+journalName(name), item); err != nil {
+				return err
+			}
+		}
+	case string:
+		fields[key] = value
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+
+		fields[key] = string(data)
+	}
+
+	return nil
+}
+
+// journalName returns a given key as a journal field name: the uppercase
+// letters, the digits and the underscores, not starting with an underscore or
+// a digit, and at most 64 characters.
+func journalName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	// the fields with a leading underscore are trusted fields
+	name = strings.TrimLeft(name, "_")
+
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "X_" + name
+	}
+
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
@@ -0,0 +1,62 @@
+//go:build linux
+
+package slogr
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// journalConnect connects to the journal socket.
+func journalConnect() (*net.UnixConn, error) {
+	if _, err := os.Stat(journalSocket); err != nil {
+		return nil, err
+	}
+
+	return net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+}
+
+// journalSend sends a given message in a datagram, or in a sealed memfd when
+// it's too large for a datagram.
+func journalSend(conn *net.UnixConn, data []byte) error {
+	_, err := conn.Write(data)
+	if !errors.Is(err, syscall.EMSGSIZE) && !errors.Is(err, syscall.ENOBUFS) {
+		return err
+	}
+
+	fd, err := unix.MemfdCreate("slogr-journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+
+	file := os.NewFile(uintptr(fd), "slogr-journal")
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	// journald only takes sealed memfds
+	if _, err := unix.FcntlInt(file.Fd(), unix.F_ADD_SEALS, unix.F_SEAL_SHRINK|unix.F_SEAL_GROW|unix.F_SEAL_WRITE|unix.F_SEAL_SEAL); err != nil {
+		return err
+	}
+
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	// the connected datagram sockets don't take WriteMsgUnix
+	if cerr := raw.Write(func(socket uintptr) bool {
+		err = unix.Sendmsg(int(socket), nil, unix.UnixRights(int(file.Fd())), nil, 0)
+		return err != unix.EAGAIN
+	}); cerr != nil {
+		return cerr
+	}
+
+	return err
+}
@@ -0,0 +1,21 @@
+//go:build !linux
+
+package slogr
+
+import (
+	"fmt"
+	"net"
+)
+
+// errJournalUnsupported is returned on the systems without systemd-journald.
+var errJournalUnsupported = fmt.Errorf("%w: the journal is only supported on linux", ErrUnsupportedPlatform)
+
+// journalConnect reports that there's no journal socket.
+func journalConnect() (*net.UnixConn, error) {
+	return nil, errJournalUnsupported
+}
+
+// journalSend is never called without a journal socket.
+func journalSend(_ *net.UnixConn, _ []byte) error {
+	return errJournalUnsupported
+}
@@ -0,0 +1,85 @@
+package slogr
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"sort"
+)
+
+// labelLimit is the maximum number of labels of an entry.
+const labelLimit = 64
+
+var labelsKey = &ContextKey{name: "labels"}
+
+// WithLabels provides the labels in a given context, in addition to the ones
+// provided by the parent contexts. The handler adds them to every entry logged
+// with the context. The Label attributes of the entry win over the context
+// labels, which win over HandlerOptions.Labels.
+func WithLabels(ctx context.Context, kv map[string]string) context.Context {
+	if len(kv) == 0 {
+		return ctx
+	}
+
+	parent := labelsFromContext(ctx)
+	// copy on write, so the siblings don't share the labels
+	labels := make(map[string]string, len(parent)+len(kv))
+	maps.Copy(labels, parent)
+	maps.Copy(labels, kv)
+	// done!
+	return context.WithValue(ctx, labelsKey, labels)
+}
+
+func labelsFromContext(ctx context.Context) map[string]string {
+	if labels, ok := ctx.Value(labelsKey).(map[string]string); ok {
+		return labels
+	}
+
+	return nil
+}
+
+// merge adds the labels of a given map that are not in kv yet.
+func merge(kv, labels map[string]string) {
+	for k, v := range labels {
+		if _, ok := kv[k]; !ok {
+			kv[k] = v
+		}
+	}
+}
+
+// fold moves the labels beyond the label limit into the overflow label as a
+// JSON object. The labels are kept in key order, so the fold is deterministic.
+func (h *Handler) fold(kv map[string]string) {
+	if len(kv) <= labelLimit {
+		return
+	}
+
+	keys := make([]string, 0, len(kv))
+	// the overflow label is always folded
+	for key := range kv {
+		if key != h.overflow {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	folded := make(map[string]string)
+	// keep a slot for the overflow label
+	for _, key := range keys[labelLimit-1:] {
+		folded[key] = kv[key]
+		delete(kv, key)
+	}
+
+	if value, ok := kv[h.overflow]; ok {
+		folded[h.overflow] = value
+	}
+
+	// the keys of a map are sorted by the encoder
+	data, err := json.Marshal(folded)
+	if err != nil {
+		return
+	}
+
+	kv[h.overflow] = string(data)
+}
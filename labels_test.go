@@ -0,0 +1,151 @@
+package slogr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+// manyLabels returns a Label attribute with a given number of labels.
+func manyLabels(count int) slog.Attr {
+	attrs := make([]any, 0, count)
+	for index := 0; index < count; index++ {
+		attrs = append(attrs, slog.String(fmt.Sprintf("key%02d", index), "value"))
+	}
+
+	return Label(attrs...)
+}
+
+func TestLabelOverflow(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{LabelOverflowKey: "overflow"})
+	logger.Info("folded", manyLabels(labelLimit+6))
+
+	labels, _ := single(t, entries())[FieldLabels].(map[string]any)
+	if len(labels) != labelLimit {
+		t.Fatalf("got %d labels, want %d", len(labels), labelLimit)
+	}
+
+	folded := make(map[string]string)
+	if err := json.Unmarshal([]byte(labels["overflow"].(string)), &folded); err != nil {
+		t.Fatal(err)
+	}
+
+	// the labels beyond the limit are the last ones in key order
+	if len(folded) != 7 || folded["key63"] != "value" || folded["key69"] != "value" {
+		t.Fatalf("got folded labels %v, want key63 to key69", folded)
+	}
+
+	if _, ok := labels["key62"]; !ok {
+		t.Fatalf("got labels %v, want key62 kept", labels)
+	}
+}
+
+func TestLabelsWithoutOverflowKey(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("unfolded", manyLabels(labelLimit+6))
+
+	if labels, _ := single(t, entries())[FieldLabels].(map[string]any); len(labels) != labelLimit+6 {
+		t.Fatalf("got %d labels, want them as is", len(labels))
+	}
+}
+
+func TestWithLabels(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{
+		Labels: map[string]string{"env": "prod", "region": "eu", "tier": "web"},
+	})
+
+	ctx := WithLabels(context.Background(), map[string]string{"region": "us", "tenant": "acme"})
+	child := WithLabels(ctx, map[string]string{"tenant": "globex"})
+
+	logger.InfoContext(child, "labeled", Label("tier", "api"))
+	logger.InfoContext(ctx, "labeled")
+
+	collection := entries()
+	if len(collection) != 2 {
+		t.Fatalf("got %d entries, want 2", len(collection))
+	}
+
+	for index, want := range []map[string]string{
+		{"env": "prod", "region": "us", "tier": "api", "tenant": "globex"},
+		// the labels of the child context aren't shared with the parent
+		{"env": "prod", "region": "us", "tier": "web", "tenant": "acme"},
+	} {
+		labels, _ := collection[index][FieldLabels].(map[string]any)
+		if len(labels) != len(want) {
+			t.Errorf("got labels %v, want %v", labels, want)
+			continue
+		}
+
+		for key, value := range want {
+			if labels[key] != value {
+				t.Errorf("got labels %v, want %v", labels, want)
+			}
+		}
+	}
+}
+
+func TestWithLabelsCopyOnWrite(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		kv   map[string]string
+		want map[string]string
+	}{
+		{"nil", nil, map[string]string{"env": "prod"}},
+		{"empty", map[string]string{}, map[string]string{"env": "prod"}},
+		{"override", map[string]string{"env": "dev"}, map[string]string{"env": "dev"}},
+		{"added", map[string]string{"tenant": "acme"}, map[string]string{"env": "prod", "tenant": "acme"}},
+	} {
+		kv := map[string]string{"env": "prod"}
+		parent := WithLabels(context.Background(), kv)
+		// the labels of a context are copied
+		kv["env"] = "changed"
+
+		child := WithLabels(parent, tc.kv)
+		for key := range tc.kv {
+			tc.kv[key] = "changed"
+		}
+
+		if got := labelsFromContext(parent); len(got) != 1 || got["env"] != "prod" {
+			t.Errorf("%s: got parent labels %v, want env=prod", tc.name, got)
+		}
+
+		got := labelsFromContext(child)
+		if len(got) != len(tc.want) {
+			t.Errorf("%s: got labels %v, want %v", tc.name, got, tc.want)
+			continue
+		}
+
+		for key, value := range tc.want {
+			if got[key] != value {
+				t.Errorf("%s: got labels %v, want %v", tc.name, got, tc.want)
+			}
+		}
+	}
+}
+
+func BenchmarkWithLabels(b *testing.B) {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo})
+
+	for _, bc := range []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"Without", context.Background()},
+		{"With", WithLabels(context.Background(), map[string]string{"tenant": "acme", "region": "eu"})},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			r := slog.NewRecord(time.Now(), slog.LevelInfo, "labeled", 0)
+
+			b.ReportAllocs()
+			for index := 0; index < b.N; index++ {
+				if err := handler.Handle(bc.ctx, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
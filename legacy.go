@@ -0,0 +1,61 @@
+package slogr
+
+import (
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// legacyFields returns the fields of the entry in the legacy Stackdriver
+// format, which has a textPayload and a metadata object.
+func (x *Entry) legacyFields(opts EncodeOptions) (map[string]interface{}, error) {
+	var (
+		message  string
+		metadata = make(map[string]interface{})
+	)
+
+	switch payload := x.GetPayload().(type) {
+	case string:
+		message = payload
+	case *structpb.Struct:
+		message = payload.GetFields()[FieldPayloadMessage].GetStringValue()
+	}
+
+	if service := opts.ServiceName; service != "" {
+		metadata["serviceContext"] = map[string]interface{}{
+			"service": service,
+		}
+	}
+
+	if len(x.Labels) > 0 {
+		metadata["labels"] = x.Labels
+	}
+
+	if x.Operation != nil {
+		data, err := protojson.Marshal(x.Operation)
+		if err != nil {
+			return nil, err
+		}
+
+		value := &structpb.Struct{}
+		// convert the data to a struct
+		if err = protojson.Unmarshal(data, value); err != nil {
+			return nil, err
+		}
+
+		metadata["operation"] = value.AsMap()
+	}
+
+	attributes := map[string]interface{}{
+		"textPayload": message,
+		"severity":    x.Severity.String(),
+		"metadata":    metadata,
+	}
+
+	if x.Timestamp != nil {
+		attributes["timestamp"] = x.Timestamp.AsTime().Format(time.RFC3339Nano)
+	}
+
+	return attributes, nil
+}
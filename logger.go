@@ -1,11 +1,18 @@
 package slogr
 
 import (
+	"context"
 	"io"
+	"os"
+	"runtime"
+	"time"
 
 	"log/slog"
 )
 
+// Exit terminates the program after Fatal. Replace it in tests.
+var Exit = os.Exit
+
 // NewLogger crates a new logger instance.
 func NewLogger(w io.Writer, options *HandlerOptions) *slog.Logger {
 	// prepare the handler
@@ -15,3 +22,39 @@ func NewLogger(w io.Writer, options *HandlerOptions) *slog.Logger {
 	// done!
 	return logger
 }
+
+// Fatal logs a given message at LevelCritical, flushes the handler of the
+// logger when it implements Flusher, and calls Exit(1).
+func Fatal(ctx context.Context, logger *slog.Logger, msg string, attrs ...slog.Attr) {
+	logAt(ctx, logger, LevelCritical, msg, attrs)
+	// flush the pending entries
+	if flusher, ok := logger.Handler().(Flusher); ok {
+		_ = flusher.Flush()
+	}
+
+	Exit(1)
+}
+
+// Panic logs a given message at LevelAlert, and panics with the message.
+func Panic(ctx context.Context, logger *slog.Logger, msg string, attrs ...slog.Attr) {
+	logAt(ctx, logger, LevelAlert, msg, attrs)
+	panic(msg)
+}
+
+// logAt logs a record with the source location of the caller of the helper.
+func logAt(ctx context.Context, logger *slog.Logger, level slog.Level, msg string, attrs []slog.Attr) {
+	handler := logger.Handler()
+	// the level is disabled
+	if !handler.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, logAt, helper]
+	runtime.Callers(3, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+	r.AddAttrs(attrs...)
+	// the record is best effort
+	_ = handler.Handle(ctx, r)
+}
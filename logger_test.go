@@ -0,0 +1,103 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// flushHandler records the flushes of a handler.
+type flushHandler struct {
+	slog.Handler
+	events *[]string
+}
+
+// Flush implements Flusher
+func (h *flushHandler) Flush() error {
+	*h.events = append(*h.events, "flush")
+	return nil
+}
+
+func TestFatal(t *testing.T) {
+	var events []string
+
+	exit := Exit
+	defer func() { Exit = exit }()
+
+	Exit = func(code int) {
+		events = append(events, "exit")
+
+		if code != 1 {
+			t.Errorf("got exit code %d, want 1", code)
+		}
+	}
+
+	logger, entries := capture(t, &HandlerOptions{AddSource: true})
+	logger = slog.New(&flushHandler{Handler: logger.Handler(), events: &events})
+
+	Fatal(context.Background(), logger, "giving up", slog.Int("attempts", 3))
+
+	entry := single(t, entries())
+	if got := entry["severity"]; got != "CRITICAL" {
+		t.Errorf("got severity %v, want CRITICAL", got)
+	}
+
+	if got := payloadOf(entry)["attempts"]; got != float64(3) {
+		t.Errorf("got attempts %v, want 3", got)
+	}
+
+	// the source location is the caller of Fatal
+	source, _ := entry[FieldSourceLocation].(map[string]any)
+	if file, _ := source["file"].(string); !strings.HasSuffix(file, "logger_test.go") {
+		t.Errorf("got source file %v, want logger_test.go", source["file"])
+	}
+
+	// the entries are flushed before the exit
+	if got := strings.Join(events, ","); got != "flush,exit" {
+		t.Errorf("got events %s, want flush,exit", got)
+	}
+}
+
+func TestFatalDisabled(t *testing.T) {
+	var code int
+
+	exit := Exit
+	defer func() { Exit = exit }()
+
+	Exit = func(value int) { code = value }
+
+	logger, entries := capture(t, &HandlerOptions{Level: LevelEmergency})
+	Fatal(context.Background(), logger, "giving up")
+
+	if got := entries(); len(got) != 0 {
+		t.Errorf("got %d entries, want none", len(got))
+	}
+
+	// the program exits even without the entry
+	if code != 1 {
+		t.Errorf("got exit code %d, want 1", code)
+	}
+}
+
+func TestPanic(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	defer func() {
+		if got := recover(); got != "corrupted state" {
+			t.Errorf("got panic %v, want corrupted state", got)
+		}
+
+		entry := single(t, entries())
+		if got := entry["severity"]; got != "ALERT" {
+			t.Errorf("got severity %v, want ALERT", got)
+		}
+
+		if got := payloadOf(entry)[FieldPayloadMessage]; got != "corrupted state" {
+			t.Errorf("got message %v, want corrupted state", got)
+		}
+	}()
+
+	Panic(context.Background(), logger, "corrupted state", slog.String("table", "books"))
+	t.Error("got no panic")
+}
@@ -0,0 +1,157 @@
+// Package logrslog provides a [logr.LogSink] that writes the logr records to
+// a [slog.Handler], such as the handler of slogr, so the libraries that take a
+// logr.Logger, e.g. controller-runtime, write Google Cloud Logging entries.
+package logrslog
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/ralch/slogr"
+)
+
+var (
+	_ logr.LogSink          = &LogSink{}
+	_ logr.CallDepthLogSink = &LogSink{}
+)
+
+// LogSink implements a [logr.LogSink].
+//
+// The V-levels are below slog.LevelDebug: V(0) is slog.LevelInfo, V(1) is
+// slog.LevelDebug, and V(n) is slog.LevelDebug-(n-1), so they are all DEBUG
+// entries. The Error records are at slog.LevelError with a slogr.Error
+// attribute. The values are the attributes of the handler, and the names are
+// joined with slashes into the log name of the entries, as given to
+// slogr.Name. The logr.Marshaler values are logged as their MarshalLog value.
+type LogSink struct {
+	handler slog.Handler
+	name    string
+	depth   int
+}
+
+// NewLogSink creates a LogSink that writes to a given handler.
+func NewLogSink(handler slog.Handler) *LogSink {
+	return &LogSink{handler: handler}
+}
+
+// NewLogger creates a [logr.Logger] that writes to a given handler.
+func NewLogger(handler slog.Handler) logr.Logger {
+	return logr.New(NewLogSink(handler))
+}
+
+// Init implements logr.LogSink
+func (s *LogSink) Init(info logr.RuntimeInfo) {
+	s.depth += info.CallDepth
+}
+
+// Enabled implements logr.LogSink
+func (s *LogSink) Enabled(level int) bool {
+	return s.handler.Enabled(context.Background(), Level(level))
+}
+
+// Info implements logr.LogSink
+func (s *LogSink) Info(level int, msg string, keysAndValues ...any) {
+	s.log(Level(level), msg, nil, keysAndValues)
+}
+
+// Error implements logr.LogSink
+func (s *LogSink) Error(err error, msg string, keysAndValues ...any) {
+	s.log(slog.LevelError, msg, err, keysAndValues)
+}
+
+// WithValues implements logr.LogSink
+func (s *LogSink) WithValues(keysAndValues ...any) logr.LogSink {
+	c := *s
+	c.handler = s.handler.WithAttrs(attrs(keysAndValues))
+	return &c
+}
+
+// WithName implements logr.LogSink
+func (s *LogSink) WithName(name string) logr.LogSink {
+	c := *s
+	// the names are joined as in logr
+	if c.name != "" {
+		name = c.name + "/" + name
+	}
+
+	c.name = name
+	return &c
+}
+
+// WithCallDepth implements logr.CallDepthLogSink
+func (s *LogSink) WithCallDepth(depth int) logr.LogSink {
+	c := *s
+	c.depth += depth
+	return &c
+}
+
+// Level returns the slog level of a given V-level.
+func Level(level int) slog.Level {
+	if level <= 0 {
+		return slog.LevelInfo
+	}
+
+	return slog.LevelDebug - slog.Level(level-1)
+}
+
+// log writes a record at a given level.
+func (s *LogSink) log(level slog.Level, msg string, err error, keysAndValues []any) {
+	ctx := context.Background()
+	// logr checks Enabled for Info only
+	if !s.handler.Enabled(ctx, level) {
+		return
+	}
+
+	var pcs [1]uintptr
+	// skip [runtime.Callers, log, Info or Error] and the frames of logr
+	runtime.Callers(3+s.depth, pcs[:])
+
+	r := slog.NewRecord(time.Now(), level, msg, pcs[0])
+
+	if s.name != "" {
+		r.AddAttrs(slogr.Name(s.name))
+	}
+
+	if err != nil {
+		r.AddAttrs(slogr.Error(err))
+	}
+
+	r.AddAttrs(attrs(keysAndValues)...)
+	// the record is best effort
+	_ = s.handler.Handle(ctx, r)
+}
+
+// attrs returns the attributes of given logr key and value pairs.
+func attrs(keysAndValues []any) []slog.Attr {
+	attrs := make([]slog.Attr, 0, (len(keysAndValues)+1)/2)
+
+	for index := 0; index < len(keysAndValues); index += 2 {
+		key, ok := keysAndValues[index].(string)
+		if !ok {
+			key = fmt.Sprint(keysAndValues[index])
+		}
+
+		// a key without a value, as in slog
+		if index+1 == len(keysAndValues) {
+			attrs = append(attrs, slog.Any("!BADKEY", keysAndValues[index]))
+			break
+		}
+
+		attrs = append(attrs, slog.Any(key, value(keysAndValues[index+1])))
+	}
+
+	return attrs
+}
+
+// value returns a given value, or its MarshalLog value.
+func value(v any) any {
+	if marshaler, ok := v.(logr.Marshaler); ok {
+		return marshaler.MarshalLog()
+	}
+
+	return v
+}
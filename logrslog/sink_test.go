@@ -0,0 +1,165 @@
+package logrslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"runtime"
+	"strconv"
+	"testing"
+
+	"github.com/go-logr/logr"
+	"github.com/ralch/slogr"
+)
+
+type secret string
+
+func (secret) MarshalLog() any { return "redacted" }
+
+// capture returns a logger that writes to a buffer, and a function that
+// returns the entries written so far.
+func capture(t *testing.T, level slog.Level) (logr.Logger, func() []map[string]any) {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	handler := slogr.NewHandler(buffer, &slogr.HandlerOptions{Level: level, AddSource: true})
+
+	return NewLogger(handler), func() []map[string]any {
+		var entries []map[string]any
+
+		decoder := json.NewDecoder(bytes.NewReader(buffer.Bytes()))
+		for decoder.More() {
+			entry := map[string]any{}
+			if err := decoder.Decode(&entry); err != nil {
+				t.Fatal(err)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+}
+
+func payload(entry map[string]any) map[string]any {
+	value, _ := entry["message"].(map[string]any)
+	return value
+}
+
+func TestLevel(t *testing.T) {
+	cases := []struct {
+		level int
+		want  slog.Level
+	}{
+		{level: -1, want: slog.LevelInfo},
+		{level: 0, want: slog.LevelInfo},
+		{level: 1, want: slog.LevelDebug},
+		{level: 2, want: slog.LevelDebug - 1},
+		{level: 5, want: slog.LevelDebug - 4},
+	}
+
+	for _, item := range cases {
+		if got := Level(item.level); got != item.want {
+			t.Errorf("Level(%d) = %v, want %v", item.level, got, item.want)
+		}
+	}
+}
+
+func TestLogSinkVerbosity(t *testing.T) {
+	logger, entries := capture(t, slog.LevelDebug-1)
+
+	logger.Info("info")
+	logger.V(1).Info("v1")
+	logger.V(2).Info("v2")
+	logger.V(3).Info("v3")
+
+	if !logger.V(2).Enabled() || logger.V(3).Enabled() {
+		t.Fatal("the enabled V-levels don't follow the handler level")
+	}
+
+	got := entries()
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3", len(got))
+	}
+
+	for index, severity := range []string{"INFO", "DEBUG", "DEBUG"} {
+		if got[index]["severity"] != severity {
+			t.Errorf("entry %d: severity %v, want %s", index, got[index]["severity"], severity)
+		}
+	}
+}
+
+func TestLogSinkValues(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo)
+
+	logger.WithName("controller").WithName("reconciler").
+		WithValues("pod", "web-1").
+		Info("reconciled", "count", 2, "token", secret("value"), "dangling")
+
+	got := entries()
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got))
+	}
+
+	fields := payload(got[0])
+
+	want := map[string]any{
+		"logName": "controller/reconciler",
+		"pod":     "web-1",
+		"count":   float64(2),
+		"token":   "redacted",
+		"!BADKEY": "dangling",
+	}
+
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s = %v, want %v", key, fields[key], value)
+		}
+	}
+}
+
+func TestLogSinkError(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo)
+
+	logger.Error(errors.New("boom"), "failed", "attempt", 3)
+	logger.Error(nil, "failed without an error")
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	if got[0]["severity"] != "ERROR" || payload(got[0])["error"] != "boom" {
+		t.Errorf("unexpected error entry: %v", got[0])
+	}
+
+	if _, ok := payload(got[1])["error"]; ok {
+		t.Errorf("unexpected error attribute: %v", got[1])
+	}
+}
+
+// helper logs on behalf of its caller.
+func helper(logger logr.Logger) {
+	logger.WithCallDepth(1).Info("from the helper")
+}
+
+func TestLogSinkCallDepth(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo)
+
+	_, _, line, _ := runtime.Caller(0)
+	logger.Info("direct")
+	helper(logger)
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	for index, want := range []int{line + 1, line + 2} {
+		location, _ := got[index]["logging.googleapis.com/sourceLocation"].(map[string]any)
+		if location["line"] != strconv.Itoa(want) {
+			t.Errorf("entry %d: line %v, want %d", index, location["line"], want)
+		}
+	}
+}
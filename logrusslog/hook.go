@@ -0,0 +1,109 @@
+// Package logrusslog provides a [logrus.Hook] that writes the logrus entries
+// to a [slog.Logger], such as a logger of slogr, so the services that still
+// log with logrus write Google Cloud Logging entries.
+package logrusslog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sort"
+
+	"github.com/ralch/slogr"
+	"github.com/sirupsen/logrus"
+)
+
+var _ logrus.Hook = &Hook{}
+
+// Hook implements a [logrus.Hook].
+//
+// The logrus levels map to the slog levels, Trace to 4 below
+// slog.LevelDebug, Fatal to slogr.LevelCritical, and Panic to
+// slogr.LevelAlert. The fields are the attributes of the records, and the
+// logrus.ErrorKey field is the attribute of slogr.Error. The caller is the
+// source location of the entries when the logrus logger reports it.
+//
+// The hook mirrors the output of logrus, which still writes its own entries,
+// unless the logrus output is discarded, as with Replace. Logrus only fires
+// the hooks of its enabled levels, so the level of the logrus logger applies
+// before the one of the slog handler.
+type Hook struct {
+	handler slog.Handler
+}
+
+// NewHook creates a Hook that writes to a given logger.
+func NewHook(logger *slog.Logger) *Hook {
+	return &Hook{handler: logger.Handler()}
+}
+
+// Replace adds a Hook that writes to a given logger to a logrus logger, and
+// discards the output of the logrus logger, so every entry is written once.
+func Replace(l *logrus.Logger, logger *slog.Logger) {
+	l.AddHook(NewHook(logger))
+	l.SetOutput(io.Discard)
+}
+
+// Levels implements logrus.Hook
+func (h *Hook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire implements logrus.Hook
+func (h *Hook) Fire(entry *logrus.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	level := Level(entry.Level)
+	if !h.handler.Enabled(ctx, level) {
+		return nil
+	}
+
+	var pc uintptr
+	if entry.HasCaller() {
+		pc = entry.Caller.PC
+	}
+
+	r := slog.NewRecord(entry.Time, level, entry.Message, pc)
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := entry.Data[key]
+		// the errors keep their causes
+		if err, ok := value.(error); ok && key == logrus.ErrorKey {
+			r.AddAttrs(slogr.Error(err))
+			continue
+		}
+
+		r.AddAttrs(slog.Any(key, value))
+	}
+
+	return h.handler.Handle(ctx, r)
+}
+
+// Level returns the slog level of a given logrus level.
+func Level(level logrus.Level) slog.Level {
+	switch level {
+	case logrus.PanicLevel:
+		return slogr.LevelAlert
+	case logrus.FatalLevel:
+		return slogr.LevelCritical
+	case logrus.ErrorLevel:
+		return slog.LevelError
+	case logrus.WarnLevel:
+		return slog.LevelWarn
+	case logrus.InfoLevel:
+		return slog.LevelInfo
+	case logrus.DebugLevel:
+		return slog.LevelDebug
+	default:
+		return slog.LevelDebug - 4
+	}
+}
@@ -0,0 +1,172 @@
+package logrusslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/ralch/slogr"
+	"github.com/sirupsen/logrus"
+)
+
+// capture returns a logrus logger that writes to a slogr logger with a
+// buffer, and a function that returns the entries written so far.
+func capture(t *testing.T, level slog.Level) (*logrus.Logger, func() []map[string]any) {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+
+	l := logrus.New()
+	l.SetLevel(logrus.TraceLevel)
+	Replace(l, slogr.NewLogger(buffer, &slogr.HandlerOptions{Level: level, AddSource: true}))
+
+	return l, func() []map[string]any {
+		var entries []map[string]any
+
+		decoder := json.NewDecoder(bytes.NewReader(buffer.Bytes()))
+		for decoder.More() {
+			entry := map[string]any{}
+			if err := decoder.Decode(&entry); err != nil {
+				t.Fatal(err)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+}
+
+func payload(entry map[string]any) map[string]any {
+	value, _ := entry["message"].(map[string]any)
+	return value
+}
+
+func TestLevel(t *testing.T) {
+	cases := []struct {
+		level logrus.Level
+		want  slog.Level
+	}{
+		{level: logrus.TraceLevel, want: slog.LevelDebug - 4},
+		{level: logrus.DebugLevel, want: slog.LevelDebug},
+		{level: logrus.InfoLevel, want: slog.LevelInfo},
+		{level: logrus.WarnLevel, want: slog.LevelWarn},
+		{level: logrus.ErrorLevel, want: slog.LevelError},
+		{level: logrus.FatalLevel, want: slogr.LevelCritical},
+		{level: logrus.PanicLevel, want: slogr.LevelAlert},
+	}
+
+	for _, item := range cases {
+		if got := Level(item.level); got != item.want {
+			t.Errorf("Level(%v) = %v, want %v", item.level, got, item.want)
+		}
+	}
+}
+
+func TestHookFire(t *testing.T) {
+	l, entries := capture(t, slog.LevelDebug-4)
+
+	l.Trace("trace")
+	l.WithError(fmt.Errorf("wrap: %w", errors.New("inner"))).WithField("user", "u").Warn("failed")
+
+	l.ExitFunc = func(int) {}
+	l.Fatal("fatal")
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("the logger didn't panic")
+			}
+		}()
+
+		l.Panic("panic")
+	}()
+
+	got := entries()
+	if len(got) != 4 {
+		t.Fatalf("got %d entries, want 4", len(got))
+	}
+
+	for index, severity := range []string{"DEBUG", "WARNING", "CRITICAL", "ALERT"} {
+		if got[index]["severity"] != severity {
+			t.Errorf("entry %d: severity %v, want %s", index, got[index]["severity"], severity)
+		}
+	}
+
+	fields := payload(got[1])
+	if fields["user"] != "u" {
+		t.Errorf("user = %v, want u", fields["user"])
+	}
+
+	// the error is the one of slogr.Error, with its causes
+	if cause, _ := fields["error"].(map[string]any); cause["message"] != "wrap: inner" {
+		t.Errorf("error = %v", fields["error"])
+	}
+}
+
+func TestHookLevelOfHandler(t *testing.T) {
+	l, entries := capture(t, slog.LevelInfo)
+
+	l.Debug("hidden")
+	l.Info("shown")
+
+	if got := entries(); len(got) != 1 {
+		t.Errorf("got %d entries, want 1", len(got))
+	}
+}
+
+func TestHookCaller(t *testing.T) {
+	l, entries := capture(t, slog.LevelInfo)
+
+	l.Info("without a caller")
+	l.SetReportCaller(true)
+	l.Info("with a caller")
+
+	got := entries()
+	if len(got) != 2 {
+		t.Fatalf("got %d entries, want 2", len(got))
+	}
+
+	if location, _ := got[0]["logging.googleapis.com/sourceLocation"].(map[string]any); location["function"] != nil {
+		t.Errorf("unexpected sourceLocation: %v", location)
+	}
+
+	location, _ := got[1]["logging.googleapis.com/sourceLocation"].(map[string]any)
+	if location["function"] != "github.com/ralch/slogr/logrusslog.TestHookCaller" {
+		t.Errorf("sourceLocation = %v", location)
+	}
+}
+
+func TestReplace(t *testing.T) {
+	l := logrus.New()
+	Replace(l, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	if l.Out != io.Discard {
+		t.Error("the logrus output isn't discarded")
+	}
+
+	if hooks := l.Hooks[logrus.InfoLevel]; len(hooks) != 1 {
+		t.Errorf("got %d hooks, want 1", len(hooks))
+	}
+}
+
+func TestHookMirror(t *testing.T) {
+	var (
+		mirror = &bytes.Buffer{}
+		buffer = &bytes.Buffer{}
+	)
+
+	l := logrus.New()
+	l.SetOutput(mirror)
+	l.AddHook(NewHook(slogr.NewLogger(buffer, &slogr.HandlerOptions{Level: slog.LevelInfo})))
+
+	l.Info("mirrored")
+
+	if mirror.Len() == 0 || buffer.Len() == 0 {
+		t.Errorf("the entry isn't mirrored: %q, %q", mirror, buffer)
+	}
+}
@@ -3,12 +3,22 @@ package slogr
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/logging/apiv2/loggingpb"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
 	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"log/slog"
 )
 
@@ -41,50 +51,178 @@ func WithContext(ctx context.Context, logger *slog.Logger) context.Context {
 	return context.WithValue(ctx, LoggerKey, logger)
 }
 
-var _ slog.Leveler = LevelVar("")
+var attrsKey = &ContextKey{name: "attrs"}
 
-// StringLevel represents a slog.Leveler for string
+// Append provides the attributes in a given context, in addition to the ones
+// accumulated by the parent contexts. The handler adds them to every entry
+// logged with the context.
+func Append(ctx context.Context, attrs ...slog.Attr) context.Context {
+	if len(attrs) == 0 {
+		return ctx
+	}
+
+	// copy on write, so the siblings don't share the appended attributes
+	collection := append(slices.Clip(attrsFromContext(ctx)), attrs...)
+	// done!
+	return context.WithValue(ctx, attrsKey, collection)
+}
+
+var levelKey = &ContextKey{name: "level"}
+
+// WithLevel provides a minimum level in a given context. The handler uses it
+// instead of HandlerOptions.Level for the entries logged with the context.
+func WithLevel(ctx context.Context, level slog.Leveler) context.Context {
+	return context.WithValue(ctx, levelKey, level)
+}
+
+func levelFromContext(ctx context.Context) slog.Leveler {
+	if ctx == nil {
+		return nil
+	}
+
+	if level, ok := ctx.Value(levelKey).(slog.Leveler); ok {
+		return level
+	}
+
+	return nil
+}
+
+func attrsFromContext(ctx context.Context) []slog.Attr {
+	if attrs, ok := ctx.Value(attrsKey).([]slog.Attr); ok {
+		return attrs
+	}
+
+	return nil
+}
+
+// Levels for the Google Cloud Logging severities that have no slog equivalent.
+const (
+	LevelNotice    = slog.Level(2)
+	LevelCritical  = slog.Level(12)
+	LevelAlert     = slog.Level(16)
+	LevelEmergency = slog.Level(20)
+)
+
+var (
+	_ slog.Leveler = LevelVar("")
+	_ flag.Value   = new(LevelVar)
+)
+
+// levelNames are the names of the levels, in the order of the canonical names.
+var levelNames = []struct {
+	name  string
+	level slog.Level
+}{
+	{name: "NOTICE", level: LevelNotice},
+	{name: "CRITICAL", level: LevelCritical},
+	{name: "ALERT", level: LevelAlert},
+	{name: "EMERGENCY", level: LevelEmergency},
+	{name: "DEBUG", level: slog.LevelDebug},
+	{name: "INFO", level: slog.LevelInfo},
+	{name: "WARN", level: slog.LevelWarn},
+	{name: "WARNING", level: slog.LevelWarn},
+	{name: "ERROR", level: slog.LevelError},
+}
+
+// ParseLevel parses a level name case-insensitively, with an optional offset
+// like "INFO+2" or "ERROR-1". The names are the ones of slog and the NOTICE,
+// CRITICAL, ALERT and EMERGENCY extensions.
+func ParseLevel(value string) (slog.Level, error) {
+	name := strings.ToUpper(strings.TrimSpace(value))
+
+	var offset int
+	// parse the offset
+	if index := strings.IndexAny(name, "+-"); index > 0 {
+		n, err := strconv.Atoi(name[index:])
+		if err != nil {
+			return 0, fmt.Errorf("slogr: invalid level %q", value)
+		}
+
+		name, offset = name[:index], n
+	}
+
+	for _, item := range levelNames {
+		if item.name == name {
+			return item.level + slog.Level(offset), nil
+		}
+	}
+
+	return 0, fmt.Errorf("slogr: invalid level %q", value)
+}
+
+// levelString returns the canonical name of a given level.
+func levelString(level slog.Level) string {
+	for _, item := range levelNames[:4] {
+		if item.level == level {
+			return item.name
+		}
+	}
+
+	return level.String()
+}
+
+// LevelVar represents a slog.Leveler for a level name. It implements
+// [flag.Value], so it can be set from a command-line flag.
 type LevelVar string
 
-// Set set the value.
-func (v *LevelVar) Set(value string) {
-	*v = LevelVar(value)
+// Set sets the level from a given name. The names are case-insensitive, and
+// can have an offset like "INFO+2". An error is returned for an invalid name.
+func (v *LevelVar) Set(value string) error {
+	level, err := ParseLevel(value)
+	if err != nil {
+		return err
+	}
+
+	*v = LevelVar(levelString(level))
+	return nil
 }
 
-// String returns the level as string.
+// String returns the canonical name of the level.
 func (v LevelVar) String() string {
+	if level, err := ParseLevel(string(v)); err == nil {
+		return levelString(level)
+	}
+
 	return string(v)
 }
 
-// Level implements [slog.Leveler].
-func (v LevelVar) Level() slog.Level {
-	data := []byte(v)
+// Err returns the error of the level name, so an invalid name is noticed. An
+// empty LevelVar is LevelInfo.
+func (v LevelVar) Err() error {
+	if v == "" {
+		return nil
+	}
 
-	var level slog.Level
-	// unmarshal the level
-	_ = level.UnmarshalText(data)
+	_, err := ParseLevel(string(v))
+	return err
+}
+
+// Type returns the type name of the flag value for spf13/pflag.
+func (v *LevelVar) Type() string {
+	return "level"
+}
+
+// Level implements [slog.Leveler]. An invalid name is LevelInfo, see Err.
+func (v LevelVar) Level() slog.Level {
+	level, _ := ParseLevel(string(v))
 	// done!
 	return level
 }
 
-// MarshalText implements [encoding.TextMarshaler] by calling [Level.MarshalText].
+// MarshalText implements [encoding.TextMarshaler].
 func (v *LevelVar) MarshalText() ([]byte, error) {
-	return v.Level().MarshalText()
+	return []byte(levelString(v.Level())), nil
 }
 
-// UnmarshalText implements [encoding.TextUnmarshaler] by calling [Level.UnmarshalText].
+// UnmarshalText implements [encoding.TextUnmarshaler].
 func (v *LevelVar) UnmarshalText(data []byte) error {
-	var level slog.Level
-
-	if err := level.UnmarshalText(data); err != nil {
-		return err
-	}
-
-	*v = LevelVar(level.String())
-	return nil
+	return v.Set(string(data))
 }
 
-var _ json.Marshaler = &Entry{}
+var (
+	_ json.Marshaler   = &Entry{}
+	_ json.Unmarshaler = &Entry{}
+)
 
 // An individual entry in a log.
 type Entry loggingpb.LogEntry
@@ -114,8 +252,145 @@ func (x *Entry) GetJsonPayload() *structpb.Struct {
 	return nil
 }
 
+// ToProto returns a copy of the entry as a LogEntry, e.g. to write it with the
+// Logging API. Changing the copy doesn't change the entry. An empty InsertId
+// is kept, so the Logging API assigns one.
+func (x *Entry) ToProto() *loggingpb.LogEntry {
+	if x == nil {
+		return nil
+	}
+
+	return proto.Clone((*loggingpb.LogEntry)(x)).(*loggingpb.LogEntry)
+}
+
+// EntryFromProto returns a given LogEntry as an entry. The entry shares the
+// memory of the LogEntry, so changing one changes the other; use proto.Clone
+// first for an independent entry.
+func EntryFromProto(e *loggingpb.LogEntry) *Entry {
+	return (*Entry)(e)
+}
+
 // MarshalJSON implements json.Marshaler.
 func (x *Entry) MarshalJSON() ([]byte, error) {
+	attributes, err := x.fields(EncodeOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(attributes)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It reads an entry in the logging
+// agent format, as written by MarshalJSON. The message is a text payload when
+// it's a string, a proto payload when it's an object with a known @type, and
+// a JSON payload otherwise. The unknown fields are ignored.
+func (x *Entry) UnmarshalJSON(data []byte) error {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	proto.Reset((*loggingpb.LogEntry)(x))
+
+	var (
+		options = protojson.UnmarshalOptions{DiscardUnknown: true}
+		errs    []error
+	)
+
+	for key, value := range fields {
+		switch key {
+		case FieldSeverity:
+			errs = append(errs, unmarshalSeverity(value, &x.Severity))
+		case FieldTime:
+			var timestamp time.Time
+			errs = append(errs, json.Unmarshal(value, &timestamp))
+			x.Timestamp = timestamppb.New(timestamp)
+		case FieldLogName:
+			errs = append(errs, json.Unmarshal(value, &x.LogName))
+		case FieldInsertID:
+			errs = append(errs, json.Unmarshal(value, &x.InsertId))
+		case FieldLabels:
+			errs = append(errs, json.Unmarshal(value, &x.Labels))
+		case FieldSpanID:
+			errs = append(errs, json.Unmarshal(value, &x.SpanId))
+		case FieldTrace:
+			errs = append(errs, json.Unmarshal(value, &x.Trace))
+		case FieldTraceSampled:
+			errs = append(errs, json.Unmarshal(value, &x.TraceSampled))
+		case FieldHTTPRequest:
+			x.HttpRequest = &ltype.HttpRequest{}
+			errs = append(errs, options.Unmarshal(value, x.HttpRequest))
+		case FieldOperation:
+			x.Operation = &loggingpb.LogEntryOperation{}
+			errs = append(errs, options.Unmarshal(value, x.Operation))
+		case FieldSourceLocation:
+			x.SourceLocation = &loggingpb.LogEntrySourceLocation{}
+			errs = append(errs, options.Unmarshal(value, x.SourceLocation))
+		case FieldMessage:
+			errs = append(errs, x.unmarshalPayload(value))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// unmarshalPayload sets the payload of the entry from a given message field.
+func (x *Entry) unmarshalPayload(data json.RawMessage) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		x.Payload = &loggingpb.LogEntry_TextPayload{TextPayload: text}
+		return nil
+	}
+
+	var kind struct {
+		Type string `json:"@type"`
+	}
+
+	if err := json.Unmarshal(data, &kind); err != nil {
+		return err
+	}
+
+	// the proto payloads of a registered type are kept as protos
+	if kind.Type != "" {
+		message := &anypb.Any{}
+		if err := protojson.Unmarshal(data, message); err == nil {
+			x.Payload = &loggingpb.LogEntry_ProtoPayload{ProtoPayload: message}
+			return nil
+		}
+	}
+
+	payload := &structpb.Struct{}
+	if err := protojson.Unmarshal(data, payload); err != nil {
+		return err
+	}
+
+	x.Payload = &loggingpb.LogEntry_JsonPayload{JsonPayload: payload}
+	return nil
+}
+
+// unmarshalSeverity reads a severity given by its name or its number.
+func unmarshalSeverity(data json.RawMessage, severity *ltype.LogSeverity) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		var number int32
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+
+		*severity = ltype.LogSeverity(number)
+		return nil
+	}
+
+	value, ok := ltype.LogSeverity_value[strings.ToUpper(name)]
+	if !ok {
+		return fmt.Errorf("slogr: unknown severity %q", name)
+	}
+
+	*severity = ltype.LogSeverity(value)
+	return nil
+}
+
+func (x *Entry) fields(opts EncodeOptions) (map[string]interface{}, error) {
 	attributes := make(map[string]interface{})
 
 	set := func(k string, v interface{}) error {
@@ -148,17 +423,69 @@ func (x *Entry) MarshalJSON() ([]byte, error) {
 		return nil
 	}
 
-	set("severity", x.Severity.String())
-	set("httpRequest", x.HttpRequest)
-	set("message", x.GetPayload())
-	set("time", x.Timestamp.AsTime())
-	set("logging.googleapis.com/insertId", x.InsertId)
-	set("logging.googleapis.com/labels", x.Labels)
-	set("logging.googleapis.com/operation", x.Operation)
-	set("logging.googleapis.com/sourceLocation", x.SourceLocation)
-	set("logging.googleapis.com/spanId", x.SpanId)
-	set("logging.googleapis.com/trace", x.Trace)
-	set("logging.googleapis.com/trace_sampled", x.TraceSampled)
+	if opts.SeverityNumber {
+		set(FieldSeverity, int32(x.Severity))
+	} else {
+		set(FieldSeverity, x.Severity.String())
+	}
 
-	return json.Marshal(attributes)
+	if opts.LogName {
+		set(FieldLogName, x.LogName)
+	}
+
+	for _, err := range []error{
+		set(FieldHTTPRequest, x.HttpRequest),
+		set(FieldMessage, x.GetPayload()),
+		set(FieldTime, x.Timestamp.AsTime()),
+		set(FieldInsertID, x.InsertId),
+		set(FieldLabels, x.Labels),
+		set(FieldOperation, x.Operation),
+		set(FieldSourceLocation, x.SourceLocation),
+		set(FieldSpanID, x.SpanId),
+		set(FieldTrace, x.Trace),
+		set(FieldTraceSampled, x.TraceSampled),
+	} {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.Profile == ProfileGeneric {
+		return generic(attributes), nil
+	}
+
+	return attributes, nil
+}
+
+// generic returns the fields of the logging agent format with the field names
+// of ProfileGeneric, and the payload fields at the top level.
+func generic(attributes map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(attributes))
+
+	for key, value := range attributes {
+		if name, ok := genericFields[key]; ok {
+			key = name
+		}
+
+		fields[key] = value
+	}
+
+	payload, ok := attributes[FieldMessage].(map[string]interface{})
+	if !ok {
+		return fields
+	}
+
+	delete(fields, genericFields[FieldMessage])
+	// the payload fields don't override the entry fields
+	for key, value := range payload {
+		if key == FieldPayloadMessage {
+			key = genericFields[FieldMessage]
+		}
+
+		if _, ok := fields[key]; !ok {
+			fields[key] = value
+		}
+	}
+
+	return fields
 }
@@ -0,0 +1,198 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestEntryRoundTrip(t *testing.T) {
+	payload, err := structpb.NewStruct(map[string]any{
+		"count": 2,
+		"call":  map[string]any{"method": "List"},
+		"tags":  []any{"a", "b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	request, err := anypb.New(&ltype.HttpRequest{RequestMethod: "GET", Status: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the log name isn't written by MarshalJSON
+	base := &loggingpb.LogEntry{
+		Severity:     ltype.LogSeverity_WARNING,
+		Timestamp:    timestamppb.New(time.Date(2023, 9, 1, 12, 30, 0, 123456789, time.UTC)),
+		InsertId:     "insert-1",
+		Labels:       map[string]string{"env": "prod"},
+		Trace:        "projects/my-project/traces/0123456789abcdef0123456789abcdef",
+		SpanId:       "0123456789abcdef",
+		TraceSampled: true,
+		HttpRequest: &ltype.HttpRequest{
+			RequestMethod: "POST",
+			RequestUrl:    "https://example.com/books",
+			Status:        201,
+			Latency:       durationpb.New(1500 * time.Millisecond),
+		},
+		Operation:      &loggingpb.LogEntryOperation{Id: "op-1", Producer: "books", First: true},
+		SourceLocation: &loggingpb.LogEntrySourceLocation{File: "main.go", Line: 42, Function: "main.main"},
+	}
+
+	payloads := map[string]func(e *loggingpb.LogEntry){
+		"text": func(e *loggingpb.LogEntry) {
+			e.Payload = &loggingpb.LogEntry_TextPayload{TextPayload: "round trip"}
+		},
+		"json": func(e *loggingpb.LogEntry) {
+			e.Payload = &loggingpb.LogEntry_JsonPayload{JsonPayload: payload}
+		},
+		"proto": func(e *loggingpb.LogEntry) {
+			e.Payload = &loggingpb.LogEntry_ProtoPayload{ProtoPayload: request}
+		},
+	}
+
+	for name, set := range payloads {
+		want := proto.Clone(base).(*loggingpb.LogEntry)
+		set(want)
+
+		data, err := json.Marshal((*Entry)(want))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		got := &Entry{}
+		if err := json.Unmarshal(data, got); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+
+		if !proto.Equal((*loggingpb.LogEntry)(got), want) {
+			t.Errorf("%s: got %v, want %v", name, (*loggingpb.LogEntry)(got), want)
+		}
+	}
+}
+
+func TestEntryRoundTripOfHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	opts := &HandlerOptions{Level: slog.LevelInfo, ProjectID: "my-project", AddSource: true}
+
+	record := slog.NewRecord(time.Now(), slog.LevelError, "failed", 0)
+	record.AddAttrs(Label("env", "prod"), slog.Int("attempt", 3))
+
+	ctx := context.Background()
+	if err := NewHandler(buffer, opts).Handle(ctx, record); err != nil {
+		t.Fatal(err)
+	}
+
+	got := &Entry{}
+	if err := json.Unmarshal(buffer.Bytes(), got); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := RecordToEntry(ctx, record, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the log name of a project isn't part of the agent format
+	want.LogName = ""
+
+	if !proto.Equal((*loggingpb.LogEntry)(got), (*loggingpb.LogEntry)(want)) {
+		t.Fatalf("got %v, want %v", (*loggingpb.LogEntry)(got), (*loggingpb.LogEntry)(want))
+	}
+}
+
+func TestEntryUnmarshalJSON(t *testing.T) {
+	entry := &Entry{}
+	if err := json.Unmarshal([]byte(`{"logName":"audit","message":"named","unknown":1}`), entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.LogName != "audit" || (*loggingpb.LogEntry)(entry).GetTextPayload() != "named" {
+		t.Fatalf("got %v, want the log name and the message", (*loggingpb.LogEntry)(entry))
+	}
+}
+
+func TestUnmarshalSeverity(t *testing.T) {
+	for data, want := range map[string]ltype.LogSeverity{
+		`{"severity":"warning"}`: ltype.LogSeverity_WARNING,
+		`{"severity":"NOTICE"}`:  ltype.LogSeverity_NOTICE,
+		`{"severity":500}`:       ltype.LogSeverity_ERROR,
+	} {
+		entry := &Entry{}
+		if err := json.Unmarshal([]byte(data), entry); err != nil {
+			t.Fatal(err)
+		}
+
+		if entry.Severity != want {
+			t.Errorf("got %v of %s, want %v", entry.Severity, data, want)
+		}
+	}
+
+	if err := json.Unmarshal([]byte(`{"severity":"loud"}`), &Entry{}); err == nil {
+		t.Error("got no error of an unknown severity")
+	}
+}
+
+func TestEntryToProto(t *testing.T) {
+	entry := &Entry{
+		LogName: "audit",
+		Labels:  map[string]string{"env": "prod"},
+		Payload: &loggingpb.LogEntry_TextPayload{TextPayload: "converted"},
+	}
+
+	message := entry.ToProto()
+	if !proto.Equal(message, (*loggingpb.LogEntry)(entry)) {
+		t.Fatalf("got %v, want a copy of the entry", message)
+	}
+
+	// changing the copy doesn't change the entry
+	message.LogName = "other"
+	message.Labels["env"] = "dev"
+
+	if entry.LogName != "audit" || entry.Labels["env"] != "prod" {
+		t.Fatalf("got %v, want the entry unchanged", (*loggingpb.LogEntry)(entry))
+	}
+
+	if message.InsertId != "" {
+		t.Errorf("got insert id %q, want the empty one", message.InsertId)
+	}
+
+	if got := (*Entry)(nil).ToProto(); got != nil {
+		t.Errorf("got %v of a nil entry, want nil", got)
+	}
+}
+
+func TestEntryFromProto(t *testing.T) {
+	message := &loggingpb.LogEntry{
+		LogName: "audit",
+		Labels:  map[string]string{"env": "prod"},
+	}
+
+	entry := EntryFromProto(message)
+	// the entry shares the memory of the message
+	entry.LogName = "other"
+	entry.Labels["env"] = "dev"
+
+	if message.LogName != "other" || message.Labels["env"] != "dev" {
+		t.Fatalf("got %v, want the changes of the entry", message)
+	}
+
+	independent := EntryFromProto(proto.Clone(message).(*loggingpb.LogEntry))
+	independent.LogName = "audit"
+
+	if message.LogName != "other" {
+		t.Fatalf("got log name %q, want the message unchanged", message.LogName)
+	}
+}
@@ -0,0 +1,247 @@
+package slogr
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// The MessagePack encoding of the Fluent Forward protocol. Only the types the
+// entries need are supported.
+
+func msgpackNil(b []byte) []byte {
+	return append(b, 0xc0)
+}
+
+func msgpackBool(b []byte, v bool) []byte {
+	if v {
+		return append(b, 0xc3)
+	}
+
+	return append(b, 0xc2)
+}
+
+func msgpackInt(b []byte, v int64) []byte {
+	switch {
+	case v >= 0 && v <= 0x7f:
+		return append(b, byte(v))
+	case v < 0 && v >= -32:
+		return append(b, byte(v))
+	default:
+		return binary.BigEndian.AppendUint64(append(b, 0xd3), uint64(v))
+	}
+}
+
+func msgpackFloat(b []byte, v float64) []byte {
+	return binary.BigEndian.AppendUint64(append(b, 0xcb), math.Float64bits(v))
+}
+
+func msgpackString(b []byte, v string) []byte {
+	switch n := len(v); {
+	case n <= 31:
+		b = append(b, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		b = append(b, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xda), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xdb), uint32(n))
+	}
+
+	return append(b, v...)
+}
+
+func msgpackBinary(b []byte, v []byte) []byte {
+	switch n := len(v); {
+	case n <= math.MaxUint8:
+		b = append(b, 0xc4, byte(n))
+	case n <= math.MaxUint16:
+		b = binary.BigEndian.AppendUint16(append(b, 0xc5), uint16(n))
+	default:
+		b = binary.BigEndian.AppendUint32(append(b, 0xc6), uint32(n))
+	}
+
+	return append(b, v...)
+}
+
+func msgpackArray(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xdc), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdd), uint32(n))
+	}
+}
+
+func msgpackMap(b []byte, n int) []byte {
+	switch {
+	case n <= 15:
+		return append(b, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return binary.BigEndian.AppendUint16(append(b, 0xde), uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32(append(b, 0xdf), uint32(n))
+	}
+}
+
+// msgpackTime appends a given time as the EventTime extension of the Fluent
+// Forward protocol.
+func msgpackTime(b []byte, t time.Time) []byte {
+	b = append(b, 0xd7, 0x00)
+	b = binary.BigEndian.AppendUint32(b, uint32(t.Unix()))
+	return binary.BigEndian.AppendUint32(b, uint32(t.Nanosecond()))
+}
+
+// msgpackValue appends a given JSON-like value. The map keys are sorted, so
+// the encoding is deterministic.
+func msgpackValue(b []byte, v any) []byte {
+	switch value := v.(type) {
+	case nil:
+		return msgpackNil(b)
+	case bool:
+		return msgpackBool(b, value)
+	case string:
+		return msgpackString(b, value)
+	case int:
+		return msgpackInt(b, int64(value))
+	case int32:
+		return msgpackInt(b, int64(value))
+	case int64:
+		return msgpackInt(b, value)
+	case uint64:
+		if value <= math.MaxInt64 {
+			return msgpackInt(b, int64(value))
+		}
+
+		return binary.BigEndian.AppendUint64(append(b, 0xcf), value)
+	case float64:
+		if value == math.Trunc(value) && math.Abs(value) < 1<<53 {
+			return msgpackInt(b, int64(value))
+		}
+
+		return msgpackFloat(b, value)
+	case []any:
+		b = msgpackArray(b, len(value))
+		for _, item := range value {
+			b = msgpackValue(b, item)
+		}
+
+		return b
+	case map[string]any:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		b = msgpackMap(b, len(keys))
+		for _, key := range keys {
+			b = msgpackValue(msgpackString(b, key), value[key])
+		}
+
+		return b
+	case map[string]string:
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		b = msgpackMap(b, len(keys))
+		for _, key := range keys {
+			b = msgpackString(msgpackString(b, key), value[key])
+		}
+
+		return b
+	default:
+		return msgpackString(b, fmt.Sprint(value))
+	}
+}
+
+// msgpackRead reads a value of the types of a Fluent Forward ack: nil,
+// booleans, strings and maps.
+func msgpackRead(r *bufio.Reader) (any, error) {
+	code, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	size := func(n int) (int, error) {
+		data := make([]byte, n)
+		if _, err := io.ReadFull(r, data); err != nil {
+			return 0, err
+		}
+
+		switch n {
+		case 1:
+			return int(data[0]), nil
+		case 2:
+			return int(binary.BigEndian.Uint16(data)), nil
+		default:
+			return int(binary.BigEndian.Uint32(data)), nil
+		}
+	}
+
+	var n int
+
+	switch {
+	case code == 0xc0:
+		return nil, nil
+	case code == 0xc2, code == 0xc3:
+		return code == 0xc3, nil
+	case code&0xe0 == 0xa0:
+		n = int(code & 0x1f)
+	case code == 0xd9, code == 0xda, code == 0xdb:
+		if n, err = size(1 << (code - 0xd9)); err != nil {
+			return nil, err
+		}
+	case code&0xf0 == 0x80:
+		return msgpackReadMap(r, int(code&0x0f))
+	case code == 0xde, code == 0xdf:
+		if n, err = size(2 << (code - 0xde)); err != nil {
+			return nil, err
+		}
+
+		return msgpackReadMap(r, n)
+	default:
+		return nil, fmt.Errorf("slogr: unsupported msgpack type 0x%x", code)
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return string(data), nil
+}
+
+func msgpackReadMap(r *bufio.Reader, n int) (map[string]any, error) {
+	value := make(map[string]any, n)
+
+	for i := 0; i < n; i++ {
+		key, err := msgpackRead(r)
+		if err != nil {
+			return nil, err
+		}
+
+		name, ok := key.(string)
+		if !ok {
+			return nil, errors.New("slogr: unsupported msgpack map key")
+		}
+
+		if value[name], err = msgpackRead(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return value, nil
+}
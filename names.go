@@ -0,0 +1,238 @@
+package slogr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnregisteredNameKey represents the payload key of the original log name of
+// an entry rerouted by the strict names mode.
+const UnregisteredNameKey = "unregistered_log_name"
+
+// RegisteredName represents a registered log name.
+type RegisteredName struct {
+	// Name is the log name.
+	Name string
+	// Description describes the entries of the log.
+	Description string
+}
+
+var registry = struct {
+	sync.RWMutex
+	names map[string]string
+}{
+	names: make(map[string]string),
+}
+
+// RegisterLogName registers a log name that the binary can emit. It's meant
+// to be called at package init. Registering the same name twice with a
+// different description returns an error.
+func RegisterLogName(name, description string) error {
+	registry.Lock()
+	defer registry.Unlock()
+
+	if value, ok := registry.names[name]; ok && value != description {
+		return fmt.Errorf("slogr: log name %q already registered as %q", name, value)
+	}
+
+	registry.names[name] = description
+	// done!
+	return nil
+}
+
+// Names returns the registered log names sorted by name.
+func Names() []RegisteredName {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	collection := make([]RegisteredName, 0, len(registry.names))
+
+	for name, description := range registry.names {
+		collection = append(collection, RegisteredName{
+			Name:        name,
+			Description: description,
+		})
+	}
+
+	sort.Slice(collection, func(i, j int) bool {
+		return collection[i].Name < collection[j].Name
+	})
+
+	return collection
+}
+
+func isRegisteredName(name string) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+
+	_, ok := registry.names[name]
+	return ok
+}
+
+// reroute returns a record with the fallback name when the record has a name
+// that was never registered.
+func (h *Handler) reroute(r slog.Record) slog.Record {
+	var name string
+
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == NameKey {
+			name = attr.Value.String()
+			return false
+		}
+
+		return true
+	})
+
+	// the name attribute value is escaped
+	if value, err := url.PathUnescape(name); err == nil {
+		name = value
+	}
+
+	if name == "" || isRegisteredName(name) {
+		return r
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	// copy the attributes without the name
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key != NameKey {
+			record.AddAttrs(attr)
+		}
+
+		return true
+	})
+
+	record.AddAttrs(
+		Name(h.fallback),
+		slog.String(UnregisteredNameKey, name),
+	)
+
+	return record
+}
+
+// enabledByName reports whether a given record is enabled by the level of its
+// log name, or by the handler level when its name has no level.
+func (h *Handler) enabledByName(ctx context.Context, r slog.Record) bool {
+	leveler, ok := h.levelByName[h.recordName(r)]
+	if !ok {
+		leveler = h.leveler
+	}
+
+	return h.enabled(ctx, r.Level, leveler)
+}
+
+// recordName returns the short name of a given record with the attributes of
+// the handler. The record of Handle is only built for the audit entries.
+func (h *Handler) recordName(r slog.Record) string {
+	// the grouped attributes of the record have no name
+	if len(h.groups) == 0 {
+		switch keys := reservedKeys(r); {
+		case keys&keyName != 0:
+			return h.shortName(r)
+		case keys&keyAudit != 0:
+			return h.shortName(h.record(r))
+		}
+	}
+
+	var audit bool
+
+	for _, attr := range h.attr {
+		switch attr.Key {
+		case NameKey:
+			name := attr.Value.String()
+			// the name attribute value is escaped
+			if value, err := url.PathUnescape(name); err == nil {
+				name = value
+			}
+
+			return name
+		case AuditKey:
+			audit = audit || isAuditLog(attr)
+		}
+	}
+
+	if audit {
+		return h.shortName(h.record(r))
+	}
+
+	return ""
+}
+
+// minNameLevel returns the lowest level of the log names.
+func (h *Handler) minNameLevel() slog.Level {
+	var (
+		level slog.Level
+		first = true
+	)
+
+	for _, leveler := range h.levelByName {
+		if value := leveler.Level(); first || value < level {
+			level, first = value, false
+		}
+	}
+
+	return level
+}
+
+// route represents the writer of a log name, or of a log name prefix.
+type route struct {
+	name   string
+	prefix bool
+	writer io.Writer
+}
+
+// newRoutes returns the routes of the given writers, with the exact names
+// first and the longest prefixes before the shorter ones.
+func newRoutes(writers map[string]io.Writer) []route {
+	routes := make([]route, 0, len(writers))
+	// prepare the routes
+	for name, writer := range writers {
+		routes = append(routes, route{
+			name:   strings.TrimSuffix(name, "*"),
+			prefix: strings.HasSuffix(name, "*"),
+			writer: writer,
+		})
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if routes[i].prefix != routes[j].prefix {
+			return !routes[i].prefix
+		}
+
+		return len(routes[i].name) > len(routes[j].name)
+	})
+
+	return routes
+}
+
+// writerByName returns the writer of the route of a given log name, which is
+// either a short name or a full resource name.
+func (h *Handler) writerByName(name string) io.Writer {
+	if len(h.routes) == 0 || name == "" {
+		return nil
+	}
+
+	// strip the project
+	if index := strings.Index(name, "/logs/"); strings.HasPrefix(name, "projects/") && index >= 0 {
+		name = name[index+len("/logs/"):]
+	}
+
+	// the log name is escaped
+	if value, err := url.PathUnescape(name); err == nil {
+		name = value
+	}
+
+	for _, route := range h.routes {
+		if route.name == name || route.prefix && strings.HasPrefix(name, route.name) {
+			return route.writer
+		}
+	}
+
+	return nil
+}
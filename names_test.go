@@ -0,0 +1,300 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLevelByName(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{
+		Level: slog.LevelInfo,
+		LevelByName: map[string]slog.Leveler{
+			"noisy":   slog.LevelWarn,
+			"verbose": slog.LevelDebug,
+		},
+	})
+
+	logger.Info("dropped", Name("noisy"))
+	logger.Warn("kept", Name("noisy"))
+	logger.Debug("kept", Name("verbose"))
+	logger.Debug("dropped")
+	logger.Debug("dropped", Name("other"))
+	// the name of the handler
+	logger.With(Name("noisy")).Info("dropped")
+	logger.With(Name("verbose")).WithGroup("call").Debug("kept", Name("noisy"))
+
+	collection := entries()
+	if len(collection) != 3 {
+		t.Fatalf("got %d entries, want 3", len(collection))
+	}
+
+	for _, entry := range collection {
+		if message, _ := entry["message"].(string); message != "kept" {
+			if payload := payloadOf(entry); payload["logging.googleapis.com/message"] != "kept" {
+				t.Errorf("got entry %v, want the kept ones", entry)
+			}
+		}
+	}
+
+	if got := logger.Handler().(*Handler).Stats().Dropped; got != 4 {
+		t.Errorf("got %d dropped entries, want 4", got)
+	}
+}
+
+func TestLevelByNameAudit(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{
+		Level:       slog.LevelInfo,
+		LevelByName: map[string]slog.Leveler{AuditKey: slog.LevelDebug},
+	})
+
+	logger.Debug("kept", Audit("alice", "Get", "books/1", nil, nil))
+	single(t, entries())
+}
+
+func TestHandleKeepsTheRecord(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{AddDeadline: true})
+
+	ctx, cancel := context.WithTimeout(Append(context.Background(), slog.String("user", "alice")), time.Hour)
+	defer cancel()
+
+	// the attributes beyond the inline ones share their storage with the copies
+	record := slog.NewRecord(time.Now(), slog.LevelInfo, "shared", 0)
+	for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+		record.AddAttrs(slog.String(key, key))
+	}
+
+	for index := 0; index < 2; index++ {
+		if err := logger.Handler().Handle(ctx, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := record.NumAttrs(); got != 6 {
+		t.Fatalf("got %d attributes of the record, want 6", got)
+	}
+
+	for _, entry := range entries() {
+		if payload := payloadOf(entry); payload["user"] != "alice" || payload["f"] != "f" {
+			t.Fatalf("got payload %v, want the attributes of the record and the context", payload)
+		}
+	}
+}
+
+func TestNameWriters(t *testing.T) {
+	var (
+		main     = &bytes.Buffer{}
+		audit    = &bytes.Buffer{}
+		requests = &bytes.Buffer{}
+		api      = &bytes.Buffer{}
+	)
+
+	logger := NewLogger(main, &HandlerOptions{
+		Level:     slog.LevelInfo,
+		ProjectID: "my-project",
+		NameWriters: map[string]io.Writer{
+			"audit":        audit,
+			"requests*":    requests,
+			"requests/api": api,
+		},
+	})
+
+	logger.Info("main")
+	logger.Info("main", Name("other"))
+	logger.Info("audit", Name("audit"))
+	logger.Info("requests", Name("requests/web"))
+	// the exact name wins over the prefix
+	logger.Info("api", Name("requests/api"))
+
+	for buffer, want := range map[*bytes.Buffer]int{main: 2, audit: 1, requests: 1, api: 1} {
+		if got := len(decodeEntries(t, buffer.Bytes())); got != want {
+			t.Errorf("got %d entries in %q, want %d", got, buffer.String(), want)
+		}
+	}
+}
+
+func TestNameWritersInParallel(t *testing.T) {
+	names := []string{"", "audit", "billing", "requests/web", "requests/api"}
+
+	var (
+		writers = make(map[string]io.Writer)
+		buffers = make(map[string]*bytes.Buffer)
+	)
+
+	for _, name := range names {
+		buffers[name] = &bytes.Buffer{}
+
+		if name != "" {
+			writers[name] = buffers[name]
+		}
+	}
+
+	logger := NewLogger(buffers[""], &HandlerOptions{
+		Level:       slog.LevelInfo,
+		ProjectID:   "my-project",
+		NameWriters: writers,
+	})
+
+	const count = 100
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		for worker := 0; worker < 4; worker++ {
+			wg.Add(1)
+
+			go func(name string) {
+				defer wg.Done()
+
+				named := logger.With("source", name)
+				if name != "" {
+					named = named.With(Name(name))
+				}
+
+				for index := 0; index < count; index++ {
+					named.Info("parallel", "index", index)
+				}
+			}(name)
+		}
+	}
+
+	wg.Wait()
+
+	for _, name := range names {
+		entries := decodeEntries(t, buffers[name].Bytes())
+		if len(entries) != 4*count {
+			t.Fatalf("got %d entries of %q, want %d", len(entries), name, 4*count)
+		}
+
+		for _, entry := range entries {
+			if got := payloadOf(entry)["source"]; got != name {
+				t.Fatalf("got an entry of %q in the writer of %q", got, name)
+			}
+		}
+	}
+}
+
+func TestRegisterLogName(t *testing.T) {
+	if err := RegisterLogName("test/registered", "The entries of the tests."); err != nil {
+		t.Fatal(err)
+	}
+
+	// registering the same name again is fine
+	if err := RegisterLogName("test/registered", "The entries of the tests."); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := RegisterLogName("test/registered", "Another description."); err == nil {
+		t.Fatal("got no error of a different description")
+	}
+
+	collection := Names()
+	if !sort.SliceIsSorted(collection, func(i, j int) bool { return collection[i].Name < collection[j].Name }) {
+		t.Fatalf("got names %v, want them sorted", collection)
+	}
+
+	if !slices.Contains(collection, RegisteredName{Name: "test/registered", Description: "The entries of the tests."}) {
+		t.Fatalf("got names %v, want the registered one", collection)
+	}
+}
+
+func TestStrictNames(t *testing.T) {
+	if err := RegisterLogName("test/strict", "The entries of the strict names test."); err != nil {
+		t.Fatal(err)
+	}
+
+	logger, entries := capture(t, &HandlerOptions{StrictNames: true})
+	logger.Info("registered", Name("test/strict"))
+	logger.Info("unregistered", Name("test/typo"))
+	logger.Info("unnamed")
+
+	collection := entries()
+	if len(collection) != 3 {
+		t.Fatalf("got %d entries, want 3", len(collection))
+	}
+
+	if got := payloadOf(collection[0])[FieldLogName]; got != "test/strict" {
+		t.Errorf("got log name %v, want the registered one", got)
+	}
+
+	payload := payloadOf(collection[1])
+	if got := payload[FieldLogName]; got != "unregistered" {
+		t.Errorf("got log name %v, want the fallback", got)
+	}
+
+	if got := payload[UnregisteredNameKey]; got != "test/typo" {
+		t.Errorf("got original name %v, want test/typo", got)
+	}
+
+	if got := collection[2]["message"]; got != "unnamed" {
+		t.Errorf("got entry %v, want the unnamed one as is", collection[2])
+	}
+}
+
+func TestNameWithoutProject(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		opts     *HandlerOptions
+		topLevel any
+		payload  any
+	}{
+		{"payload", &HandlerOptions{}, nil, "books/v1 list"},
+		{"top level", &HandlerOptions{NameField: NameFieldTopLevel}, "books/v1 list", nil},
+	} {
+		logger, entries := capture(t, tc.opts)
+		// the name is written unescaped
+		logger.Info("named", Name("books/v1 list"), slog.Int("count", 2))
+
+		entry := single(t, entries())
+		if got := entry["logName"]; got != tc.topLevel {
+			t.Errorf("%s: got top-level log name %v, want %v", tc.name, got, tc.topLevel)
+		}
+
+		if got := payloadOf(entry)[FieldLogName]; got != tc.payload {
+			t.Errorf("%s: got payload log name %v, want %v", tc.name, got, tc.payload)
+		}
+	}
+}
+
+func TestNameWithoutProjectPlainMessage(t *testing.T) {
+	logger, entries := capture(t, nil)
+	logger.Info("named", Name("audit"))
+
+	// the log name makes the payload a JSON one
+	payload := payloadOf(single(t, entries()))
+	if payload[FieldLogName] != "audit" || payload[FieldPayloadMessage] != "named" {
+		t.Fatalf("got payload %v, want the log name and the message", payload)
+	}
+}
+
+func TestNameWithProject(t *testing.T) {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo, ProjectID: "my-project"}).(*Handler)
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "named", 0)
+	r.AddAttrs(Name("books/v1 list"))
+
+	for _, tc := range []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"project", context.Background(), "projects/my-project/logs/books%2Fv1%20list"},
+		// the project of an override switches the resource path
+		{"override", WithProjectOverride(context.Background(), "tenant-alpha"), "projects/tenant-alpha/logs/books%2Fv1%20list"},
+	} {
+		entry := handler.Entry(tc.ctx, r)
+		if entry.LogName != tc.want {
+			t.Errorf("%s: got log name %q, want %q", tc.name, entry.LogName, tc.want)
+		}
+
+		// the path is in the entry, so the payload has no log name
+		if got := entry.GetJsonPayload().GetFields()[FieldLogName]; got != nil {
+			t.Errorf("%s: got payload log name %v, want none", tc.name, got)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package slogr
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// NetWriterOptions for a NetWriter. A zero NetWriterOptions consists entirely
+// of default values.
+type NetWriterOptions struct {
+	// TLS is the configuration of the TLS connections. If TLS is nil, the
+	// connections are plain.
+	TLS *tls.Config
+
+	// Backoff is the delay between the attempts to reconnect. If Backoff is
+	// nil, the writer uses an exponential backoff with a 20% jitter, without
+	// a limit of attempts.
+	Backoff *Backoff
+
+	// BufferSize is the maximum number of bytes buffered while the collector
+	// is unreachable. The oldest writes are dropped beyond it.
+	// If BufferSize is zero, the writer assumes 1MiB.
+	BufferSize int
+
+	// WriteTimeout is the time limit of a write to the collector, and of a
+	// connection attempt. If WriteTimeout is zero, the writer assumes 5s.
+	WriteTimeout time.Duration
+
+	// CloseTimeout is the time Close waits for the buffer to be sent.
+	// If CloseTimeout is zero, the writer assumes 5s.
+	CloseTimeout time.Duration
+}
+
+// NetWriter implements an [io.WriteCloser] that sends the writes to a
+// collector over the network, such as a Vector or a Fluent Bit TCP source.
+// The writes are buffered and sent in order on a background goroutine, so a
+// slow or wedged collector never blocks the handler. The writer reconnects
+// with a backoff when the connection fails, and the buffer keeps the latest
+// writes meanwhile. A write is sent again as a whole after a failure. It's
+// safe for concurrent use.
+type NetWriter struct {
+	network      string
+	address      string
+	tls          *tls.Config
+	backoff      *Backoff
+	size         int
+	timeout      time.Duration
+	closeTimeout time.Duration
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  [][]byte
+	queued int
+	closed bool
+	conn   net.Conn
+
+	ctx        context.Context
+	cancel     context.CancelFunc
+	done       chan struct{}
+	reconnects atomic.Int64
+	dropped    atomic.Int64
+}
+
+// NewNetWriter creates a NetWriter that sends the writes to a given address.
+// The connection is established in the background. Call Close to send the
+// buffered writes before the program exits.
+func NewNetWriter(network, address string, opts *NetWriterOptions) *NetWriter {
+	if opts == nil {
+		opts = &NetWriterOptions{}
+	}
+
+	w := &NetWriter{
+		network:      network,
+		address:      address,
+		tls:          opts.TLS,
+		backoff:      opts.Backoff,
+		size:         opts.BufferSize,
+		timeout:      opts.WriteTimeout,
+		closeTimeout: opts.CloseTimeout,
+		done:         make(chan struct{}),
+	}
+
+	if w.backoff == nil {
+		w.backoff = &Backoff{Jitter: 0.2}
+	}
+
+	if w.size <= 0 {
+		w.size = 1 << 20
+	}
+
+	if w.timeout <= 0 {
+		w.timeout = 5 * time.Second
+	}
+
+	if w.closeTimeout <= 0 {
+		w.closeTimeout = 5 * time.Second
+	}
+
+	w.cond = sync.NewCond(&w.mu)
+	w.ctx, w.cancel = context.WithCancel(context.Background())
+
+	go w.run()
+	// done!
+	return w
+}
+
+// Write implements io.Writer. It buffers a copy of p, and drops the oldest
+// buffered writes when the buffer is full.
+func (w *NetWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	// drop the oldest writes
+	for len(w.queue) > 0 && w.queued+len(p) > w.size {
+		w.dropped.Add(int64(len(w.queue[0])))
+		w.queued -= len(w.queue[0])
+		w.queue = w.queue[1:]
+	}
+
+	w.queue = append(w.queue, append([]byte(nil), p...))
+	w.queued += len(p)
+	w.cond.Signal()
+	return len(p), nil
+}
+
+// Close sends the buffered writes within CloseTimeout, and closes the
+// connection. The writes that weren't sent by then are dropped.
+func (w *NetWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+
+	w.closed = true
+	w.cond.Broadcast()
+	w.mu.Unlock()
+
+	timer := time.NewTimer(w.closeTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-w.done:
+		return nil
+	case <-timer.C:
+	}
+
+	// give up on the buffer
+	w.cancel()
+
+	w.mu.Lock()
+	if w.conn != nil {
+		_ = w.conn.SetDeadline(time.Now())
+	}
+	w.mu.Unlock()
+
+	<-w.done
+	return fmt.Errorf("slogr: close the net writer: %w", context.DeadlineExceeded)
+}
+
+// Reconnects returns the number of connections established after the first
+// one.
+func (w *NetWriter) Reconnects() int64 {
+	return w.reconnects.Load()
+}
+
+// DroppedBytes returns the number of bytes dropped by a full buffer, or by
+// Close.
+func (w *NetWriter) DroppedBytes() int64 {
+	return w.dropped.Load()
+}
+
+// run sends the buffered writes until the writer is closed and the buffer is
+// empty, or until Close gives up.
+func (w *NetWriter) run() {
+	defer close(w.done)
+	defer w.disconnect()
+
+	var (
+		pending   []byte
+		connected bool
+		retry     int
+	)
+
+	for {
+		if pending == nil {
+			w.mu.Lock()
+			for len(w.queue) == 0 && !w.closed {
+				w.cond.Wait()
+			}
+
+			if len(w.queue) == 0 {
+				w.mu.Unlock()
+				return
+			}
+
+			pending = w.queue[0]
+			w.queue = w.queue[1:]
+			w.queued -= len(pending)
+			w.mu.Unlock()
+		}
+
+		if w.ctx.Err() != nil {
+			w.drop(pending)
+			return
+		}
+
+		conn, dialed, err := w.connect()
+		if err == nil {
+			if dialed && connected {
+				w.reconnects.Add(1)
+			}
+
+			connected, retry = true, 0
+
+			if err = w.write(conn, pending); err == nil {
+				pending = nil
+				continue
+			}
+
+			w.disconnect()
+		}
+
+		// wait before the next attempt
+		timer := time.NewTimer(w.backoff.Delay(retry))
+		retry++
+
+		select {
+		case <-w.ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+	}
+}
+
+// connect returns the connection, and dials the collector when there's none.
+// It reports whether the connection was dialed.
+func (w *NetWriter) connect() (net.Conn, bool, error) {
+	w.mu.Lock()
+	conn := w.conn
+	w.mu.Unlock()
+
+	if conn != nil {
+		return conn, false, nil
+	}
+
+	ctx, cancel := context.WithTimeout(w.ctx, w.timeout)
+	defer cancel()
+
+	var (
+		dialer = &net.Dialer{}
+		err    error
+	)
+
+	if w.tls != nil {
+		conn, err = (&tls.Dialer{NetDialer: dialer, Config: w.tls}).DialContext(ctx, w.network, w.address)
+	} else {
+		conn, err = dialer.DialContext(ctx, w.network, w.address)
+	}
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	w.mu.Lock()
+	w.conn = conn
+	w.mu.Unlock()
+
+	return conn, true, nil
+}
+
+// write writes a given buffer to a given connection with the write timeout.
+func (w *NetWriter) write(conn net.Conn, p []byte) error {
+	if err := conn.SetWriteDeadline(time.Now().Add(w.timeout)); err != nil {
+		return err
+	}
+
+	_, err := conn.Write(p)
+	return err
+}
+
+// disconnect closes the connection.
+func (w *NetWriter) disconnect() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// drop drops a given write and the buffer.
+func (w *NetWriter) drop(pending []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	count := len(pending) + w.queued
+	w.queue, w.queued = nil, 0
+	w.dropped.Add(int64(count))
+}
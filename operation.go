@@ -0,0 +1,102 @@
+package slogr
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+)
+
+var operationKey = &ContextKey{name: "operation"}
+
+// operation represents an operation provided in a context.
+type operation struct {
+	id       string
+	producer string
+	start    time.Time
+}
+
+// operationValue represents the value of an operation Attr that carries the
+// elapsed time since the operation started.
+type operationValue struct {
+	operation *loggingpb.LogEntryOperation
+	elapsed   time.Duration
+}
+
+// StartOperation starts a new operation with a generated id and provides it
+// in a given context. It returns an Attr for the first operation log. Use
+// AutoProducer to derive the producer from the calling package.
+func StartOperation(ctx context.Context, producer string) (context.Context, slog.Attr) {
+	value := &operation{
+		id:       newOperationID(),
+		producer: producer,
+		start:    time.Now(),
+	}
+
+	ctx = context.WithValue(ctx, operationKey, value)
+	// done!
+	return ctx, OperationStart(value.id, value.producer)
+}
+
+// OperationFromContext returns the operation from a given context. It returns
+// nil when the context has no operation.
+func OperationFromContext(ctx context.Context) *loggingpb.LogEntryOperation {
+	if value, ok := ctx.Value(operationKey).(*operation); ok {
+		return &loggingpb.LogEntryOperation{
+			Id:       value.id,
+			Producer: value.producer,
+		}
+	}
+
+	return nil
+}
+
+// ContinueOperation returns an Attr for any non-start/end operation log of the
+// operation in a given context. An empty Attr is returned when the context
+// has no operation.
+func ContinueOperation(ctx context.Context) slog.Attr {
+	if operation := OperationFromContext(ctx); operation != nil {
+		return OperationContinue(operation.Id, operation.Producer)
+	}
+
+	return slog.Attr{}
+}
+
+// EndOperation returns an Attr for the last operation log of the operation in
+// a given context. The handler adds the time elapsed since StartOperation to
+// the payload as ElapsedKey. An empty Attr is returned when the context has no
+// operation.
+func EndOperation(ctx context.Context) slog.Attr {
+	value, ok := ctx.Value(operationKey).(*operation)
+	if !ok {
+		return slog.Attr{}
+	}
+
+	attr := OperationEnd(value.id, value.producer)
+	// the elapsed time is unknown without a start time
+	if !value.start.IsZero() {
+		attr.Value = slog.AnyValue(&operationValue{
+			operation: attr.Value.Any().(*loggingpb.LogEntryOperation),
+			elapsed:   time.Since(value.start),
+		})
+	}
+
+	return attr
+}
+
+// newOperationID returns a random UUID (version 4).
+func newOperationID() string {
+	data := make([]byte, 16)
+	// read the random data
+	if _, err := rand.Read(data); err != nil {
+		panic(err)
+	}
+
+	data[6] = (data[6] & 0x0f) | 0x40
+	data[8] = (data[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:])
+}
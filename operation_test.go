@@ -0,0 +1,92 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"regexp"
+	"testing"
+)
+
+// uuid matches a random UUID.
+var uuid = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestOperationInContext(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	ctx, start := StartOperation(context.Background(), "books")
+	logger.InfoContext(ctx, "start", start)
+	logger.InfoContext(ctx, "continue", ContinueOperation(ctx))
+	logger.InfoContext(ctx, "end", EndOperation(ctx))
+
+	collection := entries()
+	if len(collection) != 3 {
+		t.Fatalf("got %d entries, want 3", len(collection))
+	}
+
+	id := OperationFromContext(ctx).GetId()
+	if !uuid.MatchString(id) {
+		t.Fatalf("got operation id %q, want a UUID", id)
+	}
+
+	for index, want := range []map[string]any{
+		{"id": id, "producer": "books", "first": true},
+		{"id": id, "producer": "books"},
+		{"id": id, "producer": "books", "last": true},
+	} {
+		got, _ := collection[index][FieldOperation].(map[string]any)
+		if len(got) != len(want) {
+			t.Errorf("got operation %v, want %v", got, want)
+			continue
+		}
+
+		for key, value := range want {
+			if got[key] != value {
+				t.Errorf("got operation %v, want %v", got, want)
+			}
+		}
+	}
+
+	// the time since the start of the operation
+	if _, ok := payloadOf(collection[2])[ElapsedKey].(string); !ok {
+		t.Errorf("got payload %v, want the elapsed time", payloadOf(collection[2]))
+	}
+}
+
+func TestOperationElapsedOfAttr(t *testing.T) {
+	ctx, _ := StartOperation(context.Background(), "books")
+
+	for _, tc := range []struct {
+		name  string
+		attrs []any
+	}{
+		{"before", []any{slog.String(ElapsedKey, "user"), EndOperation(ctx)}},
+		{"after", []any{EndOperation(ctx), slog.String(ElapsedKey, "user")}},
+	} {
+		logger, entries := capture(t, nil)
+		logger.InfoContext(ctx, "end", tc.attrs...)
+
+		entry := single(t, entries())
+		// the attribute wins over the elapsed time
+		if got := payloadOf(entry)[ElapsedKey]; got != "user" {
+			t.Errorf("%s: got elapsed %v, want user", tc.name, got)
+		}
+
+		if got, _ := entry[FieldOperation].(map[string]any); got["last"] != true {
+			t.Errorf("%s: got operation %v, want the last entry", tc.name, got)
+		}
+	}
+}
+
+func TestOperationWithoutContext(t *testing.T) {
+	ctx := context.Background()
+
+	if got := OperationFromContext(ctx); got != nil {
+		t.Fatalf("got operation %v, want none", got)
+	}
+
+	for _, attr := range []slog.Attr{ContinueOperation(ctx), EndOperation(ctx)} {
+		if !attr.Equal(slog.Attr{}) {
+			t.Fatalf("got %v, want an empty attr", attr)
+		}
+	}
+}
@@ -0,0 +1,286 @@
+// Package otellog provides a [slog.Handler] that emits the Google Cloud Logging
+// shaped entries of slogr as OpenTelemetry log records.
+package otellog
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/ralch/slogr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/log"
+	"go.opentelemetry.io/otel/trace"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// HandlerOptions for the OpenTelemetry handler. A zero HandlerOptions consists
+// entirely of default values.
+type HandlerOptions struct {
+	slogr.HandlerOptions
+
+	// Name is the instrumentation scope name of the logger.
+	// If Name is empty, the handler assumes "github.com/ralch/slogr".
+	Name string
+
+	// Attributes are added to every record. Use them for the resource
+	// attributes when the logger provider has no resource.
+	Attributes []attribute.KeyValue
+}
+
+// Handler implements a [slog.Handler].
+type Handler struct {
+	handler *slogr.Handler
+	logger  log.Logger
+	attrs   []attribute.KeyValue
+}
+
+// NewHandler creates a [slog.Handler] that emits the entries via a logger of
+// a given provider.
+func NewHandler(provider log.LoggerProvider, opts *HandlerOptions) slog.Handler {
+	name := opts.Name
+	// set the default name
+	if name == "" {
+		name = "github.com/ralch/slogr"
+	}
+
+	attrs := []attribute.KeyValue{}
+	// the project id is the cloud account id
+	if project := opts.ProjectID; project != "" {
+		attrs = append(attrs,
+			attribute.String("cloud.provider", "gcp"),
+			attribute.String("cloud.account.id", project),
+		)
+	}
+
+	attrs = append(attrs, opts.Attributes...)
+
+	h := &Handler{
+		handler: slogr.NewHandler(io.Discard, &opts.HandlerOptions).(*slogr.Handler),
+		logger:  provider.Logger(name),
+		attrs:   attrs,
+	}
+
+	return h
+}
+
+// Enabled implements slog.Handler
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	entry := h.handler.Entry(ctx, r)
+
+	var record log.Record
+	record.SetTimestamp(entry.Timestamp.AsTime())
+	record.SetObservedTimestamp(time.Now())
+	record.SetSeverity(severity(entry.Severity))
+	record.SetSeverityText(entry.Severity.String())
+	record.AddAttributes(h.attrs...)
+
+	if name := entry.LogName; name != "" {
+		record.AddAttributes(attribute.String("logName", name))
+	}
+
+	if count := len(entry.Labels); count > 0 {
+		labels := make([]attribute.KeyValue, 0, count)
+		// add the labels
+		for k, v := range entry.Labels {
+			labels = append(labels, attribute.String(k, v))
+		}
+
+		record.AddAttributes(attribute.Map(slogr.FieldLabels, sorted(labels)...))
+	}
+
+	for _, field := range []struct {
+		key string
+		msg proto.Message
+	}{
+		{key: slogr.FieldHTTPRequest, msg: entry.HttpRequest},
+		{key: slogr.FieldOperation, msg: entry.Operation},
+		{key: slogr.FieldSourceLocation, msg: entry.SourceLocation},
+	} {
+		if value, ok := message(field.msg); ok {
+			record.AddAttributes(attribute.KeyValue{Key: attribute.Key(field.key), Value: value})
+		}
+	}
+
+	switch payload := entry.GetPayload().(type) {
+	case string:
+		record.SetBody(attribute.StringValue(payload))
+	case proto.Message:
+		data, err := protojson.Marshal(payload)
+		if err != nil {
+			return err
+		}
+
+		props := make(map[string]interface{})
+		// convert the data to a map[string]interface{}
+		if err = json.Unmarshal(data, &props); err != nil {
+			return err
+		}
+
+		if text, ok := props[slogr.FieldPayloadMessage].(string); ok {
+			delete(props, slogr.FieldPayloadMessage)
+			// the message is the body
+			record.SetBody(attribute.StringValue(text))
+			record.AddAttributes(mapping(props).AsMap()...)
+		} else {
+			record.SetBody(mapping(props))
+		}
+	}
+
+	// the trace of the entry can come from attributes, not only the context
+	if sctx, ok := spanContext(entry); ok {
+		ctx = trace.ContextWithSpanContext(ctx, sctx)
+	}
+
+	h.logger.Emit(ctx, record)
+	// done!
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.clone()
+	c.handler = h.handler.WithAttrs(attrs).(*slogr.Handler)
+	return c
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	c := h.clone()
+	c.handler = h.handler.WithGroup(name).(*slogr.Handler)
+	return c
+}
+
+func (h *Handler) clone() *Handler {
+	return &Handler{
+		handler: h.handler,
+		logger:  h.logger,
+		attrs:   h.attrs,
+	}
+}
+
+func severity(v ltype.LogSeverity) log.Severity {
+	switch v {
+	case ltype.LogSeverity_DEBUG:
+		return log.SeverityDebug
+	case ltype.LogSeverity_INFO:
+		return log.SeverityInfo
+	case ltype.LogSeverity_NOTICE:
+		return log.SeverityInfo2
+	case ltype.LogSeverity_WARNING:
+		return log.SeverityWarn
+	case ltype.LogSeverity_ERROR:
+		return log.SeverityError
+	case ltype.LogSeverity_CRITICAL:
+		return log.SeverityFatal
+	case ltype.LogSeverity_ALERT:
+		return log.SeverityFatal2
+	case ltype.LogSeverity_EMERGENCY:
+		return log.SeverityFatal4
+	default:
+		return log.SeverityUndefined
+	}
+}
+
+func spanContext(entry *slogr.Entry) (trace.SpanContext, bool) {
+	if entry.Trace == "" {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, err := trace.TraceIDFromHex(path.Base(entry.Trace))
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	spanID, err := trace.SpanIDFromHex(entry.SpanId)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	config := trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+	}
+
+	if entry.TraceSampled {
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	sctx := trace.NewSpanContext(config)
+	// done!
+	return sctx, sctx.IsValid()
+}
+
+func message(msg proto.Message) (attribute.Value, bool) {
+	if msg == nil || !msg.ProtoReflect().IsValid() {
+		return attribute.Value{}, false
+	}
+
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return attribute.Value{}, false
+	}
+
+	props := make(map[string]interface{})
+	// convert the data to a map[string]interface{}
+	if err = json.Unmarshal(data, &props); err != nil {
+		return attribute.Value{}, false
+	}
+
+	return mapping(props), true
+}
+
+func mapping(props map[string]interface{}) attribute.Value {
+	attrs := make([]attribute.KeyValue, 0, len(props))
+
+	for k, v := range props {
+		attrs = append(attrs, attribute.KeyValue{
+			Key:   attribute.Key(k),
+			Value: value(v),
+		})
+	}
+
+	return attribute.MapValue(sorted(attrs)...)
+}
+
+func value(v interface{}) attribute.Value {
+	switch x := v.(type) {
+	case string:
+		return attribute.StringValue(x)
+	case float64:
+		return attribute.Float64Value(x)
+	case bool:
+		return attribute.BoolValue(x)
+	case map[string]interface{}:
+		return mapping(x)
+	case []interface{}:
+		collection := make([]attribute.Value, 0, len(x))
+		// convert the items
+		for _, item := range x {
+			collection = append(collection, value(item))
+		}
+
+		return attribute.SliceValue(collection...)
+	default:
+		return attribute.Value{}
+	}
+}
+
+func sorted(attrs []attribute.KeyValue) []attribute.KeyValue {
+	sort.Slice(attrs, func(i, j int) bool {
+		return attrs[i].Key < attrs[j].Key
+	})
+
+	return attrs
+}
@@ -0,0 +1,50 @@
+package slogr
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"runtime"
+	"testing"
+)
+
+// The tests of this file are portable, so they run on every platform.
+
+func TestPathSlashes(t *testing.T) {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo, ProjectID: "my-project"}).(*Handler)
+
+	if got, want := handler.path("logs", "audit"), "projects/my-project/logs/audit"; got != want {
+		t.Fatalf("got path %q, want %q", got, want)
+	}
+}
+
+func TestJournalUnsupportedPlatform(t *testing.T) {
+	writer := NewJournalWriter(&JournalOptions{Fallback: io.Discard})
+
+	switch err := writer.Err(); {
+	case runtime.GOOS != "linux":
+		if !errors.Is(err, ErrUnsupportedPlatform) || writer.Journal() {
+			t.Fatalf("got %v, want ErrUnsupportedPlatform", err)
+		}
+	case errors.Is(err, ErrUnsupportedPlatform):
+		t.Fatalf("got %v on linux", err)
+	case writer.Journal() != (err == nil):
+		t.Fatalf("got journal %v with %v", writer.Journal(), err)
+	}
+
+	// the entries are written to the fallback writer without the journal
+	logger := slog.New(NewJournalHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+	logger.Info("portable")
+}
+
+func TestNotifyReloadUnsupportedPlatform(t *testing.T) {
+	signals := make(chan os.Signal, 1)
+	defer signal.Stop(signals)
+
+	err := notifyReload(signals)
+	if want := runtime.GOOS == "js"; errors.Is(err, ErrUnsupportedPlatform) != want {
+		t.Fatalf("got %v on %s", err, runtime.GOOS)
+	}
+}
@@ -0,0 +1,63 @@
+package slogr
+
+import (
+	"context"
+	"net/url"
+	"regexp"
+)
+
+// RouteFailedLabel represents the label of the entries whose project override
+// is malformed.
+const RouteFailedLabel = "tenant_route_failed"
+
+var (
+	projectKey     = &ContextKey{name: "project"}
+	projectPattern = regexp.MustCompile(`^[a-z][a-z0-9-]{4,28}[a-z0-9]$`)
+)
+
+// WithProjectOverride provides a project id in a given context. The handler
+// uses it instead of HandlerOptions.ProjectID to build the log name and the
+// trace of the entries logged with the context. The APIWriter writes the
+// entries to the project of their override.
+func WithProjectOverride(ctx context.Context, projectID string) context.Context {
+	return context.WithValue(ctx, projectKey, projectID)
+}
+
+// route returns the handler for the project override of a given context. The
+// handler is returned unchanged when the context has no override, or when the
+// override is malformed, in which case route reports the failure.
+func (h *Handler) route(ctx context.Context) (*Handler, bool) {
+	project, ok := ctx.Value(projectKey).(string)
+	if !ok || project == h.project {
+		return h, false
+	}
+
+	if !projectPattern.MatchString(project) {
+		return h, true
+	}
+
+	c := h.clone()
+	c.project = project
+	c.overridden = true
+	// done!
+	return c, false
+}
+
+// namedWriter is implemented by the entry writers that name the entries
+// without a log name.
+type namedWriter interface {
+	logName() string
+}
+
+// qualify names a given entry of a project override after the default log
+// name of the entry writer, when the entry has no log name, so the writer
+// writes it to the project of the override instead of its own.
+func (h *Handler) qualify(e *Entry) {
+	if !h.overridden || e.LogName != "" {
+		return
+	}
+
+	if w, ok := h.sink.(namedWriter); ok {
+		e.LogName = h.path("logs", url.PathEscape(w.logName()))
+	}
+}
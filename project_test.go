@@ -0,0 +1,80 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestProjectOverride(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContextOf(1))
+	for _, project := range []string{"tenant-alpha", "tenant-beta", "Bad_Tenant"} {
+		logger.InfoContext(WithProjectOverride(ctx, project), "routed")
+	}
+
+	collection := entries()
+	if len(collection) != 3 {
+		t.Fatalf("got %d entries, want 3", len(collection))
+	}
+
+	// the malformed override falls back to the default project
+	for index, project := range []string{"tenant-alpha", "tenant-beta", "my-project"} {
+		want := "projects/" + project + "/traces/" + spanContextOf(1).TraceID().String()
+		if got := collection[index][FieldTrace]; got != want {
+			t.Errorf("entry %d: got trace %v, want %s", index, got, want)
+		}
+
+		labels, _ := collection[index][FieldLabels].(map[string]any)
+		if _, failed := labels[RouteFailedLabel]; failed != (index == 2) {
+			t.Errorf("entry %d: got labels %v", index, labels)
+		}
+	}
+}
+
+func TestProjectOverrideOfAPIWriter(t *testing.T) {
+	client := &loggingClient{}
+	writer := newTestAPIWriter(t, client, APIWriterOptions{})
+
+	logger := slog.New(NewAPIHandler(writer, &HandlerOptions{Level: slog.LevelInfo}))
+	logger.InfoContext(WithProjectOverride(context.Background(), "tenant-alpha"), "unnamed")
+	logger.InfoContext(WithProjectOverride(context.Background(), "tenant-beta"), "named", Name("audit"))
+	logger.Info("default")
+	logger.InfoContext(WithProjectOverride(context.Background(), "Bad_Tenant"), "malformed")
+
+	if err := writer.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := client.sizes(); len(got) != 3 || got[0] != 1 || got[1] != 1 || got[2] != 2 {
+		t.Fatalf("got requests of %v entries, want [1 1 2]", got)
+	}
+
+	for index, tc := range []struct {
+		request string
+		entries []string
+	}{
+		{"projects/tenant-alpha/logs/slogr", []string{"projects/tenant-alpha/logs/slogr"}},
+		{"projects/tenant-beta/logs/slogr", []string{"projects/tenant-beta/logs/audit"}},
+		{"projects/my-project/logs/slogr", []string{"", ""}},
+	} {
+		request := client.requests[index]
+		if request.LogName != tc.request {
+			t.Errorf("request %d: got log name %q, want %q", index, request.LogName, tc.request)
+		}
+
+		for position, want := range tc.entries {
+			if got := request.Entries[position].LogName; got != want {
+				t.Errorf("request %d: got entry log name %q, want %q", index, got, want)
+			}
+		}
+	}
+
+	// the malformed override is written to the default project
+	if got := client.requests[2].Entries[1].Labels[RouteFailedLabel]; got != "true" {
+		t.Errorf("got label %q, want the failed route", got)
+	}
+}
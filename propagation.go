@@ -0,0 +1,136 @@
+package slogr
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SpanContextFromRequest returns the remote span context of a given request.
+// The headers are tried in order: the W3C traceparent header, the
+// X-Cloud-Trace-Context header, then the B3 single header and the B3 multiple
+// headers. An invalid span context is returned when none is present.
+//
+// Use it with SpanContext or trace.ContextWithRemoteSpanContext, so the trace
+// flows into the entries.
+func SpanContextFromRequest(r *http.Request) trace.SpanContext {
+	for _, extract := range []func(http.Header) (trace.SpanContext, bool){
+		traceParent,
+		cloudTraceContext,
+		b3Single,
+		b3Multiple,
+	} {
+		if sctx, ok := extract(r.Header); ok {
+			return sctx
+		}
+	}
+
+	return trace.SpanContext{}
+}
+
+// traceParent extracts a span context from the "traceparent" header,
+// formatted as "version-traceid-spanid-flags".
+func traceParent(header http.Header) (trace.SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header.Get("traceparent")), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[3]) != 2 {
+		return trace.SpanContext{}, false
+	}
+
+	flags, err := strconv.ParseUint(parts[3], 16, 8)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	return remoteSpanContext(parts[1], parts[2], flags&1 == 1)
+}
+
+// cloudTraceContext extracts a span context from the "X-Cloud-Trace-Context"
+// header, formatted as "traceid/spanid;o=options" with a decimal span id.
+func cloudTraceContext(header http.Header) (trace.SpanContext, bool) {
+	value := strings.TrimSpace(header.Get("X-Cloud-Trace-Context"))
+	if value == "" {
+		return trace.SpanContext{}, false
+	}
+
+	traceID, rest, _ := strings.Cut(value, "/")
+	spanID, options, _ := strings.Cut(rest, ";")
+
+	id, err := strconv.ParseUint(spanID, 10, 64)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	return remoteSpanContext(traceID, fmt.Sprintf("%016x", id), options == "o=1")
+}
+
+// b3Single extracts a span context from the "b3" header, formatted as
+// "traceid-spanid-sampled-parentspanid" with optional sampled and parent span
+// id parts.
+func b3Single(header http.Header) (trace.SpanContext, bool) {
+	parts := strings.Split(strings.TrimSpace(header.Get("b3")), "-")
+	// the header can carry only the sampling decision
+	if len(parts) < 2 {
+		return trace.SpanContext{}, false
+	}
+
+	var sampled bool
+	// the debug flag implies the sampling
+	if len(parts) > 2 {
+		sampled = parts[2] == "1" || parts[2] == "d"
+	}
+
+	return remoteSpanContext(b3TraceID(parts[0]), parts[1], sampled)
+}
+
+// b3Multiple extracts a span context from the "X-B3-TraceId", "X-B3-SpanId",
+// "X-B3-Sampled" and "X-B3-Flags" headers.
+func b3Multiple(header http.Header) (trace.SpanContext, bool) {
+	traceID := strings.TrimSpace(header.Get("X-B3-TraceId"))
+	if traceID == "" {
+		return trace.SpanContext{}, false
+	}
+
+	sampled := header.Get("X-B3-Sampled")
+	// the debug flag implies the sampling
+	debug := header.Get("X-B3-Flags") == "1"
+
+	return remoteSpanContext(b3TraceID(traceID), strings.TrimSpace(header.Get("X-B3-SpanId")), debug || sampled == "1" || sampled == "true")
+}
+
+// b3TraceID left-pads the 64-bit trace ids to 128 bits.
+func b3TraceID(id string) string {
+	if len(id) == 16 {
+		return strings.Repeat("0", 16) + id
+	}
+
+	return id
+}
+
+func remoteSpanContext(traceID, spanID string, sampled bool) (trace.SpanContext, bool) {
+	tid, err := trace.TraceIDFromHex(traceID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	sid, err := trace.SpanIDFromHex(spanID)
+	if err != nil {
+		return trace.SpanContext{}, false
+	}
+
+	config := trace.SpanContextConfig{
+		TraceID: tid,
+		SpanID:  sid,
+		Remote:  true,
+	}
+
+	if sampled {
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	sctx := trace.NewSpanContext(config)
+	// done!
+	return sctx, sctx.IsValid()
+}
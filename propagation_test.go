@@ -0,0 +1,89 @@
+package slogr
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSpanContextFromRequest(t *testing.T) {
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+
+	for _, tc := range []struct {
+		name    string
+		header  map[string]string
+		traceID string
+		spanID  string
+		sampled bool
+	}{
+		{"traceparent", map[string]string{"traceparent": "00-" + traceID + "-" + spanID + "-01"}, traceID, spanID, true},
+		{"traceparent unsampled", map[string]string{"traceparent": "00-" + traceID + "-" + spanID + "-00"}, traceID, spanID, false},
+		{"traceparent invalid version", map[string]string{"traceparent": "ff-" + traceID + "-" + spanID + "-01"}, "", "", false},
+		{"cloud trace", map[string]string{"X-Cloud-Trace-Context": traceID + "/67667974448284343;o=1"}, traceID, "00f067aa0ba902b7", true},
+		{"cloud trace unsampled", map[string]string{"X-Cloud-Trace-Context": traceID + "/67667974448284343"}, traceID, spanID, false},
+		{"cloud trace invalid span", map[string]string{"X-Cloud-Trace-Context": traceID + "/span;o=1"}, "", "", false},
+		{"b3 single", map[string]string{"b3": traceID + "-" + spanID + "-1"}, traceID, spanID, true},
+		{"b3 single debug", map[string]string{"b3": traceID + "-" + spanID + "-d-0000000000000001"}, traceID, spanID, true},
+		{"b3 single unsampled", map[string]string{"b3": traceID + "-" + spanID}, traceID, spanID, false},
+		{"b3 single 64-bit", map[string]string{"b3": "a3ce929d0e0e4736-" + spanID + "-1"}, "0000000000000000a3ce929d0e0e4736", spanID, true},
+		{"b3 sampling only", map[string]string{"b3": "0"}, "", "", false},
+		{"b3 multiple", map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Sampled": "1"}, traceID, spanID, true},
+		{"b3 multiple debug", map[string]string{"X-B3-TraceId": traceID, "X-B3-SpanId": spanID, "X-B3-Flags": "1"}, traceID, spanID, true},
+		{"b3 multiple 64-bit", map[string]string{"X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": spanID, "X-B3-Sampled": "true"}, "0000000000000000a3ce929d0e0e4736", spanID, true},
+		{"b3 multiple without span", map[string]string{"X-B3-TraceId": traceID}, "", "", false},
+		// the single header wins over the multiple headers
+		{"b3 precedence", map[string]string{"b3": traceID + "-" + spanID + "-0", "X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": "0000000000000001"}, traceID, spanID, false},
+		// the W3C header wins over the other ones
+		{"precedence", map[string]string{"traceparent": "00-" + traceID + "-" + spanID + "-01", "b3": "a3ce929d0e0e4736-0000000000000001-0"}, traceID, spanID, true},
+		{"none", nil, "", "", false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		for key, value := range tc.header {
+			r.Header.Set(key, value)
+		}
+
+		sctx := SpanContextFromRequest(r)
+		if tc.traceID == "" {
+			if sctx.IsValid() {
+				t.Errorf("%s: got %v, want an invalid span context", tc.name, sctx)
+			}
+
+			continue
+		}
+
+		if got := sctx.TraceID().String(); got != tc.traceID {
+			t.Errorf("%s: got trace id %s, want %s", tc.name, got, tc.traceID)
+		}
+
+		if got := sctx.SpanID().String(); got != tc.spanID {
+			t.Errorf("%s: got span id %s, want %s", tc.name, got, tc.spanID)
+		}
+
+		if sctx.IsSampled() != tc.sampled || !sctx.IsRemote() {
+			t.Errorf("%s: got sampled %v and remote %v, want sampled %v", tc.name, sctx.IsSampled(), sctx.IsRemote(), tc.sampled)
+		}
+	}
+}
+
+func TestSpanContextFromRequestEntry(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("b3", "a3ce929d0e0e4736-00f067aa0ba902b7-1")
+
+	logger.Info("extracted", SpanContext(SpanContextFromRequest(r)))
+
+	entry := single(t, entries())
+	for key, want := range map[string]any{
+		FieldTrace:        "projects/my-project/traces/0000000000000000a3ce929d0e0e4736",
+		FieldSpanID:       "00f067aa0ba902b7",
+		FieldTraceSampled: true,
+	} {
+		if got := entry[key]; got != want {
+			t.Errorf("got %s %v, want %v", key, got, want)
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package pubsub provides a [slog.Handler] that publishes the Google Cloud
+// Logging shaped entries of slogr to a Pub/Sub topic.
+package pubsub
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"sync"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"cloud.google.com/go/pubsub"
+	"github.com/ralch/slogr"
+	"google.golang.org/protobuf/proto"
+)
+
+// Format reports how the entries are encoded in the messages.
+type Format int
+
+const (
+	// FormatJSON encodes the entries in the logging agent format.
+	FormatJSON Format = iota
+	// FormatProto encodes the entries as binary LogEntry protos.
+	FormatProto
+)
+
+// The attribute keys of the messages.
+const (
+	AttributeSeverity = "severity"
+	AttributeLogName  = "logName"
+)
+
+// HandlerOptions for the Pub/Sub handler. A zero HandlerOptions consists
+// entirely of default values.
+type HandlerOptions struct {
+	slogr.HandlerOptions
+
+	// Format reports how the entries are encoded.
+	// By default, the entries are encoded in JSON.
+	Format Format
+
+	// MaxPending is the maximum number of published messages whose results
+	// are awaited. Handle blocks when it's reached. If MaxPending is zero, the
+	// handler assumes 1000.
+	MaxPending int
+
+	// OnError is called with the error of a message that couldn't be
+	// published. If OnError is nil, the errors are ignored.
+	OnError func(error)
+}
+
+// Handler implements a [slog.Handler].
+type Handler struct {
+	handler   slog.Handler
+	publisher *publisher
+}
+
+// NewHandler creates a [slog.Handler] that publishes the entries to a given
+// topic. The messages are batched by the PublishSettings of the topic. The
+// trace id of an entry is its ordering key, which is used when the topic has
+// EnableMessageOrdering set. The records go through the levels and the stats
+// of slogr.Handler. Close the handler to stop the topic.
+func NewHandler(topic *pubsub.Topic, opts *HandlerOptions) *Handler {
+	if opts == nil {
+		opts = &HandlerOptions{}
+	}
+
+	options := opts.HandlerOptions
+	// the level of a zero HandlerOptions
+	if options.Level == nil {
+		options.Level = slog.LevelInfo
+	}
+
+	size := opts.MaxPending
+	if size <= 0 {
+		size = 1000
+	}
+
+	p := &publisher{
+		topic:   topic,
+		format:  opts.Format,
+		onError: opts.OnError,
+		closing: make(chan struct{}),
+		results: make(chan published, size),
+		done:    make(chan struct{}),
+	}
+
+	go p.run()
+
+	return &Handler{
+		handler:   slogr.NewEntryHandler(p, &options),
+		publisher: p,
+	}
+}
+
+// Enabled implements slog.Handler
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler. The message is published in the
+// background, and its error is reported to OnError.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &Handler{
+		handler:   h.handler.WithAttrs(attrs),
+		publisher: h.publisher,
+	}
+}
+
+// WithGroup implements slog.Handler
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return &Handler{
+		handler:   h.handler.WithGroup(name),
+		publisher: h.publisher,
+	}
+}
+
+// Stats returns the stats of the handler and of its clones.
+func (h *Handler) Stats() slogr.Stats {
+	return h.handler.(*slogr.Handler).Stats()
+}
+
+// Flush publishes the pending messages of the topic.
+func (h *Handler) Flush() error {
+	h.publisher.topic.Flush()
+	return nil
+}
+
+// Close publishes the pending messages, stops the topic, and waits for the
+// results of the messages. The records handled after Close fail with
+// slogr.ErrClosed.
+func (h *Handler) Close() error {
+	return h.publisher.close()
+}
+
+// published is a message whose result is awaited.
+type published struct {
+	result *pubsub.PublishResult
+	key    string
+}
+
+// publisher implements the slogr.EntryWriter of the handler. A single
+// goroutine awaits the results of the published messages in order.
+type publisher struct {
+	topic   *pubsub.Topic
+	format  Format
+	onError func(error)
+
+	mu      sync.RWMutex
+	closed  bool
+	closing chan struct{}
+	pending sync.WaitGroup
+	results chan published
+	done    chan struct{}
+}
+
+// WriteEntry publishes a given entry.
+func (p *publisher) WriteEntry(entry *slogr.Entry) error {
+	data, err := p.encode(entry)
+	if err != nil {
+		return err
+	}
+
+	message := &pubsub.Message{
+		Data: data,
+		Attributes: map[string]string{
+			AttributeSeverity: entry.Severity.String(),
+		},
+	}
+
+	if name := entry.LogName; name != "" {
+		message.Attributes[AttributeLogName] = name
+	}
+
+	if p.topic.EnableMessageOrdering {
+		message.OrderingKey = entry.Trace
+	}
+
+	if !p.enter() {
+		return slogr.ErrClosed
+	}
+
+	defer p.pending.Done()
+
+	item := published{
+		result: p.topic.Publish(context.Background(), message),
+		key:    message.OrderingKey,
+	}
+
+	select {
+	case p.results <- item:
+	case <-p.closing:
+		// the topic is stopped by Close, so the result is ready soon
+		p.check(item)
+	}
+
+	return nil
+}
+
+// enter reports whether the publisher is open, and adds the write to the
+// pending ones.
+func (p *publisher) enter() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.closed {
+		return false
+	}
+
+	p.pending.Add(1)
+	return true
+}
+
+func (p *publisher) run() {
+	defer close(p.done)
+
+	for item := range p.results {
+		p.check(item)
+	}
+}
+
+// check waits for the result of a given message, and reports its error.
+func (p *publisher) check(item published) {
+	if _, err := item.result.Get(context.Background()); err != nil {
+		// the ordering key is paused after a failure
+		if item.key != "" {
+			p.topic.ResumePublish(item.key)
+		}
+
+		if p.onError != nil {
+			p.onError(err)
+		}
+	}
+}
+
+func (p *publisher) close() error {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil
+	}
+
+	p.closed = true
+	close(p.closing)
+	p.mu.Unlock()
+
+	p.topic.Stop()
+	// the queue is closed once the pending writes are done
+	p.pending.Wait()
+	close(p.results)
+
+	<-p.done
+	return nil
+}
+
+func (p *publisher) encode(entry *slogr.Entry) ([]byte, error) {
+	if p.format == FormatProto {
+		return proto.Marshal((*loggingpb.LogEntry)(entry))
+	}
+
+	buffer := &bytes.Buffer{}
+	// encode the entry
+	if err := slogr.EncodeEntry(buffer, entry, slogr.EncodeOptions{LogName: true}); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+}
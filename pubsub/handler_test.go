@@ -0,0 +1,168 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"cloud.google.com/go/pubsub"
+	"cloud.google.com/go/pubsub/pstest"
+	"github.com/ralch/slogr"
+	"google.golang.org/api/option"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/proto"
+)
+
+// newTopic returns a topic of a fake Pub/Sub server.
+func newTopic(t *testing.T) (*pstest.Server, *pubsub.Topic) {
+	t.Helper()
+
+	server := pstest.NewServer()
+	t.Cleanup(func() { server.Close() })
+
+	conn, err := grpc.Dial(server.Addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	ctx := context.Background()
+
+	client, err := pubsub.NewClient(ctx, "my-project", option.WithGRPCConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	topic, err := client.CreateTopic(ctx, "logs")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return server, topic
+}
+
+func TestHandler(t *testing.T) {
+	server, topic := newTopic(t)
+
+	handler := NewHandler(topic, nil)
+	logger := slog.New(handler)
+	logger.Debug("ignored")
+	logger.Info("published", slogr.Name("audit"), "user", "alice")
+
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 {
+		t.Fatalf("got %d messages, want 1", len(messages))
+	}
+
+	message := messages[0]
+	if got := message.Attributes[AttributeSeverity]; got != "INFO" {
+		t.Errorf("got severity %q, want INFO", got)
+	}
+
+	if got := message.Attributes[AttributeLogName]; got != "audit" {
+		t.Errorf("got log name %q, want audit", got)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(message.Data, &entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if payload, _ := entry["message"].(map[string]any); payload["user"] != "alice" {
+		t.Errorf("got entry %v, want the user in the payload", entry)
+	}
+
+	if got := handler.Stats().Emitted["INFO"]; got != 1 {
+		t.Errorf("got %d emitted entries, want 1", got)
+	}
+
+	if err := logger.Handler().Handle(context.Background(), slog.Record{Level: slog.LevelInfo}); !errors.Is(err, slogr.ErrClosed) {
+		t.Errorf("got %v after Close, want ErrClosed", err)
+	}
+}
+
+func TestHandlerLevelByName(t *testing.T) {
+	server, topic := newTopic(t)
+
+	handler := NewHandler(topic, &HandlerOptions{
+		HandlerOptions: slogr.HandlerOptions{
+			Level:       slog.LevelInfo,
+			LevelByName: map[string]slog.Leveler{"noisy": slog.LevelWarn},
+		},
+	})
+
+	logger := slog.New(handler).With(slogr.Name("noisy"))
+	logger.Info("dropped")
+	logger.Warn("published")
+
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(server.Messages()); got != 1 {
+		t.Fatalf("got %d messages, want 1", got)
+	}
+
+	if got := handler.Stats().Dropped; got != 1 {
+		t.Errorf("got %d dropped records, want 1", got)
+	}
+}
+
+func TestHandlerProto(t *testing.T) {
+	server, topic := newTopic(t)
+
+	handler := NewHandler(topic, &HandlerOptions{Format: FormatProto})
+	slog.New(handler).Warn("published")
+
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := &loggingpb.LogEntry{}
+	if err := proto.Unmarshal(server.Messages()[0].Data, entry); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := entry.GetTextPayload(); got != "published" {
+		t.Fatalf("got payload %q, want published", got)
+	}
+}
+
+func TestHandlerError(t *testing.T) {
+	_, topic := newTopic(t)
+
+	errs := make(chan error, 10)
+	handler := NewHandler(topic, &HandlerOptions{
+		MaxPending: 1,
+		OnError:    func(err error) { errs <- err },
+	})
+
+	// the messages of a stopped topic fail
+	topic.Stop()
+
+	logger := slog.New(handler)
+	for index := 0; index < 3; index++ {
+		logger.Info("failed")
+	}
+
+	if err := handler.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := len(errs); got != 3 {
+		t.Fatalf("got %d errors, want 3", got)
+	}
+
+	if err := <-errs; !errors.Is(err, pubsub.ErrTopicStopped) {
+		t.Fatalf("got %v, want ErrTopicStopped", err)
+	}
+}
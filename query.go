@@ -0,0 +1,126 @@
+package slogr
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+var (
+	queryNameKey  = &ContextKey{name: "query_name"}
+	queryStartKey = &ContextKey{name: "query_start"}
+)
+
+// WithQueryName provides the statement name of the next query in a given
+// context. The name is used by QueryHook instead of the statement digest.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey, name)
+}
+
+// QueryDigest returns a stable digest of a given statement, so the statement
+// can be identified without logging the literals it contains.
+func QueryDigest(query string) string {
+	query = strings.Join(strings.Fields(query), " ")
+	// compute the digest
+	sum := sha256.Sum256([]byte(query))
+	// done!
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// Query returns an Attr for a database query.
+// A negative number of rows means the number is unknown and is omitted.
+//
+// Use Query to collect several Attrs under a query
+// key on a log line.
+func Query(name string, dur time.Duration, rows int64, err error) slog.Attr {
+	attrs := []any{
+		slog.String("name", name),
+		slog.Duration("duration", dur),
+	}
+
+	if rows >= 0 {
+		attrs = append(attrs, slog.Int64("rows", rows))
+	}
+
+	if err != nil {
+		attrs = append(attrs, slog.String(ErrorKey, err.Error()))
+	}
+
+	return slog.Group(QueryKey, attrs...)
+}
+
+// QueryHook logs database queries with the logger from the context. Its
+// Before, After and OnError methods match the hooks expected by
+// sqlhooks-style driver wrappers.
+type QueryHook struct {
+	// Level reports the level of successful queries.
+	// If Level is nil, the hook assumes LevelDebug.
+	Level slog.Leveler
+
+	// SlowThreshold reports the duration above which a query is logged at
+	// LevelWarn. A zero SlowThreshold disables the feature.
+	SlowThreshold time.Duration
+}
+
+// Before records the start time of the query.
+func (h *QueryHook) Before(ctx context.Context, query string, args ...any) (context.Context, error) {
+	return context.WithValue(ctx, queryStartKey, time.Now()), nil
+}
+
+// After logs a successful query.
+func (h *QueryHook) After(ctx context.Context, query string, args ...any) (context.Context, error) {
+	h.log(ctx, query, nil)
+	return ctx, nil
+}
+
+// OnError logs a failed query.
+func (h *QueryHook) OnError(ctx context.Context, err error, query string, args ...any) error {
+	h.log(ctx, query, err)
+	return err
+}
+
+// Log logs a query with a given name, duration, rows and error. Failed queries
+// are logged at LevelError.
+func (h *QueryHook) Log(ctx context.Context, name string, dur time.Duration, rows int64, err error) {
+	level := slog.LevelDebug
+	// use the configured level if present
+	if h.Level != nil {
+		level = h.Level.Level()
+	}
+
+	attrs := []slog.Attr{
+		Query(name, dur, rows, err),
+	}
+
+	switch {
+	case err != nil:
+		level = slog.LevelError
+	case h.SlowThreshold > 0 && dur > h.SlowThreshold:
+		level = max(level, slog.LevelWarn)
+		// mark the query as slow
+		attrs = append(attrs,
+			slog.Bool("slow_query", true),
+			slog.Duration("slow_query_threshold", h.SlowThreshold),
+		)
+	}
+
+	FromContext(ctx).LogAttrs(ctx, level, "query", attrs...)
+}
+
+func (h *QueryHook) log(ctx context.Context, query string, err error) {
+	name, ok := ctx.Value(queryNameKey).(string)
+	if !ok {
+		name = QueryDigest(query)
+	}
+
+	var dur time.Duration
+	// compute the duration if the start time is present
+	if start, ok := ctx.Value(queryStartKey).(time.Time); ok {
+		dur = time.Since(start)
+	}
+
+	h.Log(ctx, name, dur, -1, err)
+}
@@ -0,0 +1,192 @@
+package slogr
+
+import (
+	"container/list"
+	"context"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SuppressedKey represents the key of the number of the entries suppressed by
+// the rate limit before an entry.
+const SuppressedKey = "suppressed"
+
+// RateLimitOptions for the rate limit handler. A zero RateLimitOptions
+// consists entirely of default values.
+type RateLimitOptions struct {
+	// Burst is the number of entries of a key allowed per Interval.
+	// If Burst is zero, the handler assumes 10.
+	Burst int
+
+	// Interval is the time in which the tokens of a key are refilled.
+	// If Interval is zero, the handler assumes 1s.
+	Interval time.Duration
+
+	// Key returns the key of a given record. If Key is nil, the key is the
+	// level and the message of the record.
+	Key func(r slog.Record) string
+
+	// MaxKeys is the number of keys the handler keeps track of. The least
+	// recently used key is forgotten beyond it.
+	// If MaxKeys is zero, the handler assumes 1000.
+	MaxKeys int
+
+	// Exempt reports the level at or above which the entries are never
+	// limited. If Exempt is nil, every level is limited.
+	Exempt slog.Leveler
+}
+
+// NewRateLimitHandler creates a [slog.Handler] that limits the entries of
+// every key with a token bucket, and drops the excess. The first entry allowed
+// after a drop carries the number of the suppressed entries as SuppressedKey.
+func NewRateLimitHandler(inner slog.Handler, opts *RateLimitOptions) slog.Handler {
+	if opts == nil {
+		opts = &RateLimitOptions{}
+	}
+
+	state := &rateLimitState{
+		burst:    float64(opts.Burst),
+		interval: opts.Interval,
+		size:     opts.MaxKeys,
+		keys:     make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+
+	if state.burst <= 0 {
+		state.burst = 10
+	}
+
+	if state.interval <= 0 {
+		state.interval = time.Second
+	}
+
+	if state.size <= 0 {
+		state.size = 1000
+	}
+
+	return &rateLimitHandler{
+		inner:  inner,
+		key:    opts.Key,
+		exempt: opts.Exempt,
+		state:  state,
+	}
+}
+
+// rateLimitBucket represents the token bucket of a key.
+type rateLimitBucket struct {
+	key        string
+	tokens     float64
+	last       time.Time
+	suppressed int
+}
+
+// rateLimitState represents the buckets shared by a handler and its clones,
+// in the least recently used order.
+type rateLimitState struct {
+	burst    float64
+	interval time.Duration
+	size     int
+	now      func() time.Time
+
+	mu    sync.Mutex
+	keys  map[string]*list.Element
+	order *list.List
+}
+
+type rateLimitHandler struct {
+	inner  slog.Handler
+	key    func(r slog.Record) string
+	exempt slog.Leveler
+	state  *rateLimitState
+}
+
+// Enabled implements slog.Handler
+func (h *rateLimitHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *rateLimitHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.exempt != nil && r.Level >= h.exempt.Level() {
+		return h.inner.Handle(ctx, r)
+	}
+
+	key := r.Message + "\x00" + strconv.Itoa(int(r.Level))
+	if h.key != nil {
+		key = h.key(r)
+	}
+
+	allowed, suppressed := h.state.take(key)
+	if !allowed {
+		return nil
+	}
+
+	if suppressed > 0 {
+		// the record is shared with the caller
+		r = r.Clone()
+		r.AddAttrs(slog.Int(SuppressedKey, suppressed))
+	}
+
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *rateLimitHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *rateLimitHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	return &c
+}
+
+// handlerStats implements statsHolder
+func (h *rateLimitHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+// take takes a token of a given key. It reports whether the entry is allowed,
+// and the number of the entries suppressed before it.
+func (s *rateLimitState) take(key string) (bool, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+
+	element, ok := s.keys[key]
+	if ok {
+		s.order.MoveToFront(element)
+	} else {
+		element = s.order.PushFront(&rateLimitBucket{key: key, tokens: s.burst, last: now})
+		s.keys[key] = element
+		// forget the least recently used key
+		if s.order.Len() > s.size {
+			oldest := s.order.Back()
+			s.order.Remove(oldest)
+			delete(s.keys, oldest.Value.(*rateLimitBucket).key)
+		}
+	}
+
+	bucket := element.Value.(*rateLimitBucket)
+	// refill the tokens
+	bucket.tokens = min(s.burst, bucket.tokens+s.burst*float64(now.Sub(bucket.last))/float64(s.interval))
+	bucket.last = now
+
+	if bucket.tokens < 1 {
+		bucket.suppressed++
+		return false, 0
+	}
+
+	bucket.tokens--
+
+	suppressed := bucket.suppressed
+	bucket.suppressed = 0
+	return true, suppressed
+}
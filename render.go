@@ -0,0 +1,97 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// Renderer renders the JSON object of a given record, e.g. with the field
+// conventions of another slog handler.
+type Renderer func(ctx context.Context, r slog.Record) (json.RawMessage, error)
+
+// WrapJSONHandler creates a [slog.Handler] that writes the entries in the
+// Google Cloud Logging agent format to w, with the payload rendered by a given
+// renderer. The envelope fields, such as the severity, trace, labels and log
+// name, are computed from the record and the context as usual, and the
+// rendered JSON object is embedded as the payload verbatim. The message is
+// added to the payload under FieldPayloadMessage when it's missing. The record
+// passed to the renderer has the attributes of WithAttrs and WithGroup.
+func WrapJSONHandler(w io.Writer, render Renderer, opts *HandlerOptions) slog.Handler {
+	h := NewHandler(w, opts).(*Handler)
+	h.render = render
+	return h
+}
+
+// NewJSONRenderer returns a Renderer that renders the records with
+// slog.JSONHandler and a given options.
+func NewJSONRenderer(opts *slog.HandlerOptions) Renderer {
+	return func(ctx context.Context, r slog.Record) (json.RawMessage, error) {
+		buffer := &bytes.Buffer{}
+		// render the record
+		if err := slog.NewJSONHandler(buffer, opts).Handle(ctx, r); err != nil {
+			return nil, err
+		}
+
+		return bytes.TrimSpace(buffer.Bytes()), nil
+	}
+}
+
+func (h *Handler) writeRendered(ctx context.Context, entry *Entry, r slog.Record) error {
+	data, err := h.render(ctx, h.record(r))
+	if err != nil {
+		return err
+	}
+
+	data = bytes.TrimSpace(data)
+
+	props := make(map[string]json.RawMessage)
+	// the payload must be a JSON object
+	if !bytes.HasPrefix(data, []byte("{")) || json.Unmarshal(data, &props) != nil {
+		return errors.New("slogr: rendered payload is not a JSON object")
+	}
+
+	if _, ok := props[FieldPayloadMessage]; !ok {
+		message, err := json.Marshal(r.Message)
+		if err != nil {
+			return err
+		}
+
+		prefix := `{"` + FieldPayloadMessage + `":` + string(message)
+		// inject the message in front of the other fields
+		if len(props) > 0 {
+			prefix += ","
+		}
+
+		data = append([]byte(prefix), bytes.TrimPrefix(data, []byte("{"))...)
+	}
+
+	opts := EncodeOptions{
+		Indent:     h.indent,
+		EscapeHTML: true,
+		LogName:    h.nameField == NameFieldTopLevel && !strings.HasPrefix(entry.LogName, "projects/"),
+		Profile:    h.profile,
+	}
+
+	// the payload is replaced by the rendered one
+	entry.Payload = nil
+
+	attributes, err := entry.fields(opts)
+	if err != nil {
+		return err
+	}
+
+	attributes[FieldMessage] = json.RawMessage(data)
+
+	buffer := &bytes.Buffer{}
+	// encode the entry
+	if err := encode(buffer, attributes, opts); err != nil {
+		return err
+	}
+
+	return h.flush(buffer, entry)
+}
@@ -0,0 +1,130 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestWrapJSONHandler(t *testing.T) {
+	buffer := &bytes.Buffer{}
+
+	handler := WrapJSONHandler(buffer, NewJSONRenderer(nil), &HandlerOptions{
+		Level:     slog.LevelInfo,
+		ProjectID: "my-project",
+		Labels:    map[string]string{"env": "prod"},
+	})
+
+	ctx := trace.ContextWithSpanContext(context.Background(), spanContextOf(1))
+	// the envelope-like fields of the payload are left untouched
+	slog.New(handler).With("user", "ana").WithGroup("call").
+		WarnContext(ctx, "wrapped", "severity", "low", "method", "GET")
+
+	entry := single(t, decodeEntries(t, buffer.Bytes()))
+	if got := entry["severity"]; got != "WARNING" {
+		t.Errorf("got severity %v, want WARNING", got)
+	}
+
+	if labels, _ := entry[FieldLabels].(map[string]any); labels["env"] != "prod" {
+		t.Errorf("got labels %v, want env=prod", labels)
+	}
+
+	if got, want := entry[FieldTrace], "projects/my-project/traces/"+spanContextOf(1).TraceID().String(); got != want {
+		t.Errorf("got trace %v, want %s", got, want)
+	}
+
+	payload := payloadOf(entry)
+	for key, want := range map[string]any{
+		"msg":               "wrapped",
+		"level":             "WARN",
+		"user":              "ana",
+		FieldPayloadMessage: "wrapped",
+	} {
+		if got := payload[key]; got != want {
+			t.Errorf("got %s %v, want %v", key, got, want)
+		}
+	}
+
+	if call, _ := payload["call"].(map[string]any); call["severity"] != "low" || call["method"] != "GET" {
+		t.Errorf("got call %v, want the attributes of the group", call)
+	}
+}
+
+func TestWrapJSONHandlerMessage(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		rendered string
+		want     string
+	}{
+		{"empty", `{}`, `{"` + FieldPayloadMessage + `":"kept"}`},
+		{"missing", ` {"a":1} `, `{"` + FieldPayloadMessage + `":"kept","a":1}`},
+		// the message of the renderer wins
+		{"present", `{"` + FieldPayloadMessage + `":"rendered"}`, `{"` + FieldPayloadMessage + `":"rendered"}`},
+	} {
+		buffer := &bytes.Buffer{}
+
+		render := func(context.Context, slog.Record) (json.RawMessage, error) {
+			return json.RawMessage(tc.rendered), nil
+		}
+
+		handler := WrapJSONHandler(buffer, render, &HandlerOptions{Level: slog.LevelInfo})
+		if err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "kept", 0)); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		got, err := json.Marshal(single(t, decodeEntries(t, buffer.Bytes()))["message"])
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var want any
+		if err := json.Unmarshal([]byte(tc.want), &want); err != nil {
+			t.Fatal(err)
+		}
+
+		if data, _ := json.Marshal(want); string(got) != string(data) {
+			t.Errorf("%s: got payload %s, want %s", tc.name, got, data)
+		}
+	}
+}
+
+func TestWrapJSONHandlerInvalidPayload(t *testing.T) {
+	errRender := errors.New("render failed")
+
+	for _, tc := range []struct {
+		name     string
+		rendered string
+		err      error
+	}{
+		{"array", `[1,2]`, nil},
+		{"string", `"text"`, nil},
+		{"invalid", `{"a":`, nil},
+		{"error", ``, errRender},
+	} {
+		buffer := &bytes.Buffer{}
+
+		render := func(context.Context, slog.Record) (json.RawMessage, error) {
+			return json.RawMessage(tc.rendered), tc.err
+		}
+
+		handler := WrapJSONHandler(buffer, render, &HandlerOptions{Level: slog.LevelInfo})
+
+		err := handler.Handle(context.Background(), slog.NewRecord(time.Now(), slog.LevelInfo, "lost", 0))
+		switch {
+		case err == nil:
+			t.Errorf("%s: got no error", tc.name)
+		case tc.err != nil && !errors.Is(err, tc.err):
+			t.Errorf("%s: got %v, want %v", tc.name, err, tc.err)
+		}
+
+		if buffer.Len() != 0 {
+			t.Errorf("%s: got %s, want nothing written", tc.name, buffer)
+		}
+	}
+}
@@ -0,0 +1,391 @@
+// Package rotate provides an [io.Writer] that writes to a file, and rotates
+// it by size and by age.
+package rotate
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupFormat is the time format of the backup file names.
+const backupFormat = "2006-01-02T15-04-05.000"
+
+// Options for a Writer. Filename is mandatory.
+type Options struct {
+	// Filename is the file to write to. The backups are kept in its directory.
+	Filename string
+
+	// MaxSize is the maximum size of the file in bytes before it's rotated.
+	// If MaxSize is zero, the writer assumes 100MiB.
+	MaxSize int64
+
+	// MaxAge is the maximum age of the file before it's rotated. If MaxAge is
+	// zero, the file isn't rotated by age.
+	MaxAge time.Duration
+
+	// MaxBackups is the maximum number of backups to keep. If MaxBackups is
+	// zero, every backup is kept.
+	MaxBackups int
+
+	// When Compress is true, the backups are compressed with gzip.
+	Compress bool
+
+	// ReopenSignal is the signal that makes the writer reopen the file, such
+	// as syscall.SIGHUP, for the compatibility with an external rotation like
+	// logrotate. If ReopenSignal is nil, the writer reopens it only on Reopen.
+	ReopenSignal os.Signal
+}
+
+// Writer represents a file writer that rotates the file. It's safe for
+// concurrent use.
+//
+// The file is only rotated or reopened after a newline, so a JSON line written
+// with several writes, e.g. by a buffered writer, is never split across two
+// files.
+type Writer struct {
+	filename string
+	maxSize  int64
+	maxAge   time.Duration
+	backups  int
+	compress bool
+
+	mu      sync.Mutex
+	file    *os.File
+	size    int64
+	opened  time.Time
+	newline bool
+	reopen  bool
+	signals chan os.Signal
+	wg      sync.WaitGroup
+	// bg serializes the compression and the pruning
+	bg sync.Mutex
+}
+
+// New creates a Writer, and opens its file for appending.
+func New(opts *Options) (*Writer, error) {
+	if opts.Filename == "" {
+		return nil, errors.New("rotate: the writer requires a file name")
+	}
+
+	w := &Writer{
+		filename: opts.Filename,
+		maxSize:  opts.MaxSize,
+		maxAge:   opts.MaxAge,
+		backups:  opts.MaxBackups,
+		compress: opts.Compress,
+	}
+
+	if w.maxSize <= 0 {
+		w.maxSize = 100 << 20
+	}
+
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+
+	if opts.ReopenSignal != nil {
+		w.signals = make(chan os.Signal, 1)
+		// reopen the file on the signal
+		signal.Notify(w.signals, opts.ReopenSignal)
+
+		w.wg.Add(1)
+		go w.run()
+	}
+
+	return w, nil
+}
+
+// Write implements io.Writer
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	due := w.reopen || w.size > 0 && (w.size+int64(len(p)) > w.maxSize || w.maxAge > 0 && time.Since(w.opened) > w.maxAge)
+	if !due {
+		return w.write(p)
+	}
+
+	// the file is switched after the last complete line
+	index := 0
+	if !w.newline {
+		index = bytes.LastIndexByte(p, '\n') + 1
+	}
+
+	if !w.newline && index == 0 {
+		return w.write(p)
+	}
+
+	n, err := w.write(p[:index])
+	if err != nil {
+		return n, err
+	}
+
+	if w.reopen {
+		err = w.reopenFile()
+	} else {
+		err = w.rotate()
+	}
+
+	if err != nil {
+		return n, err
+	}
+
+	m, err := w.write(p[index:])
+	return n + m, err
+}
+
+func (w *Writer) write(p []byte) (int, error) {
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+
+	if n > 0 {
+		w.newline = p[n-1] == '\n'
+	}
+
+	return n, err
+}
+
+// Rotate rotates the file, regardless of its size and age.
+func (w *Writer) Rotate() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	return w.rotate()
+}
+
+// Reopen closes the file, and opens it again. Use it after the file was moved
+// by an external rotation. When the last write ended in the middle of a line,
+// the file is reopened by the write that completes the line.
+func (w *Writer) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return os.ErrClosed
+	}
+
+	if !w.newline {
+		w.reopen = true
+		return nil
+	}
+
+	return w.reopenFile()
+}
+
+func (w *Writer) reopenFile() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	return w.open()
+}
+
+// Close closes the file, and waits for the compression of the backups.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if w.file == nil {
+		w.mu.Unlock()
+		return os.ErrClosed
+	}
+
+	err := w.file.Close()
+	w.file = nil
+	w.mu.Unlock()
+
+	if w.signals != nil {
+		signal.Stop(w.signals)
+		close(w.signals)
+	}
+
+	w.wg.Wait()
+	return err
+}
+
+// run reopens the file on the signal until the writer is closed.
+func (w *Writer) run() {
+	defer w.wg.Done()
+
+	for range w.signals {
+		_ = w.Reopen()
+	}
+}
+
+// open opens the file for appending.
+func (w *Writer) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.filename), 0o755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(w.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	w.file = file
+	w.size = info.Size()
+	w.opened = time.Now()
+	w.newline = true
+	w.reopen = false
+	// done!
+	return nil
+}
+
+// rotate moves the file to a backup, and opens a new one.
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+
+	backup := w.backupName(time.Now())
+	if err := os.Rename(w.filename, backup); err != nil {
+		return err
+	}
+
+	if err := w.open(); err != nil {
+		return err
+	}
+
+	w.wg.Add(1)
+	// compress and prune the backups in the background
+	go func() {
+		defer w.wg.Done()
+
+		w.bg.Lock()
+		defer w.bg.Unlock()
+
+		if w.compress {
+			_ = compress(backup)
+		}
+
+		w.prune()
+	}()
+
+	return nil
+}
+
+// backupName returns the name of the backup of a given time, such as
+// app-2006-01-02T15-04-05.000.log for app.log. The time of a backup that
+// already exists, from a rotation in the same millisecond, is moved to the
+// next millisecond, so the backups are never overwritten and keep their order.
+func (w *Writer) backupName(t time.Time) string {
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(w.filename, ext)
+
+	for {
+		name := prefix + "-" + t.UTC().Format(backupFormat) + ext
+		// the backup might be compressed already
+		if !exists(name) && !exists(name+".gz") {
+			return name
+		}
+
+		t = t.Add(time.Millisecond)
+	}
+}
+
+// exists reports whether a given file exists.
+func exists(name string) bool {
+	_, err := os.Lstat(name)
+	return !errors.Is(err, os.ErrNotExist)
+}
+
+// prune removes the oldest backups beyond MaxBackups.
+func (w *Writer) prune() {
+	if w.backups <= 0 {
+		return
+	}
+
+	ext := filepath.Ext(w.filename)
+	prefix := strings.TrimSuffix(w.filename, ext) + "-"
+
+	matches, err := filepath.Glob(prefix + "*" + ext + "*")
+	if err != nil {
+		return
+	}
+
+	var (
+		stamps []string
+		names  = make(map[string][]string)
+	)
+
+	// group the files that are named like backups by time
+	for _, name := range matches {
+		stamp := strings.TrimSuffix(strings.TrimSuffix(strings.TrimPrefix(name, prefix), ".gz"), ext)
+		if _, err := time.Parse(backupFormat, stamp); err != nil {
+			continue
+		}
+
+		if _, ok := names[stamp]; !ok {
+			stamps = append(stamps, stamp)
+		}
+
+		names[stamp] = append(names[stamp], name)
+	}
+
+	// the stamps sort by time
+	sort.Sort(sort.Reverse(sort.StringSlice(stamps)))
+
+	for index, stamp := range stamps {
+		if index < w.backups {
+			continue
+		}
+
+		for _, name := range names[stamp] {
+			_ = os.Remove(name)
+		}
+	}
+}
+
+// compress compresses a given file with gzip, and removes it.
+func compress(name string) error {
+	source, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	target, err := os.OpenFile(name+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+
+	writer := gzip.NewWriter(target)
+
+	if _, err := io.Copy(writer, source); err != nil {
+		target.Close()
+		return err
+	}
+
+	if err := writer.Close(); err != nil {
+		target.Close()
+		return err
+	}
+
+	if err := target.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(name)
+}
@@ -0,0 +1,215 @@
+package rotate
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// backups returns the backups of a given file name, oldest first.
+func backups(t *testing.T, filename string) []string {
+	t.Helper()
+
+	ext := filepath.Ext(filename)
+
+	matches, err := filepath.Glob(strings.TrimSuffix(filename, ext) + "-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(matches)
+	return matches
+}
+
+func read(t *testing.T, name string) string {
+	t.Helper()
+
+	file, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var reader io.Reader = file
+	if strings.HasSuffix(name, ".gz") {
+		if reader, err = gzip.NewReader(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return string(data)
+}
+
+func TestWriterRotatesBySize(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := New(&Options{Filename: filename, MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := io.WriteString(w, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := backups(t, filename)
+	if len(collection) != 2 {
+		t.Fatalf("got backups %v, want 2", collection)
+	}
+
+	for index, want := range []string{"first\n", "second\n"} {
+		if got := read(t, collection[index]); got != want {
+			t.Errorf("got backup %q, want %q", got, want)
+		}
+	}
+
+	if got := read(t, filename); got != "third\n" {
+		t.Errorf("got file %q, want the third line", got)
+	}
+}
+
+func TestWriterKeepsPartialLines(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := New(&Options{Filename: filename, MaxSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, chunk := range []string{"a partial", " line\nnext\n", "last\n"} {
+		if _, err := io.WriteString(w, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := backups(t, filename)
+	// the file is switched after the last line of the write
+	if len(collection) != 1 || read(t, collection[0]) != "a partial line\nnext\n" {
+		t.Fatalf("got backups %v, want the complete lines", collection)
+	}
+
+	if got := read(t, filename); got != "last\n" {
+		t.Errorf("got file %q, want the last line", got)
+	}
+}
+
+func TestWriterRotatesInTheSameMillisecond(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := New(&Options{Filename: filename, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const count = 20
+	for index := 0; index < count; index++ {
+		if _, err := io.WriteString(w, "line\n"); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := backups(t, filename)
+	if len(collection) != count {
+		t.Fatalf("got %d backups, want %d", len(collection), count)
+	}
+
+	for _, name := range collection {
+		if got := read(t, name); got != "line\n" {
+			t.Fatalf("got backup %q of %s, want a line", got, name)
+		}
+	}
+}
+
+func TestWriterPrunesBackups(t *testing.T) {
+	filename := filepath.Join(t.TempDir(), "app.log")
+
+	w, err := New(&Options{Filename: filename, MaxBackups: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := io.WriteString(w, line); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Rotate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	collection := backups(t, filename)
+	if len(collection) != 2 || read(t, collection[0]) != "second\n" {
+		t.Fatalf("got backups %v, want the last two", collection)
+	}
+}
+
+func TestWriterReopen(t *testing.T) {
+	var (
+		dir      = t.TempDir()
+		filename = filepath.Join(dir, "app.log")
+		moved    = filepath.Join(dir, "app.log.1")
+	)
+
+	w, err := New(&Options{Filename: filename})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	if _, err := io.WriteString(w, "before\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// the external rotation
+	if err := os.Rename(filename, moved); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Reopen(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := io.WriteString(w, "after\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := read(t, moved); got != "before\n" {
+		t.Errorf("got moved file %q, want the line before", got)
+	}
+
+	if got := read(t, filename); got != "after\n" {
+		t.Errorf("got file %q, want the line after", got)
+	}
+}
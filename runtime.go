@@ -0,0 +1,151 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"math"
+	"math/rand"
+	"os"
+	"runtime/metrics"
+	"time"
+)
+
+// RuntimeKey represents the key of the runtime stats group.
+const RuntimeKey = "runtime"
+
+// RuntimeStatsOptions for the runtime stats logger. A zero RuntimeStatsOptions
+// consists entirely of default values.
+type RuntimeStatsOptions struct {
+	// Name is the log name of the runtime stats entries.
+	// If Name is empty, the logger assumes "runtime".
+	Name string
+
+	// Level reports the level of the runtime stats entries.
+	// If Level is nil, the logger assumes LevelInfo.
+	Level slog.Leveler
+
+	// Jitter reports the maximum random delay added to every interval, so
+	// a fleet of processes doesn't log in lockstep.
+	Jitter time.Duration
+}
+
+var runtimeSamples = []string{
+	"/memory/classes/heap/objects:bytes",
+	"/gc/cycles/total:gc-cycles",
+	"/gc/pauses:seconds",
+	"/sched/goroutines:goroutines",
+}
+
+// RuntimeStatsLogger logs a snapshot of the runtime stats every interval
+// until the context is cancelled. If interval is zero or negative, the logger
+// assumes one minute.
+func RuntimeStatsLogger(ctx context.Context, logger *slog.Logger, interval time.Duration, opts *RuntimeStatsOptions) {
+	if opts == nil {
+		opts = &RuntimeStatsOptions{}
+	}
+
+	// set the default interval
+	if interval <= 0 {
+		interval = time.Minute
+	}
+
+	name := opts.Name
+	// set the default name
+	if name == "" {
+		name = RuntimeKey
+	}
+
+	level := slog.LevelInfo
+	// set the configured level
+	if opts.Level != nil {
+		level = opts.Level.Level()
+	}
+
+	samples := make([]metrics.Sample, len(runtimeSamples))
+	// prepare the samples
+	for index, key := range runtimeSamples {
+		samples[index].Name = key
+	}
+
+	for {
+		delay := interval
+		// add the jitter if configured
+		if opts.Jitter > 0 {
+			delay += time.Duration(rand.Int63n(int64(opts.Jitter)))
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+			metrics.Read(samples)
+			// log the snapshot
+			logger.LogAttrs(ctx, level, "runtime stats", Name(name), RuntimeStats(samples))
+		}
+	}
+}
+
+// RuntimeStats returns an Attr for a given runtime/metrics samples.
+func RuntimeStats(samples []metrics.Sample) slog.Attr {
+	var attrs []any
+
+	for _, sample := range samples {
+		switch sample.Name {
+		case "/memory/classes/heap/objects:bytes":
+			if sample.Value.Kind() == metrics.KindUint64 {
+				attrs = append(attrs, slog.Uint64("heap_inuse_bytes", sample.Value.Uint64()))
+			}
+		case "/gc/cycles/total:gc-cycles":
+			if sample.Value.Kind() == metrics.KindUint64 {
+				attrs = append(attrs, slog.Uint64("gc_cycles", sample.Value.Uint64()))
+			}
+		case "/gc/pauses:seconds":
+			if sample.Value.Kind() == metrics.KindFloat64Histogram {
+				count, pause := histogram(sample.Value.Float64Histogram())
+				attrs = append(attrs,
+					slog.Uint64("gc_pauses", count),
+					slog.Duration("gc_pause_max", pause),
+				)
+			}
+		case "/sched/goroutines:goroutines":
+			if sample.Value.Kind() == metrics.KindUint64 {
+				attrs = append(attrs, slog.Uint64("goroutines", sample.Value.Uint64()))
+			}
+		}
+	}
+
+	// the open file descriptors are only available on some platforms
+	if entries, err := os.ReadDir("/proc/self/fd"); err == nil {
+		attrs = append(attrs, slog.Int("open_fds", len(entries)))
+	}
+
+	return slog.Group(RuntimeKey, attrs...)
+}
+
+// histogram returns the number of observations and the upper bound of the
+// highest non-empty bucket of a given histogram.
+func histogram(h *metrics.Float64Histogram) (uint64, time.Duration) {
+	var (
+		count uint64
+		bound float64
+	)
+
+	for index, value := range h.Counts {
+		if value == 0 {
+			continue
+		}
+
+		count += value
+		// the bucket upper bound can be +Inf
+		if limit := h.Buckets[index+1]; !math.IsInf(limit, 1) {
+			bound = limit
+		} else {
+			bound = h.Buckets[index]
+		}
+	}
+
+	return count, time.Duration(bound * float64(time.Second))
+}
@@ -0,0 +1,79 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"runtime/metrics"
+	"testing"
+	"time"
+)
+
+func TestRuntimeStats(t *testing.T) {
+	samples := make([]metrics.Sample, len(runtimeSamples))
+	for index, key := range runtimeSamples {
+		samples[index].Name = key
+	}
+
+	metrics.Read(samples)
+
+	attr := RuntimeStats(samples)
+	if attr.Key != RuntimeKey {
+		t.Fatalf("got key %q, want %s", attr.Key, RuntimeKey)
+	}
+
+	keys := make(map[string]bool)
+	for _, item := range attr.Value.Group() {
+		keys[item.Key] = true
+	}
+
+	for _, key := range []string{"heap_inuse_bytes", "gc_cycles", "gc_pauses", "gc_pause_max", "goroutines"} {
+		if !keys[key] {
+			t.Errorf("got no %s in %v", key, attr)
+		}
+	}
+}
+
+func TestRuntimeStatsLogger(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		RuntimeStatsLogger(ctx, logger, time.Millisecond, &RuntimeStatsOptions{Name: "stats", Level: slog.LevelWarn})
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	cancel()
+	<-done
+
+	collection := entries()
+	if len(collection) == 0 {
+		t.Fatal("got no runtime stats")
+	}
+
+	entry := collection[0]
+	if got := entry["severity"]; got != "WARNING" {
+		t.Errorf("got severity %v, want WARNING", got)
+	}
+
+	if payload := payloadOf(entry); payload[RuntimeKey] == nil {
+		t.Errorf("got payload %v, want the runtime stats", payload)
+	}
+}
+
+func TestRuntimeStatsLoggerWithoutInterval(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		// the default interval is one minute
+		RuntimeStatsLogger(ctx, logger, interval, nil)
+		cancel()
+	}
+
+	if got := len(entries()); got != 0 {
+		t.Fatalf("got %d entries before the default interval, want 0", got)
+	}
+}
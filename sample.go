@@ -0,0 +1,269 @@
+package slogr
+
+import (
+	"context"
+	"hash/fnv"
+	"log/slog"
+	"math/rand"
+	"slices"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// SamplingOptions for the sampling handler. A zero SamplingOptions keeps
+// every record.
+type SamplingOptions struct {
+	// Rates reports the fraction of the records that are kept per level,
+	// between 0 and 1. A record uses the rate of the highest level at or below
+	// its own, and the records below every level of Rates are kept. For
+	// instance, {LevelDebug: 0.01, LevelInfo: 0.1, LevelWarn: 1} keeps 1% of
+	// the debug records, 10% of the info records, and every record at
+	// LevelWarn or above.
+	Rates map[slog.Level]float64
+
+	// SummaryInterval is the time between the summary entries that report the
+	// number of records sampled out per severity. If SummaryInterval is zero,
+	// the handler writes no summary.
+	SummaryInterval time.Duration
+}
+
+// NewSamplingHandler creates a [slog.Handler] that writes a random sample of
+// the records of every level to a given handler, and drops the others. The
+// records that share a trace are sampled together: a hash of the trace id is
+// compared to the rate of each record, so the records of a level are either
+// all kept or all dropped for a trace, and a trace kept at a rate is kept at
+// the higher ones too. The records at a rate of 1 are always kept.
+//
+// The records sampled out are counted by the stats of the handler, or of the
+// slogr Handler it wraps, and reported by a summary entry every
+// SummaryInterval. Use Flush to write the pending summary.
+func NewSamplingHandler(inner slog.Handler, opts *SamplingOptions) slog.Handler {
+	if opts == nil {
+		opts = &SamplingOptions{}
+	}
+
+	levels := make([]slog.Level, 0, len(opts.Rates))
+	// the highest levels are looked up first
+	for level := range opts.Rates {
+		levels = append(levels, level)
+	}
+
+	slices.Sort(levels)
+	slices.Reverse(levels)
+
+	state := &sampleState{
+		interval: opts.SummaryInterval,
+		dropped:  make(map[slog.Level]int),
+		random:   rand.Float64,
+		handler:  inner,
+	}
+
+	// the drops are counted by the stats of the handler, or of the one it wraps
+	state.stats = statsOf(inner)
+
+	return &sampleHandler{
+		inner:  inner,
+		levels: levels,
+		rates:  opts.Rates,
+		state:  state,
+	}
+}
+
+// sampleState represents the drops shared by a handler and its clones.
+type sampleState struct {
+	interval time.Duration
+	stats    *stats
+	random   func() float64
+	// handler writes the summaries, without the attributes of the clones
+	handler slog.Handler
+
+	mu      sync.Mutex
+	dropped map[slog.Level]int
+	timer   *time.Timer
+}
+
+type sampleHandler struct {
+	inner  slog.Handler
+	levels []slog.Level
+	rates  map[slog.Level]float64
+	state  *sampleState
+}
+
+// Enabled implements slog.Handler
+func (h *sampleHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *sampleHandler) Handle(ctx context.Context, r slog.Record) error {
+	if h.keep(ctx, r) {
+		return h.inner.Handle(ctx, r)
+	}
+
+	h.state.drop(r.Level)
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *sampleHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *sampleHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	return &c
+}
+
+// handlerStats implements statsHolder
+func (h *sampleHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+// Flush writes the pending summary, and flushes the handler when it
+// implements Flusher.
+func (h *sampleHandler) Flush() error {
+	h.state.summarize()
+
+	if flusher, ok := h.inner.(Flusher); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// rate returns the rate of a given level.
+func (h *sampleHandler) rate(level slog.Level) float64 {
+	for _, item := range h.levels {
+		if level >= item {
+			return h.rates[item]
+		}
+	}
+
+	return 1
+}
+
+// keep reports whether a given record is kept.
+func (h *sampleHandler) keep(ctx context.Context, r slog.Record) bool {
+	rate := h.rate(r.Level)
+	// the records at a rate of 1 are always kept
+	if rate >= 1 {
+		return true
+	}
+
+	id := sampleTraceID(ctx, r)
+	if id == "" {
+		return h.state.random() < rate
+	}
+
+	return sampleTraceHash(id) < rate
+}
+
+// sampleTraceHash returns the hash of a given trace id, between 0 and 1.
+func sampleTraceHash(id string) float64 {
+	digest := fnv.New64a()
+	_, _ = digest.Write([]byte(id))
+	// the 53 high bits fit the mantissa
+	return float64(digest.Sum64()>>11) / (1 << 53)
+}
+
+// drop counts a record of a given level that was sampled out, and schedules
+// the summary.
+func (s *sampleState) drop(level slog.Level) {
+	s.stats.sample(severityOf(level))
+
+	if s.interval <= 0 {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.dropped[level]++
+	// the first drop of an interval schedules its summary
+	if s.timer == nil {
+		s.timer = time.AfterFunc(s.interval, s.summarize)
+	}
+}
+
+// summarize writes the summary of the records sampled out since the last one.
+func (s *sampleState) summarize() {
+	s.mu.Lock()
+	dropped := s.dropped
+
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+
+	s.dropped = make(map[slog.Level]int)
+	s.mu.Unlock()
+
+	if len(dropped) == 0 {
+		return
+	}
+
+	levels := make([]slog.Level, 0, len(dropped))
+	for level := range dropped {
+		levels = append(levels, level)
+	}
+
+	slices.Sort(levels)
+
+	var (
+		counts = make([]any, 0, len(levels))
+		total  = 0
+	)
+
+	for _, level := range levels {
+		counts = append(counts, slog.Int(levelString(level), dropped[level]))
+		total += dropped[level]
+	}
+
+	r := slog.NewRecord(time.Now(), slog.LevelInfo, "slogr: records sampled out", 0)
+	r.AddAttrs(slog.Int("sampled_out", total), slog.Group("sampled_out_by_level", counts...))
+
+	_ = s.handler.Handle(context.Background(), r)
+}
+
+// sampleTraceID returns the trace id of a given record: the one of its span
+// attribute, or the one of the span of the context.
+func sampleTraceID(ctx context.Context, r slog.Record) string {
+	var sctx trace.SpanContext
+	// the span attribute wins over the context
+	r.Attrs(func(attr slog.Attr) bool {
+		value, ok := spanContextOfAttr(attr)
+		if ok {
+			sctx = value
+		}
+
+		return !ok
+	})
+
+	if !sctx.IsValid() {
+		sctx = trace.SpanContextFromContext(ctx)
+	}
+
+	if !sctx.IsValid() {
+		for _, lookup := range spanContextLookups {
+			if sctx = lookup(ctx); sctx.IsValid() {
+				break
+			}
+		}
+	}
+
+	if !sctx.IsValid() {
+		sctx = syntheticSpanContext(ctx)
+	}
+
+	if !sctx.IsValid() {
+		return ""
+	}
+
+	return sctx.TraceID().String()
+}
@@ -0,0 +1,132 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanContextOf returns a span context of a given trace number.
+func spanContextOf(number int) trace.SpanContext {
+	var id trace.TraceID
+	copy(id[:], fmt.Sprintf("%016d", number))
+
+	return trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: id,
+		SpanID:  trace.SpanID{1},
+	})
+}
+
+func TestSamplingHandlerRates(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	inner := NewHandler(buffer, &HandlerOptions{Level: slog.LevelDebug}).(*Handler)
+
+	handler := NewSamplingHandler(inner, &SamplingOptions{
+		Rates: map[slog.Level]float64{slog.LevelDebug: 0, slog.LevelWarn: 1},
+	})
+
+	logger := slog.New(handler)
+	logger.Debug("dropped")
+	logger.Info("dropped")
+	logger.Warn("kept")
+	logger.Error("kept")
+
+	if got := len(decodeEntries(t, buffer.Bytes())); got != 2 {
+		t.Fatalf("got %d entries, want 2", got)
+	}
+
+	stats := inner.Stats()
+	if got := stats.Sampled["DEBUG"] + stats.Sampled["INFO"]; got != 2 {
+		t.Fatalf("got %d records sampled out, want 2", got)
+	}
+}
+
+func TestSamplingHandlerBelowRates(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{Level: slog.LevelDebug})
+
+	handler := NewSamplingHandler(logger.Handler(), &SamplingOptions{
+		Rates: map[slog.Level]float64{slog.LevelInfo: 0},
+	})
+
+	slog.New(handler).Debug("kept")
+	single(t, entries())
+}
+
+func TestSamplingHandlerTrace(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	inner := NewHandler(buffer, &HandlerOptions{Level: slog.LevelDebug})
+
+	handler := NewSamplingHandler(inner, &SamplingOptions{
+		Rates: map[slog.Level]float64{slog.LevelDebug: 0.1, slog.LevelInfo: 0.5},
+	})
+
+	const count = 1000
+	for number := 0; number < count; number++ {
+		var (
+			ctx    = trace.ContextWithSpanContext(context.Background(), spanContextOf(number))
+			logger = slog.New(handler)
+			before = buffer.Len()
+		)
+
+		logger.DebugContext(ctx, "debug")
+		debug := buffer.Len() > before
+		// the decision is the same for every record of a level
+		for index := 0; index < 3; index++ {
+			before = buffer.Len()
+			logger.InfoContext(ctx, "info")
+
+			if info := buffer.Len() > before; debug && !info {
+				t.Fatalf("trace %d is kept at the debug rate, but not at the info rate", number)
+			}
+		}
+	}
+
+	var debug, info int
+	for _, entry := range decodeEntries(t, buffer.Bytes()) {
+		switch entry["message"] {
+		case "debug":
+			debug++
+		case "info":
+			info++
+		}
+	}
+
+	if info%3 != 0 {
+		t.Fatalf("got %d info records, want a multiple of 3", info)
+	}
+
+	if debug < count/20 || debug > count/5 {
+		t.Errorf("got %d debug records of %d, want about 10%%", debug, count)
+	}
+
+	if info/3 < count*2/5 || info/3 > count*3/5 {
+		t.Errorf("got %d info traces of %d, want about 50%%", info/3, count)
+	}
+}
+
+func TestSamplingHandlerSummary(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{Level: slog.LevelDebug})
+
+	handler := NewSamplingHandler(logger.Handler(), &SamplingOptions{
+		Rates:           map[slog.Level]float64{slog.LevelDebug: 0},
+		SummaryInterval: time.Hour,
+	})
+
+	sampled := slog.New(handler)
+	sampled.Debug("dropped")
+	sampled.Info("dropped")
+
+	if err := handler.(Flusher).Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	payload := payloadOf(single(t, entries()))
+	if got := payload["sampled_out"]; got != 2.0 {
+		t.Fatalf("got %v records sampled out, want 2", got)
+	}
+}
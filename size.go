@@ -0,0 +1,190 @@
+package slogr
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// TruncatedKey represents the key of the payload flag of the entries whose
+// payload was truncated to fit MaxEntrySize. A payload field with the same key
+// is kept, and the flag isn't added.
+const TruncatedKey = "truncated"
+
+// DefaultMaxEntrySize is the maximum size of an entry accepted by Cloud
+// Logging.
+const DefaultMaxEntrySize = 256 * 1024
+
+// truncatedMarker is appended to a truncated string value, with its original
+// length in bytes.
+const truncatedMarker = "...[truncated %d bytes]"
+
+// sizeOverhead is an upper bound of the encoded size of a payload value in
+// bytes, the tags and the length prefixes around it, without its content.
+const sizeOverhead = 24
+
+// fit truncates the payload of a given entry until its estimated size fits a
+// given limit. The largest string values are truncated first, then the
+// largest fields are dropped. The message, the severity, the trace and the
+// HTTP request are never changed.
+//
+// The size is computed once, and the estimate is reduced by the bytes removed
+// from the payload, so it stays an upper bound of the encoded size.
+func fit(e *Entry, limit int) {
+	payload, ok := e.Payload.(*loggingpb.LogEntry_JsonPayload)
+	if !ok || payload.JsonPayload == nil {
+		return
+	}
+
+	fields := payload.JsonPayload.Fields
+
+	e.Payload = nil
+	// the metadata is small, unlike the payload
+	metadata := proto.Size((*loggingpb.LogEntry)(e))
+	e.Payload = payload
+
+	// most entries are far below the limit, so the cheap estimate spares
+	// the size of their payload
+	if metadata+fieldsBound(fields) <= limit {
+		return
+	}
+
+	size := metadata + sizeOverhead + proto.Size(payload.JsonPayload)
+	if size <= limit {
+		return
+	}
+
+	// the flag doesn't replace a field with the same key
+	_, flagged := fields[TruncatedKey]
+	if !flagged {
+		fields[TruncatedKey] = structpb.NewBoolValue(true)
+		// the flag is part of the estimate
+		if size += len(TruncatedKey) + sizeOverhead; size <= limit {
+			return
+		}
+	}
+
+	// truncate the largest strings first
+	for _, value := range largestStrings(fields) {
+		excess := size - limit
+		if excess <= 0 {
+			return
+		}
+
+		text := value.GetStringValue()
+		marker := fmt.Sprintf(truncatedMarker, len(text))
+		keep := max(0, len(text)-excess-len(marker))
+		// don't split a rune
+		for keep > 0 && !utf8.RuneStart(text[keep]) {
+			keep--
+		}
+
+		if keep+len(marker) >= len(text) {
+			continue
+		}
+
+		value.Kind = &structpb.Value_StringValue{StringValue: text[:keep] + marker}
+		size -= len(text) - keep - len(marker)
+	}
+
+	if size <= limit {
+		return
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != FieldPayloadMessage && (key != TruncatedKey || flagged) {
+			keys = append(keys, key)
+		}
+	}
+
+	sizes := make(map[string]int, len(keys))
+	for _, key := range keys {
+		sizes[key] = len(key) + proto.Size(fields[key])
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return sizes[keys[i]] > sizes[keys[j]]
+	})
+
+	// drop the largest fields as a last resort
+	for _, key := range keys {
+		if size <= limit {
+			return
+		}
+
+		delete(fields, key)
+		size -= sizes[key]
+	}
+}
+
+// fieldsBound returns an upper bound of the encoded size of the given fields,
+// without encoding them.
+func fieldsBound(fields map[string]*structpb.Value) int {
+	size := sizeOverhead
+
+	for key, value := range fields {
+		size += len(key) + valueBound(value)
+	}
+
+	return size
+}
+
+// valueBound returns an upper bound of the encoded size of a given value.
+func valueBound(value *structpb.Value) int {
+	switch kind := value.GetKind().(type) {
+	case *structpb.Value_StringValue:
+		return sizeOverhead + len(kind.StringValue)
+	case *structpb.Value_StructValue:
+		return sizeOverhead + fieldsBound(kind.StructValue.GetFields())
+	case *structpb.Value_ListValue:
+		size := sizeOverhead
+		for _, item := range kind.ListValue.GetValues() {
+			size += valueBound(item)
+		}
+
+		return size
+	default:
+		return sizeOverhead
+	}
+}
+
+// largestStrings returns the string values of the given fields and their
+// nested values, except the message, from the longest to the shortest.
+func largestStrings(fields map[string]*structpb.Value) []*structpb.Value {
+	var (
+		collection []*structpb.Value
+		walk       func(value *structpb.Value)
+	)
+
+	walk = func(value *structpb.Value) {
+		switch kind := value.GetKind().(type) {
+		case *structpb.Value_StringValue:
+			collection = append(collection, value)
+		case *structpb.Value_StructValue:
+			for _, item := range kind.StructValue.GetFields() {
+				walk(item)
+			}
+		case *structpb.Value_ListValue:
+			for _, item := range kind.ListValue.GetValues() {
+				walk(item)
+			}
+		}
+	}
+
+	for key, value := range fields {
+		if key != FieldPayloadMessage {
+			walk(value)
+		}
+	}
+
+	sort.SliceStable(collection, func(i, j int) bool {
+		return len(collection[i].GetStringValue()) > len(collection[j].GetStringValue())
+	})
+
+	return collection
+}
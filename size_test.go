@@ -0,0 +1,135 @@
+package slogr
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// jsonEntry returns an entry of a given JSON payload.
+func jsonEntry(t testing.TB, fields map[string]any) *Entry {
+	t.Helper()
+
+	payload, err := structpb.NewStruct(fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &Entry{
+		LogName:  "projects/my-project/logs/books",
+		InsertId: "insert-42",
+		Trace:    "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736",
+		Payload:  &loggingpb.LogEntry_JsonPayload{JsonPayload: payload},
+	}
+}
+
+func TestFit(t *testing.T) {
+	const limit = 1024
+
+	for _, tc := range []struct {
+		name   string
+		fields map[string]any
+		// want reports the payload fields after fit, nil for the dropped ones
+		want map[string]any
+	}{
+		{
+			name:   "small",
+			fields: map[string]any{FieldPayloadMessage: "small", "count": 1},
+			want:   map[string]any{FieldPayloadMessage: "small", "count": float64(1), TruncatedKey: nil},
+		},
+		{
+			name:   "string",
+			fields: map[string]any{FieldPayloadMessage: "large", "body": strings.Repeat("a", 2048)},
+			want:   map[string]any{FieldPayloadMessage: "large", TruncatedKey: true},
+		},
+		{
+			name: "fields",
+			fields: map[string]any{
+				FieldPayloadMessage: strings.Repeat("m", 512),
+				"list":              []any{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20, 21, 22, 23, 24, 25, 26, 27, 28, 29, 30, 31, 32, 33, 34, 35, 36, 37, 38, 39, 40, 41, 42, 43, 44, 45, 46, 47, 48, 49, 50, 51, 52, 53, 54, 55, 56, 57, 58, 59, 60},
+			},
+			// the message is never truncated
+			want: map[string]any{FieldPayloadMessage: strings.Repeat("m", 512), "list": nil, TruncatedKey: true},
+		},
+		{
+			name:   "collision",
+			fields: map[string]any{FieldPayloadMessage: "large", TruncatedKey: "user", "body": strings.Repeat("a", 2048)},
+			// the field of the record isn't replaced by the flag
+			want: map[string]any{FieldPayloadMessage: "large", TruncatedKey: "user"},
+		},
+	} {
+		entry := jsonEntry(t, tc.fields)
+		fit(entry, limit)
+
+		if size := proto.Size((*loggingpb.LogEntry)(entry)); size > limit {
+			t.Errorf("%s: got size %d, want at most %d", tc.name, size, limit)
+		}
+
+		fields := entry.Payload.(*loggingpb.LogEntry_JsonPayload).JsonPayload.AsMap()
+		for key, want := range tc.want {
+			got, ok := fields[key]
+			if want == nil && ok {
+				t.Errorf("%s: got %s %v, want none", tc.name, key, got)
+			}
+
+			if want != nil && got != want {
+				t.Errorf("%s: got %s %v, want %v", tc.name, key, got, want)
+			}
+		}
+	}
+}
+
+func TestFitTruncatedString(t *testing.T) {
+	entry := jsonEntry(t, map[string]any{"body": strings.Repeat("€", 1000)})
+	fit(entry, 1024)
+
+	body := entry.Payload.(*loggingpb.LogEntry_JsonPayload).JsonPayload.Fields["body"].GetStringValue()
+	// the value keeps its start, a rune boundary and the original length
+	if !strings.HasPrefix(body, "€€€") || !strings.HasSuffix(body, "...[truncated 3000 bytes]") {
+		t.Errorf("got body %q", body)
+	}
+
+	if !utf8.ValidString(body) {
+		t.Errorf("got body %q, want whole runes", body)
+	}
+}
+
+func TestFitBound(t *testing.T) {
+	for _, fields := range []map[string]any{
+		{},
+		{"count": 1, "ok": true, "none": nil},
+		{"text": strings.Repeat("a", 300), "nested": map[string]any{"list": []any{"a", 1, map[string]any{"b": "c"}}}},
+		{strings.Repeat("k", 200): strings.Repeat("v", 70000)},
+	} {
+		entry := jsonEntry(t, fields)
+		payload := entry.Payload.(*loggingpb.LogEntry_JsonPayload)
+
+		entry.Payload = nil
+		metadata := proto.Size((*loggingpb.LogEntry)(entry))
+		entry.Payload = payload
+
+		// the estimate is never below the encoded size
+		if bound, size := metadata+fieldsBound(payload.JsonPayload.Fields), proto.Size((*loggingpb.LogEntry)(entry)); bound < size {
+			t.Errorf("got bound %d of size %d", bound, size)
+		}
+	}
+}
+
+func BenchmarkFit(b *testing.B) {
+	entry := jsonEntry(b, map[string]any{
+		FieldPayloadMessage: "served",
+		"method":            "GET",
+		"status":            200,
+		"tags":              []any{"alpha", "beta"},
+	})
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		fit(entry, DefaultMaxEntrySize)
+	}
+}
@@ -0,0 +1,190 @@
+// Package slogrtest provides helpers for testing the code that logs with
+// log/slog.
+package slogrtest
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// builtinHandler is the handler of the built-in default logger of log/slog.
+var builtinHandler = slog.Default().Handler()
+
+// AssertMaxLevel makes the test fail when an entry above a given level is
+// logged with the default logger. It returns a function that restores the
+// previous default logger and reports the violations. The function is also
+// registered with t.Cleanup, so the default logger is restored even when the
+// test panics.
+func AssertMaxLevel(t testing.TB, level slog.Level) func() {
+	t.Helper()
+
+	var (
+		previous = slog.Default()
+		output   = log.Writer()
+		flags    = log.Flags()
+		inner    = previous
+	)
+
+	// the built-in default handler writes to the log package, which writes
+	// to the default logger once it's replaced
+	if previous.Handler() == builtinHandler {
+		inner = slog.New(slog.NewTextHandler(output, nil))
+	}
+
+	logger, report := AssertLoggerMaxLevel(t, inner, level)
+	// install the logger
+	slog.SetDefault(logger)
+
+	var once sync.Once
+
+	closer := func() {
+		t.Helper()
+
+		once.Do(func() {
+			slog.SetDefault(previous)
+			// restoring the default handler doesn't restore the log package
+			log.SetOutput(output)
+			log.SetFlags(flags)
+		})
+
+		report()
+	}
+
+	t.Cleanup(closer)
+	// done!
+	return closer
+}
+
+// AssertLoggerMaxLevel returns a logger that writes to a given logger, and
+// records the entries above a given level. The returned function makes the
+// test fail when there are violations. It's also registered with t.Cleanup.
+func AssertLoggerMaxLevel(t testing.TB, logger *slog.Logger, level slog.Level) (*slog.Logger, func()) {
+	t.Helper()
+
+	handler := &maxLevelHandler{
+		inner: logger.Handler(),
+		max:   level,
+		state: &violations{},
+	}
+
+	var once sync.Once
+
+	report := func() {
+		t.Helper()
+
+		var collection []string
+		// the violations are reported once
+		once.Do(func() {
+			collection = handler.state.collection()
+		})
+
+		for _, violation := range collection {
+			t.Errorf("slogrtest: %s", violation)
+		}
+	}
+
+	t.Cleanup(report)
+	// done!
+	return slog.New(handler), report
+}
+
+// violations represents the violations recorded by a handler and its clones.
+type violations struct {
+	mu    sync.Mutex
+	items []string
+}
+
+func (v *violations) add(item string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.items = append(v.items, item)
+}
+
+func (v *violations) collection() []string {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return append([]string{}, v.items...)
+}
+
+// maxLevelHandler implements a [slog.Handler] that records the entries above
+// a maximum level before delegating them to another handler.
+type maxLevelHandler struct {
+	inner slog.Handler
+	max   slog.Level
+	state *violations
+	attrs []string
+}
+
+// Enabled implements slog.Handler
+func (h *maxLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level > h.max || h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *maxLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level > h.max {
+		h.state.add(h.violation(r))
+	}
+
+	if !h.inner.Enabled(ctx, r.Level) {
+		return nil
+	}
+
+	return h.inner.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler
+func (h *maxLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.clone()
+	c.inner = h.inner.WithAttrs(attrs)
+
+	for _, attr := range attrs {
+		c.attrs = append(c.attrs, attr.String())
+	}
+
+	return c
+}
+
+// WithGroup implements slog.Handler
+func (h *maxLevelHandler) WithGroup(name string) slog.Handler {
+	c := h.clone()
+	c.inner = h.inner.WithGroup(name)
+	return c
+}
+
+func (h *maxLevelHandler) clone() *maxLevelHandler {
+	return &maxLevelHandler{
+		inner: h.inner,
+		max:   h.max,
+		state: h.state,
+		attrs: append([]string{}, h.attrs...),
+	}
+}
+
+// violation describes a given record with its call site.
+func (h *maxLevelHandler) violation(r slog.Record) string {
+	attrs := append([]string{}, h.attrs...)
+	// collect the attributes
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr.String())
+		return true
+	})
+
+	location := "unknown location"
+	// the call site is unknown without a pc
+	if r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		frame, _ := frames.Next()
+		location = fmt.Sprintf("%s:%d", frame.File, frame.Line)
+	}
+
+	return fmt.Sprintf("%s: %s entry above %s: %q [%s]", location, r.Level, h.max, r.Message, strings.Join(attrs, " "))
+}
@@ -0,0 +1,83 @@
+package slogrtest
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+// recorder is a testing.TB that records the errors.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+// Helper implements testing.TB
+func (r *recorder) Helper() {}
+
+// Errorf implements testing.TB
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// Cleanup implements testing.TB
+func (r *recorder) Cleanup(_ func()) {}
+
+func TestAssertMaxLevel(t *testing.T) {
+	if slog.Default().Handler() != builtinHandler {
+		t.Skip("the default logger is replaced")
+	}
+
+	var (
+		output   = &bytes.Buffer{}
+		previous = log.Writer()
+	)
+
+	log.SetOutput(output)
+	defer log.SetOutput(previous)
+
+	state := &recorder{TB: t}
+
+	restore := AssertMaxLevel(state, slog.LevelInfo)
+	slog.Info("quiet")
+	slog.Warn("loud", "user", "alice")
+	restore()
+
+	if len(state.errors) != 1 || !strings.Contains(state.errors[0], `"loud" [user=alice]`) {
+		t.Fatalf("got errors %q, want the loud entry", state.errors)
+	}
+
+	// the built-in handler is replaced by a text handler of the log output
+	if got := strings.Count(output.String(), "\n"); got != 2 {
+		t.Fatalf("got %q, want both entries", output.String())
+	}
+
+	if slog.Default().Handler() != builtinHandler {
+		t.Fatal("the default logger is not restored")
+	}
+}
+
+func TestAssertLoggerMaxLevel(t *testing.T) {
+	var (
+		output = &bytes.Buffer{}
+		state  = &recorder{TB: t}
+	)
+
+	logger, report := AssertLoggerMaxLevel(state, slog.New(slog.NewTextHandler(output, nil)), slog.LevelWarn)
+	logger.With("user", "alice").WithGroup("call").Error("failed")
+	logger.Warn("allowed")
+	report()
+	// the violations are reported once
+	report()
+
+	if len(state.errors) != 1 || !strings.Contains(state.errors[0], "ERROR entry above WARN") {
+		t.Fatalf("got errors %q, want the error entry", state.errors)
+	}
+
+	if got := strings.Count(output.String(), "\n"); got != 2 {
+		t.Fatalf("got %q, want both entries", output.String())
+	}
+}
@@ -0,0 +1,74 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// spanEventAttrs is the maximum number of attributes copied to a span event.
+const spanEventAttrs = 16
+
+// annotateSpan adds a given record as an event to the recording span of a
+// given context. A failure of the span never fails the log write.
+func (h *Handler) annotateSpan(ctx context.Context, r slog.Record) {
+	span := trace.SpanFromContext(ctx)
+	// skip the spans that are not recording
+	if !span.IsRecording() {
+		return
+	}
+
+	defer func() {
+		_ = recover()
+	}()
+
+	attrs := make([]attribute.KeyValue, 0, spanEventAttrs)
+
+	add := func(attr slog.Attr) bool {
+		if len(attrs) >= spanEventAttrs {
+			return false
+		}
+
+		switch {
+		case attr.Key == "", isReserved(attr):
+			return true
+		case attr.Key == ErrorKey:
+			attrs = append(attrs, attribute.String("exception.message", errorString(attr.Value)))
+			return true
+		default:
+			attrs = append(attrs, spanAttribute(attr))
+			return true
+		}
+	}
+
+	for _, attr := range h.attr {
+		if !add(attr) {
+			break
+		}
+	}
+
+	r.Attrs(add)
+	// add the event
+	span.AddEvent(r.Message, trace.WithAttributes(attrs...), trace.WithTimestamp(r.Time))
+}
+
+func spanAttribute(attr slog.Attr) attribute.KeyValue {
+	value := attr.Value.Resolve()
+
+	switch value.Kind() {
+	case slog.KindString:
+		return attribute.String(attr.Key, value.String())
+	case slog.KindInt64:
+		return attribute.Int64(attr.Key, value.Int64())
+	case slog.KindUint64:
+		return attribute.Int64(attr.Key, int64(value.Uint64()))
+	case slog.KindFloat64:
+		return attribute.Float64(attr.Key, value.Float64())
+	case slog.KindBool:
+		return attribute.Bool(attr.Key, value.Bool())
+	default:
+		return attribute.String(attr.Key, value.String())
+	}
+}
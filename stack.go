@@ -0,0 +1,169 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+const (
+	// StackTraceKey is the payload key of the stack trace of an error.
+	StackTraceKey = "stack_trace"
+	// ErrorTypeKey is the payload key of the type of an error.
+	ErrorTypeKey = "error_type"
+)
+
+const (
+	// DefaultStackDepth is the default maximum number of frames of the stack
+	// traces.
+	DefaultStackDepth = 64
+	// stackLimit is the maximum number of frames of a captured stack trace.
+	stackLimit = 256
+)
+
+// ErrorWithStack returns the error attributes of a given error with a stack
+// trace: the error string, the %T of the error as ErrorTypeKey, and the stack
+// as StackTraceKey, in the style of runtime.Stack after the error string. The
+// stack is the one of the error when it has a StackTrace method, such as the
+// errors of github.com/pkg/errors, or the stack of the caller otherwise. The
+// handler keeps the frames up to its HandlerOptions.StackDepth.
+func ErrorWithStack(err error) slog.Attr {
+	pcs := errorStack(err)
+	if pcs == nil {
+		pcs = make([]uintptr, stackLimit)
+		// skip runtime.Callers and ErrorWithStack
+		pcs = pcs[:runtime.Callers(2, pcs)]
+	}
+
+	return slog.Attr{
+		Value: slog.GroupValue(
+			Error(err),
+			slog.String(ErrorTypeKey, fmt.Sprintf("%T", err)),
+			slog.Any(StackTraceKey, stackTrace{message: err.Error(), pcs: pcs}),
+		),
+	}
+}
+
+// stackTrace represents the stack trace of an error. The handler formats it
+// with its stack depth.
+type stackTrace struct {
+	message string
+	pcs     []uintptr
+}
+
+// format returns the error string followed by the frames up to a given depth
+// in the style of runtime.Stack.
+func (x stackTrace) format(depth int) string {
+	return x.message + "\n\n" + formatStack(x.pcs[:min(len(x.pcs), depth)])
+}
+
+// String implements fmt.Stringer, for the other handlers.
+func (x stackTrace) String() string {
+	return x.format(DefaultStackDepth)
+}
+
+// MarshalJSON implements json.Marshaler, for the other handlers.
+func (x stackTrace) MarshalJSON() ([]byte, error) {
+	return json.Marshal(x.String())
+}
+
+// errorStack returns the program counters of the innermost error of a chain
+// with a StackTrace method returning a slice of program counters, such as
+// the errors.StackTrace of github.com/pkg/errors. It returns nil when there's
+// no such error.
+func errorStack(err error) []uintptr {
+	var pcs []uintptr
+
+	for ; err != nil; err = errors.Unwrap(err) {
+		value := reflect.ValueOf(err)
+		if value.Kind() == reflect.Pointer && value.IsNil() {
+			break
+		}
+
+		method := value.MethodByName("StackTrace")
+		if !method.IsValid() || method.Type().NumIn() != 0 || method.Type().NumOut() != 1 {
+			continue
+		}
+
+		if kind := method.Type().Out(0); kind.Kind() != reflect.Slice || kind.Elem().Kind() != reflect.Uintptr {
+			continue
+		}
+
+		frames := method.Call(nil)[0]
+		pcs = make([]uintptr, 0, min(frames.Len(), stackLimit))
+
+		for index := 0; index < cap(pcs); index++ {
+			pcs = append(pcs, uintptr(frames.Index(index).Uint()))
+		}
+	}
+
+	return pcs
+}
+
+// callers returns the program counters of the current goroutine from the
+// frame of a given program counter, such as the one of a record. It falls
+// back to the frame of the program counter alone when the goroutine doesn't
+// run it, e.g. in an async handler.
+func callers(pc uintptr, depth int) []uintptr {
+	pcs := make([]uintptr, depth+32)
+	// skip runtime.Callers and callers
+	pcs = pcs[:runtime.Callers(2, pcs)]
+
+	for index, item := range pcs {
+		if item == pc {
+			pcs = pcs[index:]
+			return pcs[:min(len(pcs), depth)]
+		}
+	}
+
+	if pc == 0 {
+		return nil
+	}
+
+	return []uintptr{pc}
+}
+
+// formatStack formats the frames of given program counters in the style of
+// runtime.Stack, which Error Reporting parses.
+func formatStack(pcs []uintptr) string {
+	buffer := &bytes.Buffer{}
+	buffer.WriteString(goroutineHeader())
+
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		if frame.Function != "" {
+			fmt.Fprintf(buffer, "\n%s(...)\n\t%s:%d +0x%x", frame.Function, frame.File, frame.Line, frame.PC-frame.Entry)
+		}
+
+		if !more {
+			break
+		}
+	}
+
+	return buffer.String()
+}
+
+// goroutineHeader returns the header of the stack of the current goroutine,
+// such as "goroutine 1 [running]:".
+func goroutineHeader() string {
+	data := make([]byte, 64)
+	data = data[:runtime.Stack(data, false)]
+
+	if index := bytes.IndexByte(data, '\n'); index >= 0 {
+		data = data[:index]
+	}
+
+	header := string(data)
+	// the header is cut by the size of the buffer
+	if !strings.HasSuffix(header, ":") {
+		header = "goroutine 1 [running]:"
+	}
+
+	return header
+}
@@ -0,0 +1,104 @@
+package slogr
+
+import (
+	"errors"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+type stackFrame uintptr
+
+type stackFrames []stackFrame
+
+// stackError is an error with a stack trace, as in github.com/pkg/errors.
+type stackError struct {
+	pcs []uintptr
+}
+
+func newStackError() error {
+	pcs := make([]uintptr, 32)
+	return &stackError{pcs: pcs[:runtime.Callers(1, pcs)]}
+}
+
+func (x *stackError) Error() string {
+	return "stacked"
+}
+
+func (x *stackError) StackTrace() stackFrames {
+	frames := make(stackFrames, len(x.pcs))
+	for index, pc := range x.pcs {
+		frames[index] = stackFrame(pc)
+	}
+
+	return frames
+}
+
+func TestErrorWithStack(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	logger.Error("failed", ErrorWithStack(errors.New("boom")))
+
+	payload := payloadOf(single(t, entries()))
+	if payload[ErrorKey] != "boom" {
+		t.Errorf("error = %v, want boom", payload[ErrorKey])
+	}
+
+	if payload[ErrorTypeKey] != "*errors.errorString" {
+		t.Errorf("error_type = %v, want *errors.errorString", payload[ErrorTypeKey])
+	}
+
+	stack, _ := payload[StackTraceKey].(string)
+	if !strings.HasPrefix(stack, "boom\n\ngoroutine ") {
+		t.Errorf("stack_trace doesn't start with the error and the goroutine: %q", stack)
+	}
+
+	if !strings.Contains(stack, "slogr.TestErrorWithStack(...)") {
+		t.Errorf("stack_trace doesn't contain the caller: %q", stack)
+	}
+}
+
+func TestErrorWithStackOfError(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	logger.Error("failed", ErrorWithStack(newStackError()))
+
+	stack, _ := payloadOf(single(t, entries()))[StackTraceKey].(string)
+	if !strings.Contains(stack, "slogr.newStackError(...)") {
+		t.Errorf("stack_trace isn't the one of the error: %q", stack)
+	}
+}
+
+func TestErrorWithStackDepth(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{StackDepth: 1})
+
+	logger.Error("failed", ErrorWithStack(errors.New("boom")))
+
+	stack, _ := payloadOf(single(t, entries()))[StackTraceKey].(string)
+	if count := strings.Count(stack, "\n\t"); count != 1 {
+		t.Errorf("got %d frames, want 1: %q", count, stack)
+	}
+}
+
+func TestErrorKeepsItsShape(t *testing.T) {
+	attr := Error(newStackError())
+	if attr.Key != ErrorKey || attr.Value.String() != "stacked" {
+		t.Errorf("Error = %v, want the error string", attr)
+	}
+}
+
+func TestErrorWithStackReported(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ErrorReporting: true})
+
+	logger.Error("failed", ErrorWithStack(newStackError()))
+
+	payload := payloadOf(single(t, entries()))
+	if payload["@type"] != ReportedErrorEventType {
+		t.Fatalf("@type = %v, want %s", payload["@type"], ReportedErrorEventType)
+	}
+
+	stack, _ := payload[StackTraceKey].(string)
+	if !strings.HasPrefix(stack, "failed: stacked\n\ngoroutine ") || !strings.Contains(stack, "slogr.newStackError(...)") {
+		t.Errorf("stack_trace isn't the one of the error: %q", stack)
+	}
+}
@@ -0,0 +1,178 @@
+package slogr
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+)
+
+// Stats represents a snapshot of the stats of a handler and its clones.
+type Stats struct {
+	// Level is the name of the current minimum level.
+	Level string `json:"level"`
+	// Adaptive reports whether the adaptive level is active.
+	Adaptive bool `json:"adaptive"`
+	// Emitted is the number of entries written per severity.
+	Emitted map[string]int64 `json:"emitted"`
+	// Dropped is the number of entries dropped by the level of their log name.
+	Dropped int64 `json:"dropped"`
+	// Errors is the number of entries that failed to be written.
+	Errors int64 `json:"errors"`
+	// Overflowed is the number of records dropped by a full queue, the one of
+	// an async handler or of an APIWriter.
+	Overflowed int64 `json:"overflowed"`
+	// Sampled is the number of records sampled out per severity.
+	Sampled map[string]int64 `json:"sampled"`
+	// Reconnects is the number of reconnections of a NetWriter.
+	Reconnects int64 `json:"reconnects"`
+	// DroppedBytes is the number of bytes dropped by a NetWriter.
+	DroppedBytes int64 `json:"dropped_bytes"`
+}
+
+// stats represents the lock-free counters of a handler and its clones.
+type stats struct {
+	// emitted is indexed by the severity divided by 100
+	emitted    [9]atomic.Int64
+	dropped    atomic.Int64
+	errors     atomic.Int64
+	overflowed atomic.Int64
+	// sampled is indexed like emitted
+	sampled [9]atomic.Int64
+}
+
+// statsHolder is implemented by the handlers that count the records, either
+// by their own stats or by the stats of the handler they wrap.
+type statsHolder interface {
+	handlerStats() *stats
+}
+
+// statsOf returns the stats of a given handler, or nil when it has none.
+func statsOf(h slog.Handler) *stats {
+	if holder, ok := h.(statsHolder); ok {
+		return holder.handlerStats()
+	}
+
+	return nil
+}
+
+func (s *stats) count(severity ltype.LogSeverity, err error) {
+	if s == nil {
+		return
+	}
+
+	if err != nil {
+		s.errors.Add(1)
+		return
+	}
+
+	if index := int(severity) / 100; index >= 0 && index < len(s.emitted) {
+		s.emitted[index].Add(1)
+	}
+}
+
+func (s *stats) drop() {
+	if s != nil {
+		s.dropped.Add(1)
+	}
+}
+
+func (s *stats) overflow() {
+	if s != nil {
+		s.overflowed.Add(1)
+	}
+}
+
+func (s *stats) sample(severity ltype.LogSeverity) {
+	if s == nil {
+		return
+	}
+
+	if index := int(severity) / 100; index >= 0 && index < len(s.sampled) {
+		s.sampled[index].Add(1)
+	}
+}
+
+// handlerStats implements statsHolder
+func (h *Handler) handlerStats() *stats {
+	return h.stats
+}
+
+// Stats returns a snapshot of the stats of the handler and its clones. The
+// snapshot of a nil handler is empty.
+func (h *Handler) Stats() Stats {
+	snapshot := Stats{
+		Emitted: make(map[string]int64),
+		Sampled: make(map[string]int64),
+	}
+
+	if h == nil {
+		return snapshot
+	}
+
+	if h.leveler != nil {
+		snapshot.Level = levelString(h.leveler.Level())
+	}
+
+	if h.adaptive != nil {
+		snapshot.Adaptive = h.adaptive.active()
+	}
+
+	if w, ok := h.writer.(*NetWriter); ok {
+		snapshot.Reconnects = w.Reconnects()
+		snapshot.DroppedBytes = w.DroppedBytes()
+	}
+
+	if h.stats == nil {
+		return snapshot
+	}
+
+	for index := range h.stats.emitted {
+		if count := h.stats.emitted[index].Load(); count > 0 {
+			snapshot.Emitted[ltype.LogSeverity(index*100).String()] = count
+		}
+	}
+
+	for index := range h.stats.sampled {
+		if count := h.stats.sampled[index].Load(); count > 0 {
+			snapshot.Sampled[ltype.LogSeverity(index*100).String()] = count
+		}
+	}
+
+	snapshot.Dropped = h.stats.dropped.Load()
+	snapshot.Errors = h.stats.errors.Load()
+	snapshot.Overflowed = h.stats.overflowed.Load()
+	return snapshot
+}
+
+var (
+	published     atomic.Pointer[Handler]
+	publishedOnce sync.Once
+)
+
+// PublishStats publishes the stats of a given handler via expvar under
+// "slogr". A later call replaces the published handler, and a nil handler
+// publishes empty stats.
+func PublishStats(h *Handler) {
+	published.Store(h)
+	// expvar panics on the second publish of a name
+	publishedOnce.Do(func() {
+		expvar.Publish("slogr", expvar.Func(func() any {
+			return published.Load().Stats()
+		}))
+	})
+}
+
+// StatsHandler returns an [http.Handler] that renders the stats of a given
+// handler as JSON.
+func StatsHandler(h *Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		// write the snapshot
+		_ = json.NewEncoder(w).Encode(h.Stats())
+	})
+}
@@ -0,0 +1,104 @@
+package slogr
+
+import (
+	"encoding/json"
+	"errors"
+	"expvar"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+// Write implements io.Writer
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("failed")
+}
+
+func TestHandlerStats(t *testing.T) {
+	handler := NewHandler(io.Discard, &HandlerOptions{
+		Level:       slog.LevelInfo,
+		LevelByName: map[string]slog.Leveler{"noisy": slog.LevelError},
+	}).(*Handler)
+
+	logger := slog.New(handler)
+	logger.Info("emitted")
+	logger.Info("emitted")
+	logger.Warn("emitted")
+	logger.Info("dropped", Name("noisy"))
+
+	stats := handler.Stats()
+	if stats.Level != "INFO" {
+		t.Errorf("got level %q, want INFO", stats.Level)
+	}
+
+	if stats.Emitted["INFO"] != 2 || stats.Emitted["WARNING"] != 1 {
+		t.Errorf("got emitted %v, want 2 INFO and 1 WARNING", stats.Emitted)
+	}
+
+	if stats.Dropped != 1 {
+		t.Errorf("got %d dropped, want 1", stats.Dropped)
+	}
+}
+
+func TestHandlerStatsErrors(t *testing.T) {
+	handler := NewHandler(failingWriter{}, &HandlerOptions{Level: slog.LevelInfo}).(*Handler)
+	slog.New(handler).Info("failed")
+
+	if got := handler.Stats(); got.Errors != 1 || len(got.Emitted) != 0 {
+		t.Fatalf("got %+v, want 1 error and no emitted entry", got)
+	}
+}
+
+func TestNilHandlerStats(t *testing.T) {
+	var handler *Handler
+
+	if got := handler.Stats(); got.Level != "" || got.Emitted == nil {
+		t.Fatalf("got %+v, want empty stats", got)
+	}
+}
+
+func TestPublishStats(t *testing.T) {
+	// a nil handler publishes empty stats
+	PublishStats(nil)
+
+	if value := expvar.Get("slogr"); value == nil || value.String() == "" {
+		t.Fatal("got no published stats")
+	}
+
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelWarn}).(*Handler)
+	PublishStats(handler)
+
+	var stats Stats
+	if err := json.Unmarshal([]byte(expvar.Get("slogr").String()), &stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Level != "WARN" {
+		t.Fatalf("got level %q, want WARN", stats.Level)
+	}
+}
+
+func TestStatsHandler(t *testing.T) {
+	handler := NewHandler(io.Discard, &HandlerOptions{Level: slog.LevelInfo}).(*Handler)
+	slog.New(handler).Info("emitted")
+
+	recorder := httptest.NewRecorder()
+	StatsHandler(handler).ServeHTTP(recorder, httptest.NewRequest("GET", "/debug/slogr", nil))
+
+	if got := recorder.Header().Get("Content-Type"); got != "application/json" {
+		t.Errorf("got content type %q, want application/json", got)
+	}
+
+	var stats Stats
+	if err := json.NewDecoder(recorder.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+
+	if stats.Emitted["INFO"] != 1 {
+		t.Fatalf("got emitted %v, want 1 INFO", stats.Emitted)
+	}
+}
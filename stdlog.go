@@ -0,0 +1,137 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StdLogName is the log name of the entries of the loggers of NewStdLogger.
+const StdLogName = "stdlog"
+
+// stdPendingLimit is the maximum size of the line kept by a StdWriter until
+// its newline.
+const stdPendingLimit = 64 << 10
+
+// LevelMatcher infers the level of the lines that start with its prefix,
+// ignoring the case and the leading spaces.
+type LevelMatcher struct {
+	Prefix string
+	Level  slog.Level
+}
+
+// DefaultLevelMatchers are the level matchers of NewStdLogger. The first
+// matching prefix wins.
+var DefaultLevelMatchers = []LevelMatcher{
+	// the handshake errors are the failures of the clients
+	{Prefix: "http: TLS handshake error", Level: slog.LevelWarn},
+	{Prefix: "http: panic serving", Level: slog.LevelError},
+	{Prefix: "[error]", Level: slog.LevelError},
+	{Prefix: "[warn]", Level: slog.LevelWarn},
+	{Prefix: "[warning]", Level: slog.LevelWarn},
+	{Prefix: "[info]", Level: slog.LevelInfo},
+	{Prefix: "[debug]", Level: slog.LevelDebug},
+	{Prefix: "ERROR", Level: slog.LevelError},
+	{Prefix: "FATAL", Level: LevelCritical},
+	{Prefix: "PANIC", Level: LevelAlert},
+	{Prefix: "WARN", Level: slog.LevelWarn},
+	{Prefix: "NOTICE", Level: LevelNotice},
+	{Prefix: "INFO", Level: slog.LevelInfo},
+	{Prefix: "DEBUG", Level: slog.LevelDebug},
+}
+
+// NewStdLogger creates a [log.Logger] that writes its lines to a given
+// logger, for the libraries that only take a *log.Logger, such as the
+// ErrorLog of an http.Server. The level of a line is inferred with the
+// DefaultLevelMatchers, and is a given level otherwise.
+func NewStdLogger(logger *slog.Logger, level slog.Level) *log.Logger {
+	return log.New(NewStdWriter(logger, level, DefaultLevelMatchers...), "", 0)
+}
+
+// StdWriter implements an [io.Writer] that writes the lines to a logger,
+// with the StdLogName log name. The lines of a single write are a single
+// entry, such as a stack trace, and the level of the entry is inferred from
+// its first line. A line that isn't terminated by a newline is kept until the
+// next write, unless it's longer than 64KiB, in which case it's written as is.
+// It's safe for concurrent use.
+type StdWriter struct {
+	logger   *slog.Logger
+	level    slog.Level
+	matchers []LevelMatcher
+
+	mu      sync.Mutex
+	pending []byte
+}
+
+// NewStdWriter creates a StdWriter that writes to a given logger, and infers
+// the levels with given matchers. The lines without a matching prefix are at
+// a given level.
+func NewStdWriter(logger *slog.Logger, level slog.Level, matchers ...LevelMatcher) *StdWriter {
+	return &StdWriter{
+		logger:   logger,
+		level:    level,
+		matchers: matchers,
+	}
+}
+
+// Write implements io.Writer
+func (w *StdWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data := append(w.pending, p...)
+
+	index := bytes.LastIndexByte(data, '\n')
+	if index >= 0 {
+		if message := strings.TrimRight(string(data[:index]), "\r\n"); message != "" {
+			w.log(message)
+		}
+
+		// the rest is copied, so the buffer of the lines is released
+		data = append([]byte(nil), data[index+1:]...)
+	}
+
+	w.pending = data
+	// the line would grow without bound
+	if len(data) > stdPendingLimit {
+		w.pending = nil
+		w.log(string(data))
+	}
+
+	return len(p), nil
+}
+
+// log writes a given message at its inferred level.
+func (w *StdWriter) log(message string) {
+	var (
+		ctx     = context.Background()
+		level   = w.infer(message)
+		handler = w.logger.Handler()
+	)
+
+	if !handler.Enabled(ctx, level) {
+		return
+	}
+
+	r := slog.NewRecord(time.Now(), level, message, 0)
+	r.AddAttrs(Name(StdLogName))
+	// the record is best effort
+	_ = handler.Handle(ctx, r)
+}
+
+// infer returns the level of a given message.
+func (w *StdWriter) infer(message string) slog.Level {
+	line := strings.TrimLeft(message, " \t")
+
+	for _, matcher := range w.matchers {
+		if len(line) >= len(matcher.Prefix) && strings.EqualFold(line[:len(matcher.Prefix)], matcher.Prefix) {
+			return matcher.Level
+		}
+	}
+
+	return w.level
+}
@@ -0,0 +1,120 @@
+package slogr
+
+import (
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestStdWriter(t *testing.T) {
+	type line struct {
+		message  string
+		severity string
+	}
+
+	for _, test := range []struct {
+		name   string
+		writes []string
+		want   []line
+	}{
+		{
+			name:   "default level",
+			writes: []string{"listening\n"},
+			want:   []line{{"listening", "NOTICE"}},
+		},
+		{
+			name: "prefixes",
+			writes: []string{
+				"http: TLS handshake error from 192.0.2.1:1234: EOF\n",
+				"  [ERROR] failed\n",
+				"warn: not a prefix of the matchers\n",
+				"WARNING: disk\n",
+				"fatal: exiting\n",
+				"debug output\n",
+			},
+			want: []line{
+				{"http: TLS handshake error from 192.0.2.1:1234: EOF", "WARNING"},
+				{"  [ERROR] failed", "ERROR"},
+				{"warn: not a prefix of the matchers", "WARNING"},
+				{"WARNING: disk", "WARNING"},
+				{"fatal: exiting", "CRITICAL"},
+				{"debug output", "DEBUG"},
+			},
+		},
+		{
+			name:   "multi-line write",
+			writes: []string{"http: panic serving 192.0.2.1: boom\ngoroutine 1 [running]:\nmain.main()\n"},
+			want:   []line{{"http: panic serving 192.0.2.1: boom\ngoroutine 1 [running]:\nmain.main()", "ERROR"}},
+		},
+		{
+			name:   "partial lines",
+			writes: []string{"ERROR par", "tial", " line\nINFO next", "\n"},
+			want:   []line{{"ERROR partial line", "ERROR"}, {"INFO next", "INFO"}},
+		},
+		{
+			name:   "empty lines",
+			writes: []string{"\n", "\r\n"},
+		},
+		{
+			name:   "pending line",
+			writes: []string{"no newline"},
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			logger, entries := capture(t, &HandlerOptions{Level: slog.LevelDebug})
+			writer := NewStdWriter(logger, LevelNotice, DefaultLevelMatchers...)
+
+			for _, data := range test.writes {
+				if n, err := io.WriteString(writer, data); err != nil || n != len(data) {
+					t.Fatalf("got %d, %v, want %d", n, err, len(data))
+				}
+			}
+
+			collection := entries()
+			if len(collection) != len(test.want) {
+				t.Fatalf("got %d entries, want %d", len(collection), len(test.want))
+			}
+
+			for index, want := range test.want {
+				entry := collection[index]
+				if got := payloadOf(entry)["logging.googleapis.com/message"]; got != want.message {
+					t.Errorf("got message %q, want %q", got, want.message)
+				}
+
+				if got := entry["severity"]; got != want.severity {
+					t.Errorf("got severity %v of %q, want %s", got, want.message, want.severity)
+				}
+			}
+		})
+	}
+}
+
+func TestStdWriterPendingLimit(t *testing.T) {
+	logger, entries := capture(t, nil)
+	writer := NewStdWriter(logger, slog.LevelInfo)
+
+	chunk := strings.Repeat("x", 1024)
+	for written := 0; written <= stdPendingLimit; written += len(chunk) {
+		if _, err := io.WriteString(writer, chunk); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := len(writer.pending); got != 0 {
+		t.Fatalf("got %d pending bytes, want none", got)
+	}
+
+	single(t, entries())
+}
+
+func TestNewStdLogger(t *testing.T) {
+	logger, entries := capture(t, nil)
+
+	NewStdLogger(logger, slog.LevelInfo).Printf("[warn] %d retries", 3)
+
+	entry := single(t, entries())
+	if got := entry["severity"]; got != "WARNING" {
+		t.Fatalf("got severity %v, want WARNING", got)
+	}
+}
@@ -0,0 +1,379 @@
+package slogr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	ltype "google.golang.org/genproto/googleapis/logging/type"
+)
+
+// syslogID is the SD-ID of the structured data of the syslog messages.
+const syslogID = "slogr@32473"
+
+// syslogPaths are the paths of the local syslog socket.
+var syslogPaths = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogOptions for a SyslogWriter. A zero SyslogOptions writes to the local
+// syslog socket.
+type SyslogOptions struct {
+	// Network is the network of the syslog server, such as "tcp", "udp" or
+	// "unixgram". If Network is empty, the writer uses the local syslog
+	// socket.
+	Network string
+
+	// Address is the address of the syslog server.
+	Address string
+
+	// Facility is the syslog facility of the messages.
+	// If Facility is zero, the writer assumes 1 (LOG_USER).
+	Facility int
+
+	// AppName is the APP-NAME of the messages.
+	// If AppName is empty, the writer assumes the name of the binary.
+	AppName string
+
+	// Hostname is the HOSTNAME of the messages.
+	// If Hostname is empty, the writer assumes os.Hostname.
+	Hostname string
+
+	// When StructuredData is true, the payload fields are written as the
+	// SD-PARAMS of the message, and the message is the text of the entry. By
+	// default, the message is the JSON entry in the logging agent format.
+	StructuredData bool
+}
+
+// SyslogWriter writes the entries to a syslog server as RFC 5424 messages.
+// The severity of an entry maps to the syslog severity of the same name. It
+// reconnects when a write fails, and it's safe for concurrent use.
+type SyslogWriter struct {
+	network    string
+	address    string
+	facility   int
+	app        string
+	hostname   string
+	structured bool
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter creates a SyslogWriter, and connects to the syslog server.
+func NewSyslogWriter(opts *SyslogOptions) (*SyslogWriter, error) {
+	w := &SyslogWriter{
+		network:    opts.Network,
+		address:    opts.Address,
+		facility:   opts.Facility,
+		app:        opts.AppName,
+		hostname:   opts.Hostname,
+		structured: opts.StructuredData,
+	}
+
+	if w.facility == 0 {
+		w.facility = 1
+	}
+
+	if w.app == "" {
+		w.app = filepath.Base(os.Args[0])
+	}
+
+	if w.hostname == "" {
+		w.hostname, _ = os.Hostname()
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewSyslogHandler creates a [slog.Handler] that writes the entries to a given
+// SyslogWriter instead of an io.Writer. The options are the ones of
+// NewHandler.
+func NewSyslogHandler(w *SyslogWriter, opts *HandlerOptions) slog.Handler {
+	h := NewHandler(nil, opts).(*Handler)
+	h.sink = w
+	// done!
+	return h
+}
+
+// WriteEntry writes a given entry as a syslog message, and reconnects once
+// when the write fails.
+func (w *SyslogWriter) WriteEntry(e *Entry) error {
+	message, err := w.format(e)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn != nil {
+		if err = w.write(message); err == nil {
+			return nil
+		}
+	}
+
+	// reconnect and try again
+	if err := w.connect(); err != nil {
+		return err
+	}
+
+	return w.write(message)
+}
+
+// Close closes the connection to the syslog server.
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.conn == nil {
+		return nil
+	}
+
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// connect dials the syslog server, or the local syslog socket.
+func (w *SyslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+
+	if w.network != "" {
+		conn, err := net.Dial(w.network, w.address)
+		if err != nil {
+			return err
+		}
+
+		w.conn = conn
+		return nil
+	}
+
+	for _, network := range []string{"unixgram", "unix"} {
+		for _, path := range syslogPaths {
+			if conn, err := net.Dial(network, path); err == nil {
+				w.conn = conn
+				return nil
+			}
+		}
+	}
+
+	return errors.New("slogr: the local syslog socket is unavailable")
+}
+
+// write writes a given message. The stream connections frame the messages
+// with their length, as of RFC 6587.
+func (w *SyslogWriter) write(message []byte) error {
+	switch w.conn.(type) {
+	case *net.TCPConn:
+		message = append([]byte(fmt.Sprintf("%d ", len(message))), message...)
+	case *net.UnixConn:
+		if w.conn.LocalAddr().Network() == "unix" {
+			message = append(message, '\n')
+		}
+	}
+
+	_, err := w.conn.Write(message)
+	return err
+}
+
+// format returns the RFC 5424 message of a given entry.
+func (w *SyslogWriter) format(e *Entry) ([]byte, error) {
+	var (
+		buffer    = &bytes.Buffer{}
+		timestamp = time.Now()
+		msgid     = "-"
+	)
+
+	if e.Timestamp != nil {
+		timestamp = e.Timestamp.AsTime()
+	}
+
+	if name := e.LogName; name != "" {
+		msgid = syslogName(name[strings.LastIndex(name, "/")+1:], 32)
+	}
+
+	fmt.Fprintf(buffer, "<%d>1 %s %s %s %d %s ",
+		w.facility*8+syslogSeverity(e.Severity),
+		timestamp.UTC().Format(time.RFC3339Nano),
+		syslogValue(w.hostname, 255),
+		syslogValue(w.app, 48),
+		os.Getpid(),
+		msgid,
+	)
+
+	if !w.structured {
+		buffer.WriteString("- ")
+		// the message is the json entry
+		if err := EncodeEntry(buffer, e, EncodeOptions{}); err != nil {
+			return nil, err
+		}
+
+		return bytes.TrimSuffix(buffer.Bytes(), []byte("\n")), nil
+	}
+
+	params, message, err := syslogParams(e)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(params) == 0 {
+		buffer.WriteString("-")
+	} else {
+		buffer.WriteString("[" + syslogID)
+		// write the params
+		for _, param := range params {
+			fmt.Fprintf(buffer, " %s=\"%s\"", param[0], syslogEscape(param[1]))
+		}
+		buffer.WriteString("]")
+	}
+
+	if message != "" {
+		buffer.WriteString(" " + message)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// syslogParams returns the sorted SD-PARAMS and the message of a given entry.
+func syslogParams(e *Entry) ([][2]string, string, error) {
+	attributes, err := e.fields(EncodeOptions{})
+	if err != nil {
+		return nil, "", err
+	}
+
+	var (
+		params  [][2]string
+		message string
+	)
+
+	add := func(key string, value any) error {
+		text, ok := value.(string)
+		if !ok {
+			data, err := json.Marshal(value)
+			if err != nil {
+				return err
+			}
+
+			text = string(data)
+		}
+
+		params = append(params, [2]string{syslogName(strings.TrimPrefix(key, "logging.googleapis.com/"), 32), text})
+		return nil
+	}
+
+	for key, value := range attributes {
+		switch key {
+		case FieldSeverity, FieldTime:
+			continue
+		case FieldLabels:
+			if len(e.Labels) == 0 {
+				continue
+			}
+		case FieldMessage:
+			switch payload := e.Payload.(type) {
+			case *loggingpb.LogEntry_TextPayload:
+				message = payload.TextPayload
+				continue
+			case *loggingpb.LogEntry_JsonPayload:
+				// flatten the payload fields
+				for name, field := range payload.JsonPayload.AsMap() {
+					if name == FieldPayloadMessage {
+						message, _ = field.(string)
+					} else if err := add(name, field); err != nil {
+						return nil, "", err
+					}
+				}
+
+				continue
+			}
+		}
+
+		if err := add(key, value); err != nil {
+			return nil, "", err
+		}
+	}
+
+	sort.Slice(params, func(i, j int) bool {
+		return params[i][0] < params[j][0]
+	})
+
+	return params, message, nil
+}
+
+// syslogSeverity returns the syslog severity of a given severity.
+func syslogSeverity(severity ltype.LogSeverity) int {
+	switch {
+	case severity >= ltype.LogSeverity_EMERGENCY:
+		return 0
+	case severity >= ltype.LogSeverity_ALERT:
+		return 1
+	case severity >= ltype.LogSeverity_CRITICAL:
+		return 2
+	case severity >= ltype.LogSeverity_ERROR:
+		return 3
+	case severity >= ltype.LogSeverity_WARNING:
+		return 4
+	case severity >= ltype.LogSeverity_NOTICE:
+		return 5
+	case severity >= ltype.LogSeverity_INFO, severity == ltype.LogSeverity_DEFAULT:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// syslogValue returns a given header value as printable ASCII, truncated to
+// a given length.
+func syslogValue(value string, limit int) string {
+	value = strings.Map(func(r rune) rune {
+		if r <= ' ' || r > '~' {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if value == "" {
+		return "-"
+	}
+
+	if len(value) > limit {
+		value = value[:limit]
+	}
+
+	return value
+}
+
+// syslogName returns a given SD-NAME without the forbidden characters.
+func syslogName(value string, limit int) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '=' || r == ']' || r == '"' {
+			return '_'
+		}
+
+		return r
+	}, value)
+
+	return syslogValue(value, limit)
+}
+
+// syslogEscape escapes a given SD-PARAM value.
+func syslogEscape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(value)
+}
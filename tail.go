@@ -0,0 +1,109 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errNoEntry is returned by the polling when there's no matching entry yet.
+var errNoEntry = errors.New("slogr: no entry found")
+
+// WaitForEntry waits for the first entry of a given project that matches a
+// given Cloud Logging filter. It tails the entries via the TailLogEntries
+// stream, and falls back to polling ListLogEntries when the stream is not
+// permitted. The polling also matches the entries written before the call, so
+// the filter should identify the expected entry, e.g. by its trace.
+func WaitForEntry(ctx context.Context, client loggingpb.LoggingServiceV2Client, projectID, filter string, timeout time.Duration) (*Entry, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	resource := path.Join("projects", projectID)
+
+	entry, err := tailEntry(ctx, client, resource, filter)
+	switch {
+	case entry != nil:
+		return entry, nil
+	case ctx.Err() != nil:
+		return nil, fmt.Errorf("slogr: no entry matching %q within %v: %w", filter, timeout, ctx.Err())
+	case status.Code(err) != codes.PermissionDenied && status.Code(err) != codes.Unimplemented:
+		return nil, err
+	}
+
+	entry, err = pollEntry(ctx, client, resource, filter)
+	switch {
+	case entry != nil:
+		return entry, nil
+	case ctx.Err() != nil, errors.Is(err, errNoEntry):
+		return nil, fmt.Errorf("slogr: no entry matching %q within %v: %w", filter, timeout, context.DeadlineExceeded)
+	default:
+		return nil, err
+	}
+}
+
+// tailEntry returns the first entry received from a TailLogEntries stream.
+func tailEntry(ctx context.Context, client loggingpb.LoggingServiceV2Client, resource, filter string) (*Entry, error) {
+	stream, err := client.TailLogEntries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &loggingpb.TailLogEntriesRequest{
+		ResourceNames: []string{resource},
+		Filter:        filter,
+	}
+
+	if err := stream.Send(request); err != nil {
+		return nil, err
+	}
+
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return nil, err
+		}
+
+		if entries := response.GetEntries(); len(entries) > 0 {
+			return (*Entry)(entries[0]), nil
+		}
+	}
+}
+
+// pollEntry returns the latest entry listed by ListLogEntries, polling with a
+// backoff until there's one.
+func pollEntry(ctx context.Context, client loggingpb.LoggingServiceV2Client, resource, filter string) (*Entry, error) {
+	var (
+		entry   *Entry
+		backoff = &Backoff{Initial: time.Second, Max: 10 * time.Second, Jitter: 0.2}
+	)
+
+	err := backoff.Do(ctx, func() error {
+		response, err := client.ListLogEntries(ctx, &loggingpb.ListLogEntriesRequest{
+			ResourceNames: []string{resource},
+			Filter:        filter,
+			OrderBy:       "timestamp desc",
+			PageSize:      1,
+		})
+		if err != nil {
+			return err
+		}
+
+		if entries := response.GetEntries(); len(entries) > 0 {
+			entry = (*Entry)(entries[0])
+			return nil
+		}
+
+		return errNoEntry
+	}, func(err error) bool {
+		// an empty list is retried
+		return errors.Is(err, errNoEntry) || IsRetryable(err)
+	})
+
+	return entry, err
+}
@@ -0,0 +1,164 @@
+package slogr
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// loggingServer is a fake Cloud Logging server that streams or lists its
+// entries whose trace matches the filter, a `trace="..."` expression.
+type loggingServer struct {
+	loggingpb.UnimplementedLoggingServiceV2Server
+
+	entries []*loggingpb.LogEntry
+	// tail is the error of TailLogEntries, e.g. PermissionDenied
+	tail error
+}
+
+func (s *loggingServer) matching(filter string) []*loggingpb.LogEntry {
+	var entries []*loggingpb.LogEntry
+
+	for _, entry := range s.entries {
+		if filter == `trace="`+entry.Trace+`"` {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries
+}
+
+// TailLogEntries implements loggingpb.LoggingServiceV2Server
+func (s *loggingServer) TailLogEntries(stream loggingpb.LoggingServiceV2_TailLogEntriesServer) error {
+	if s.tail != nil {
+		return s.tail
+	}
+
+	request, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+
+	if len(request.ResourceNames) != 1 || request.ResourceNames[0] != "projects/my-project" {
+		return status.Error(codes.InvalidArgument, "unexpected resource")
+	}
+
+	// the stream sends the entries that don't match as empty responses
+	for _, entry := range s.entries {
+		response := &loggingpb.TailLogEntriesResponse{}
+		if filter := request.Filter; filter == `trace="`+entry.Trace+`"` {
+			response.Entries = []*loggingpb.LogEntry{entry}
+		}
+
+		if err := stream.Send(response); err != nil {
+			return err
+		}
+	}
+
+	<-stream.Context().Done()
+	return nil
+}
+
+// ListLogEntries implements loggingpb.LoggingServiceV2Server
+func (s *loggingServer) ListLogEntries(_ context.Context, r *loggingpb.ListLogEntriesRequest) (*loggingpb.ListLogEntriesResponse, error) {
+	entries := s.matching(r.Filter)
+	if len(entries) > int(r.PageSize) {
+		entries = entries[len(entries)-int(r.PageSize):]
+	}
+
+	return &loggingpb.ListLogEntriesResponse{Entries: entries}, nil
+}
+
+// newLoggingClient returns a client of a given fake server.
+func newLoggingClient(t *testing.T, server *loggingServer) loggingpb.LoggingServiceV2Client {
+	t.Helper()
+
+	listener := bufconn.Listen(1 << 20)
+
+	s := grpc.NewServer()
+	loggingpb.RegisterLoggingServiceV2Server(s, server)
+
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.DialContext(context.Background(), "bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+	return loggingpb.NewLoggingServiceV2Client(conn)
+}
+
+func TestWaitForEntry(t *testing.T) {
+	entries := []*loggingpb.LogEntry{
+		{Trace: "projects/my-project/traces/other", InsertId: "1"},
+		{Trace: "projects/my-project/traces/canary", InsertId: "2"},
+	}
+
+	for _, tc := range []struct {
+		name string
+		tail error
+	}{
+		{"tail", nil},
+		{"permission denied", status.Error(codes.PermissionDenied, "denied")},
+		{"unimplemented", status.Error(codes.Unimplemented, "unimplemented")},
+	} {
+		client := newLoggingClient(t, &loggingServer{entries: entries, tail: tc.tail})
+
+		entry, err := WaitForEntry(context.Background(), client, "my-project", `trace="projects/my-project/traces/canary"`, 5*time.Second)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+
+		if entry.InsertId != "2" {
+			t.Errorf("%s: got entry %v, want the one of the canary", tc.name, (*loggingpb.LogEntry)(entry))
+		}
+	}
+}
+
+func TestWaitForEntryTimeout(t *testing.T) {
+	const filter = `trace="projects/my-project/traces/missing"`
+
+	for _, tc := range []struct {
+		name string
+		tail error
+	}{
+		{"tail", nil},
+		{"poll", status.Error(codes.PermissionDenied, "denied")},
+	} {
+		client := newLoggingClient(t, &loggingServer{
+			entries: []*loggingpb.LogEntry{{Trace: "projects/my-project/traces/other"}},
+			tail:    tc.tail,
+		})
+
+		_, err := WaitForEntry(context.Background(), client, "my-project", filter, 50*time.Millisecond)
+		if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), strconv.Quote(filter)) {
+			t.Errorf("%s: got %v, want a timeout with the filter", tc.name, err)
+		}
+	}
+}
+
+func TestWaitForEntryError(t *testing.T) {
+	client := newLoggingClient(t, &loggingServer{tail: status.Error(codes.InvalidArgument, "invalid filter")})
+
+	// the errors other than the permission ones are not retried by polling
+	_, err := WaitForEntry(context.Background(), client, "my-project", "bad", time.Second)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("got %v, want the error of the stream", err)
+	}
+}
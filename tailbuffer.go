@@ -0,0 +1,222 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+var tailBufferKey = &ContextKey{name: "tail_buffer"}
+
+// TailBufferOptions for the tail buffer handler. A zero TailBufferOptions
+// consists entirely of default values.
+type TailBufferOptions struct {
+	// Threshold reports the level of the records that flush the buffer.
+	// If Threshold is nil, the handler assumes LevelError.
+	Threshold slog.Leveler
+
+	// MaxEntries is the maximum number of buffered records per request.
+	// If MaxEntries is zero, the handler assumes 256.
+	MaxEntries int
+
+	// MaxBytes is the approximate maximum size of the buffered records per
+	// request. If MaxBytes is zero, the handler assumes 1MiB.
+	MaxBytes int
+}
+
+// NewTailBufferHandler creates a [slog.Handler] that holds the records that a
+// given handler would drop, while the context has a tail buffer provided by
+// WithTailBuffer. The buffered records are written in their original order,
+// with their original time, ahead of the first record at or above the
+// threshold, or by EndTailBuffer when the request failed. Otherwise, they're
+// discarded. The oldest records are dropped beyond the buffer bounds.
+func NewTailBufferHandler(inner slog.Handler, opts *TailBufferOptions) slog.Handler {
+	if opts == nil {
+		opts = &TailBufferOptions{}
+	}
+
+	h := &tailBufferHandler{
+		inner:      inner,
+		threshold:  opts.Threshold,
+		maxEntries: opts.MaxEntries,
+		maxBytes:   opts.MaxBytes,
+	}
+
+	if h.threshold == nil {
+		h.threshold = slog.LevelError
+	}
+
+	if h.maxEntries <= 0 {
+		h.maxEntries = 256
+	}
+
+	if h.maxBytes <= 0 {
+		h.maxBytes = 1 << 20
+	}
+
+	return h
+}
+
+// WithTailBuffer provides a tail buffer in a given context for the records
+// of a request.
+func WithTailBuffer(ctx context.Context) context.Context {
+	return context.WithValue(ctx, tailBufferKey, &tailBuffer{})
+}
+
+// EndTailBuffer ends the tail buffer of a given context. The buffered records
+// are written if the request failed with a given status of 500 or above, and
+// discarded otherwise. The records logged afterwards, e.g. by goroutines that
+// outlive the request, are no longer buffered.
+func EndTailBuffer(ctx context.Context, status int) {
+	buffer, ok := ctx.Value(tailBufferKey).(*tailBuffer)
+	if !ok {
+		return
+	}
+
+	items := buffer.end()
+	// the request succeeded
+	if status < 500 {
+		return
+	}
+
+	for _, item := range items {
+		_ = item.handler.Handle(ctx, item.record)
+	}
+}
+
+// tailItem represents a buffered record with the handler that received it.
+type tailItem struct {
+	handler slog.Handler
+	record  slog.Record
+	size    int
+}
+
+// tailBuffer represents the buffered records of a request.
+type tailBuffer struct {
+	mu    sync.Mutex
+	items []tailItem
+	size  int
+	ended bool
+}
+
+// add buffers a given item within the bounds. It returns false when the
+// buffer has ended.
+func (b *tailBuffer) add(item tailItem, maxEntries, maxBytes int) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ended {
+		return false
+	}
+
+	b.items = append(b.items, item)
+	b.size += item.size
+	// drop the oldest records beyond the bounds
+	for len(b.items) > maxEntries || (b.size > maxBytes && len(b.items) > 1) {
+		b.size -= b.items[0].size
+		b.items[0] = tailItem{}
+		b.items = b.items[1:]
+	}
+
+	return true
+}
+
+// take returns the buffered records and empties the buffer.
+func (b *tailBuffer) take() []tailItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items := b.items
+	b.items, b.size = nil, 0
+	return items
+}
+
+// end returns the buffered records and ends the buffer.
+func (b *tailBuffer) end() []tailItem {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	items := b.items
+	b.items, b.size, b.ended = nil, 0, true
+	return items
+}
+
+// isEnded reports whether the buffer has ended.
+func (b *tailBuffer) isEnded() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ended
+}
+
+type tailBufferHandler struct {
+	inner      slog.Handler
+	threshold  slog.Leveler
+	maxEntries int
+	maxBytes   int
+}
+
+// Enabled implements slog.Handler
+func (h *tailBufferHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	if h.inner.Enabled(ctx, level) {
+		return true
+	}
+
+	buffer, ok := ctx.Value(tailBufferKey).(*tailBuffer)
+	return ok && !buffer.isEnded()
+}
+
+// Handle implements slog.Handler
+func (h *tailBufferHandler) Handle(ctx context.Context, r slog.Record) error {
+	buffer, ok := ctx.Value(tailBufferKey).(*tailBuffer)
+	if !ok {
+		return h.inner.Handle(ctx, r)
+	}
+
+	if r.Level >= h.threshold.Level() {
+		// the buffered records come first
+		for _, item := range buffer.take() {
+			_ = item.handler.Handle(ctx, item.record)
+		}
+
+		return h.inner.Handle(ctx, r)
+	}
+
+	if h.inner.Enabled(ctx, r.Level) {
+		return h.inner.Handle(ctx, r)
+	}
+
+	item := tailItem{
+		handler: h.inner,
+		record:  r.Clone(),
+		size:    len(r.Message),
+	}
+
+	r.Attrs(func(attr slog.Attr) bool {
+		item.size += len(attr.Key) + len(attr.Value.String())
+		return true
+	})
+
+	buffer.add(item, h.maxEntries, h.maxBytes)
+	// done!
+	return nil
+}
+
+// WithAttrs implements slog.Handler
+func (h *tailBufferHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithAttrs(attrs)
+	return &c
+}
+
+// WithGroup implements slog.Handler
+func (h *tailBufferHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.inner = h.inner.WithGroup(name)
+	return &c
+}
+
+// handlerStats implements statsHolder
+func (h *tailBufferHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
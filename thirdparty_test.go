@@ -0,0 +1,93 @@
+package slogr
+
+import (
+	"encoding/json"
+	"log/slog"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// thirdPartyFixtures are the attribute shapes of popular slog helper
+// libraries, vendored as fixtures, and the payload the handler writes for them.
+var thirdPartyFixtures = []struct {
+	name    string
+	with    []any
+	group   string
+	attrs   []any
+	payload string
+}{
+	{
+		// a GORM logger attaches the query as a group of the logger
+		name: "gorm",
+		with: []any{slog.Group("db",
+			slog.String("operation", "SELECT"),
+			slog.Int64("rows", 3),
+			slog.Duration("elapsed", 2*time.Millisecond),
+		)},
+		attrs:   []any{slog.String("query", "SELECT * FROM books")},
+		payload: `{"db":{"operation":"SELECT","rows":3,"elapsed":"2ms"},"query":"SELECT * FROM books"}`,
+	},
+	{
+		// an HTTP middleware logs the request and the response as groups
+		name: "http",
+		attrs: []any{
+			slog.Group("request", slog.String("method", "GET"), slog.String("path", "/books")),
+			slog.Group("response", slog.Int("status", 200), slog.Duration("latency", time.Second)),
+		},
+		payload: `{"request":{"method":"GET","path":"/books"},"response":{"status":200,"latency":"1s"}}`,
+	},
+	{
+		// a tracing helper logs the names as plain strings
+		name: "tracing",
+		attrs: []any{
+			slog.String("span", "db.query"),
+			slog.String("operation", "SELECT"),
+			slog.String("labels", "a,b"),
+			slog.String("audit", "login"),
+		},
+		payload: `{"span":"db.query","operation":"SELECT","labels":"a,b","audit":"login"}`,
+	},
+	{
+		// the reserved keys of a group are written as they are
+		name:    "grouped",
+		group:   "app",
+		attrs:   []any{slog.String("operation", "List"), slog.Group("request", slog.String("id", "r-1"))},
+		payload: `{"app":{"operation":"List","request":{"id":"r-1"}}}`,
+	},
+}
+
+func TestThirdPartyAttrs(t *testing.T) {
+	for _, tc := range thirdPartyFixtures {
+		logger, entries := capture(t, nil)
+
+		if len(tc.with) > 0 {
+			logger = logger.With(tc.with...)
+		}
+
+		if tc.group != "" {
+			logger = logger.WithGroup(tc.group)
+		}
+
+		logger.Info("produced", tc.attrs...)
+
+		entry := single(t, entries())
+		for _, key := range []string{FieldHTTPRequest, FieldOperation} {
+			if got, ok := entry[key]; ok {
+				t.Errorf("%s: got %s %v, want none", tc.name, key, got)
+			}
+		}
+
+		want := make(map[string]any)
+		if err := json.Unmarshal([]byte(tc.payload), &want); err != nil {
+			t.Fatal(err)
+		}
+
+		payload := payloadOf(entry)
+		delete(payload, FieldPayloadMessage)
+
+		if !reflect.DeepEqual(payload, want) {
+			t.Errorf("%s: got payload %v, want %v", tc.name, payload, want)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package slogr
+
+import (
+	"sort"
+	"time"
+)
+
+// OperationTimeline represents the entries of a single operation in time order.
+type OperationTimeline struct {
+	// Id is the operation identifier.
+	Id string
+	// Producer is the operation producer.
+	Producer string
+	// Entries are the operation entries in time order.
+	Entries []*Entry
+	// Complete reports whether the operation has both first and last entries.
+	Complete bool
+	// Duration is the time between the earliest and the latest entry.
+	Duration time.Duration
+	// MaxGap is the longest time between two consecutive entries.
+	MaxGap time.Duration
+}
+
+// BuildOperationTimelines groups the entries that carry an operation by
+// operation id and producer. The entries of every timeline are sorted by
+// timestamp then insert id, and duplicated entries with the same insert id
+// are dropped. The timelines are sorted by their earliest entry.
+func BuildOperationTimelines(entries []*Entry) []OperationTimeline {
+	type key struct {
+		id       string
+		producer string
+	}
+
+	var (
+		keys   []key
+		groups = make(map[key][]*Entry)
+	)
+
+	for _, entry := range entries {
+		if entry == nil || entry.Operation == nil {
+			continue
+		}
+
+		k := key{
+			id:       entry.Operation.Id,
+			producer: entry.Operation.Producer,
+		}
+
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+
+		groups[k] = append(groups[k], entry)
+	}
+
+	timelines := make([]OperationTimeline, 0, len(keys))
+
+	for _, k := range keys {
+		collection := groups[k]
+		// sort the entries by timestamp then insert id
+		sort.SliceStable(collection, func(i, j int) bool {
+			ti := collection[i].Timestamp.AsTime()
+			tj := collection[j].Timestamp.AsTime()
+
+			if !ti.Equal(tj) {
+				return ti.Before(tj)
+			}
+
+			return collection[i].InsertId < collection[j].InsertId
+		})
+
+		timeline := OperationTimeline{
+			Id:       k.id,
+			Producer: k.producer,
+		}
+
+		var (
+			first bool
+			last  bool
+			seen  = make(map[string]bool)
+		)
+
+		for _, entry := range collection {
+			// drop the entries duplicated by retries
+			if id := entry.InsertId; id != "" {
+				if seen[id] {
+					continue
+				}
+
+				seen[id] = true
+			}
+
+			if count := len(timeline.Entries); count > 0 {
+				prev := timeline.Entries[count-1].Timestamp.AsTime()
+				// compute the gap between the entries
+				if gap := entry.Timestamp.AsTime().Sub(prev); gap > timeline.MaxGap {
+					timeline.MaxGap = gap
+				}
+			}
+
+			first = first || entry.Operation.First
+			last = last || entry.Operation.Last
+			// add the entry
+			timeline.Entries = append(timeline.Entries, entry)
+		}
+
+		if count := len(timeline.Entries); count > 0 {
+			start := timeline.Entries[0].Timestamp.AsTime()
+			end := timeline.Entries[count-1].Timestamp.AsTime()
+			// compute the duration
+			timeline.Duration = end.Sub(start)
+		}
+
+		timeline.Complete = first && last
+		timelines = append(timelines, timeline)
+	}
+
+	sort.SliceStable(timelines, func(i, j int) bool {
+		ti := timelines[i].Entries[0].Timestamp.AsTime()
+		tj := timelines[j].Entries[0].Timestamp.AsTime()
+		return ti.Before(tj)
+	})
+
+	return timelines
+}
@@ -0,0 +1,106 @@
+package slogr
+
+import (
+	"testing"
+	"time"
+
+	"cloud.google.com/go/logging/apiv2/loggingpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// operationEntry returns an entry of a given operation at a given offset in
+// seconds.
+func operationEntry(id, insertID string, offset int, first, last bool) *Entry {
+	return &Entry{
+		InsertId:  insertID,
+		Timestamp: timestamppb.New(time.Date(2023, 9, 1, 12, 0, offset, 0, time.UTC)),
+		Operation: &loggingpb.LogEntryOperation{
+			Id:       id,
+			Producer: "books",
+			First:    first,
+			Last:     last,
+		},
+	}
+}
+
+func TestBuildOperationTimelines(t *testing.T) {
+	entries := []*Entry{
+		// the entries are interleaved and out of order
+		operationEntry("b", "b1", 2, true, false),
+		operationEntry("a", "a3", 10, false, true),
+		operationEntry("a", "a1", 0, true, false),
+		nil,
+		{InsertId: "plain", Timestamp: timestamppb.Now()},
+		operationEntry("b", "b2", 3, false, false),
+		operationEntry("a", "a2", 4, false, false),
+		// the retries duplicate the entries
+		operationEntry("a", "a2", 4, false, false),
+		operationEntry("b", "b2", 3, false, false),
+	}
+
+	timelines := BuildOperationTimelines(entries)
+	if len(timelines) != 2 {
+		t.Fatalf("got %d timelines, want 2", len(timelines))
+	}
+
+	for index, tc := range []struct {
+		id       string
+		entries  []string
+		complete bool
+		duration time.Duration
+		gap      time.Duration
+	}{
+		{"a", []string{"a1", "a2", "a3"}, true, 10 * time.Second, 6 * time.Second},
+		// the operation has no end
+		{"b", []string{"b1", "b2"}, false, time.Second, time.Second},
+	} {
+		timeline := timelines[index]
+		if timeline.Id != tc.id || timeline.Producer != "books" {
+			t.Errorf("timeline %d: got %s of %s, want %s", index, timeline.Id, timeline.Producer, tc.id)
+		}
+
+		var ids []string
+		for _, entry := range timeline.Entries {
+			ids = append(ids, entry.InsertId)
+		}
+
+		if len(ids) != len(tc.entries) {
+			t.Errorf("timeline %s: got entries %v, want %v", tc.id, ids, tc.entries)
+			continue
+		}
+
+		for position, id := range tc.entries {
+			if ids[position] != id {
+				t.Errorf("timeline %s: got entries %v, want %v", tc.id, ids, tc.entries)
+			}
+		}
+
+		if timeline.Complete != tc.complete || timeline.Duration != tc.duration || timeline.MaxGap != tc.gap {
+			t.Errorf("timeline %s: got complete %v, duration %v and gap %v, want %v, %v and %v", tc.id,
+				timeline.Complete, timeline.Duration, timeline.MaxGap, tc.complete, tc.duration, tc.gap)
+		}
+	}
+}
+
+func TestBuildOperationTimelinesOrder(t *testing.T) {
+	timelines := BuildOperationTimelines([]*Entry{
+		// the entries of the same time are sorted by insert id
+		operationEntry("a", "2", 0, false, true),
+		operationEntry("a", "1", 0, true, false),
+		// the same id of another producer is another operation
+		{Timestamp: timestamppb.New(time.Date(2023, 9, 1, 11, 0, 0, 0, time.UTC)), Operation: &loggingpb.LogEntryOperation{Id: "a", Producer: "other"}},
+	})
+
+	if len(timelines) != 2 || timelines[0].Producer != "other" || timelines[1].Producer != "books" {
+		t.Fatalf("got timelines %v, want the earliest first", timelines)
+	}
+
+	if entries := timelines[1].Entries; entries[0].InsertId != "1" || entries[1].InsertId != "2" {
+		t.Errorf("got entries %v, want them in insert id order", entries)
+	}
+
+	// no entries build no timelines
+	if got := BuildOperationTimelines(nil); len(got) != 0 {
+		t.Errorf("got timelines %v of no entries, want none", got)
+	}
+}
@@ -0,0 +1,197 @@
+package slogr
+
+import (
+	"context"
+	"crypto/rand"
+	"log/slog"
+	"slices"
+	"strconv"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var syntheticTraceKey = &ContextKey{name: "synthetic_trace"}
+
+// WithNewTrace provides a span context with a random trace id in a given
+// context, so every entry logged with the context shares the same trace. The
+// context is returned unchanged when it already has a valid span context.
+//
+// The span context is only known to the handlers of the package. It's not
+// attached as the OpenTelemetry span context, so the propagators don't send a
+// trace that no tracer has recorded.
+func WithNewTrace(ctx context.Context) context.Context {
+	if trace.SpanContextFromContext(ctx).IsValid() || syntheticSpanContext(ctx).IsValid() {
+		return ctx
+	}
+
+	var (
+		traceID trace.TraceID
+		spanID  trace.SpanID
+	)
+
+	// read the random data
+	if _, err := rand.Read(traceID[:]); err != nil {
+		panic(err)
+	}
+
+	if _, err := rand.Read(spanID[:]); err != nil {
+		panic(err)
+	}
+
+	sctx := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: traceID,
+		SpanID:  spanID,
+		Remote:  true,
+	})
+
+	// done!
+	return context.WithValue(ctx, syntheticTraceKey, sctx)
+}
+
+// syntheticSpanContext returns the span context provided by WithNewTrace in
+// a given context.
+func syntheticSpanContext(ctx context.Context) trace.SpanContext {
+	value, _ := ctx.Value(syntheticTraceKey).(trace.SpanContext)
+	return value
+}
+
+// WithTrace returns a [slog.Handler] that adds the trace, span id and trace
+// sampled fields of the Google Cloud Logging agent format to every record
+// before delegating it to a given handler. When the handler is a slogr
+// Handler, it already writes these fields, so it's returned with the project
+// id set instead.
+func WithTrace(inner slog.Handler, projectID string) slog.Handler {
+	if h, ok := inner.(*Handler); ok {
+		if h.project != "" {
+			return h
+		}
+
+		c := h.clone()
+		c.project = projectID
+		return c
+	}
+
+	return &traceHandler{
+		inner:  inner,
+		tracer: &Handler{project: projectID},
+	}
+}
+
+// traceHandler implements a [slog.Handler] that adds the trace fields to the
+// records of another handler. The groups are applied by the trace handler
+// itself, so the trace fields always stay at the top level.
+type traceHandler struct {
+	inner  slog.Handler
+	tracer *Handler
+	groups []group
+}
+
+// Enabled implements slog.Handler
+func (h *traceHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.inner.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler
+func (h *traceHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	// collect the attributes
+	r.Attrs(func(attr slog.Attr) bool {
+		attrs = append(attrs, attr)
+		return true
+	})
+
+	// nest the attributes in the groups
+	attrs = nest(h.groups, attrs)
+
+	if span := h.tracer.trace(ctx, r); span != nil {
+		attrs = append(attrs,
+			slog.String(FieldTrace, h.tracer.path("traces", span.traceID)),
+			slog.String(FieldSpanID, span.spanID),
+			slog.Bool(FieldTraceSampled, span.sampled),
+		)
+	}
+
+	record := slog.NewRecord(r.Time, r.Level, r.Message, r.PC)
+	record.AddAttrs(attrs...)
+	// delegate the record
+	return h.inner.Handle(ctx, record)
+}
+
+// WithAttrs implements slog.Handler
+func (h *traceHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := h.clone()
+
+	if count := len(c.groups); count > 0 {
+		c.groups[count-1] = c.groups[count-1].with(attrs)
+	} else {
+		c.inner = c.inner.WithAttrs(attrs)
+	}
+
+	return c
+}
+
+// WithGroup implements slog.Handler
+func (h *traceHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	c := h.clone()
+	c.groups = append(c.groups, group{name: name})
+	return c
+}
+
+// handlerStats implements statsHolder
+func (h *traceHandler) handlerStats() *stats {
+	return statsOf(h.inner)
+}
+
+func (h *traceHandler) clone() *traceHandler {
+	return &traceHandler{
+		inner:  h.inner,
+		tracer: h.tracer,
+		groups: slices.Clone(h.groups),
+	}
+}
+
+// TraceKeys reports the vendor-neutral trace keys added to the payload.
+type TraceKeys int
+
+const (
+	// TraceKeysW3C adds the "trace_id" and "span_id" keys in hex.
+	TraceKeysW3C TraceKeys = 1 << iota
+	// TraceKeysDatadog adds the "dd.trace_id" and "dd.span_id" keys in
+	// decimal. The trace id is the low 64 bits of the 128-bit trace id.
+	TraceKeysDatadog
+)
+
+func (h *Handler) vendorTraceKeys(span *traceInfo) []slog.Attr {
+	var attrs []slog.Attr
+
+	if h.traceKeys&TraceKeysW3C != 0 {
+		attrs = append(attrs,
+			slog.String("trace_id", span.traceID),
+			slog.String("span_id", span.spanID),
+		)
+	}
+
+	if h.traceKeys&TraceKeysDatadog != 0 {
+		var group []any
+		// datadog keeps the low 64 bits of the trace id
+		if id := span.traceID; len(id) >= 16 {
+			if value, err := strconv.ParseUint(id[len(id)-16:], 16, 64); err == nil {
+				group = append(group, slog.String("trace_id", strconv.FormatUint(value, 10)))
+			}
+		}
+
+		if value, err := strconv.ParseUint(span.spanID, 16, 64); err == nil {
+			group = append(group, slog.String("span_id", strconv.FormatUint(value, 10)))
+		}
+
+		if len(group) > 0 {
+			attrs = append(attrs, slog.Group("dd", group...))
+		}
+	}
+
+	return attrs
+}
@@ -0,0 +1,36 @@
+//go:build opencensus
+
+package slogr
+
+import (
+	"context"
+
+	octrace "go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The OpenCensus span lookup is enabled with the opencensus build tag, so the
+// dependency is only compiled when it's needed.
+func init() {
+	spanContextLookups = append(spanContextLookups, opencensusSpanContext)
+}
+
+func opencensusSpanContext(ctx context.Context) trace.SpanContext {
+	span := octrace.FromContext(ctx)
+	if span == nil {
+		return trace.SpanContext{}
+	}
+
+	sctx := span.SpanContext()
+
+	config := trace.SpanContextConfig{
+		TraceID: trace.TraceID(sctx.TraceID),
+		SpanID:  trace.SpanID(sctx.SpanID),
+	}
+
+	if sctx.IsSampled() {
+		config.TraceFlags = trace.FlagsSampled
+	}
+
+	return trace.NewSpanContext(config)
+}
@@ -0,0 +1,64 @@
+//go:build opencensus
+
+package slogr
+
+import (
+	"context"
+	"testing"
+
+	octrace "go.opencensus.io/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestOpenCensusSpan(t *testing.T) {
+	ctx, span := octrace.StartSpan(context.Background(), "census", octrace.WithSampler(octrace.AlwaysSample()))
+	defer span.End()
+
+	sctx := span.SpanContext()
+
+	for _, tc := range []struct {
+		name    string
+		ctx     context.Context
+		trace   string
+		spanID  string
+		sampled bool
+	}{
+		{"opencensus", ctx, trace.TraceID(sctx.TraceID).String(), trace.SpanID(sctx.SpanID).String(), true},
+		// the opentelemetry span wins over the opencensus span
+		{"both", trace.ContextWithSpanContext(ctx, knownSpanContext(t)), "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+	} {
+		logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+		logger.InfoContext(tc.ctx, "traced")
+
+		entry := single(t, entries())
+		if got := entry[FieldTrace]; got != "projects/my-project/traces/"+tc.trace {
+			t.Errorf("%s: got trace %v, want %v", tc.name, got, tc.trace)
+		}
+
+		if got := entry[FieldSpanID]; got != tc.spanID {
+			t.Errorf("%s: got span id %v, want %v", tc.name, got, tc.spanID)
+		}
+
+		if got := entry[FieldTraceSampled]; got != tc.sampled {
+			t.Errorf("%s: got sampled %v, want %v", tc.name, got, tc.sampled)
+		}
+	}
+}
+
+func TestOpenCensusSpanNotSampled(t *testing.T) {
+	ctx, span := octrace.StartSpan(context.Background(), "census", octrace.WithSampler(octrace.NeverSample()))
+	defer span.End()
+
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project"})
+	logger.InfoContext(ctx, "traced")
+
+	entry := single(t, entries())
+	// the trace sampled field is omitted when it's false
+	if got, ok := entry[FieldTraceSampled]; ok {
+		t.Errorf("got sampled %v, want none", got)
+	}
+
+	if got := entry[FieldSpanID]; got != trace.SpanID(span.SpanContext().SpanID).String() {
+		t.Errorf("got span id %v", got)
+	}
+}
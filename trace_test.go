@@ -0,0 +1,149 @@
+package slogr
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// knownSpanContext returns the span context of the W3C trace context example.
+func knownSpanContext(t *testing.T) trace.SpanContext {
+	t.Helper()
+
+	tid, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sid, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return trace.NewSpanContext(trace.SpanContextConfig{TraceID: tid, SpanID: sid, TraceFlags: trace.FlagsSampled})
+}
+
+func TestEmitVendorTraceKeys(t *testing.T) {
+	w3c := map[string]any{
+		"trace_id": "4bf92f3577b34da6a3ce929d0e0e4736",
+		"span_id":  "00f067aa0ba902b7",
+	}
+
+	// datadog keeps the low 64 bits of the trace id, in decimal
+	datadog := map[string]any{
+		"dd": map[string]any{
+			"trace_id": "11803532876627986230",
+			"span_id":  "67667974448284343",
+		},
+	}
+
+	for _, tc := range []struct {
+		name string
+		keys TraceKeys
+		want []map[string]any
+	}{
+		{"none", 0, nil},
+		{"w3c", TraceKeysW3C, []map[string]any{w3c}},
+		{"datadog", TraceKeysDatadog, []map[string]any{datadog}},
+		{"both", TraceKeysW3C | TraceKeysDatadog, []map[string]any{w3c, datadog}},
+	} {
+		logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project", EmitVendorTraceKeys: tc.keys})
+
+		ctx := trace.ContextWithSpanContext(context.Background(), knownSpanContext(t))
+		logger.InfoContext(ctx, "traced", "count", 1)
+
+		entry := single(t, entries())
+		// the standard fields are computed from the same span context
+		if got := entry[FieldTrace]; got != "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s: got trace %v", tc.name, got)
+		}
+
+		want := map[string]any{"count": 1.0, FieldPayloadMessage: "traced"}
+		for _, keys := range tc.want {
+			for key, value := range keys {
+				want[key] = value
+			}
+		}
+
+		if payload := payloadOf(entry); !reflect.DeepEqual(payload, want) {
+			t.Errorf("%s: got payload %v, want %v", tc.name, payload, want)
+		}
+	}
+}
+
+func TestEmitVendorTraceKeysWithoutSpan(t *testing.T) {
+	logger, entries := capture(t, &HandlerOptions{ProjectID: "my-project", EmitVendorTraceKeys: TraceKeysW3C | TraceKeysDatadog})
+	logger.Info("untraced")
+
+	if got := single(t, entries())["message"]; got != "untraced" {
+		t.Fatalf("got payload %v, want no vendor keys", got)
+	}
+}
+
+func TestWithNewTrace(t *testing.T) {
+	ctx := WithNewTrace(context.Background())
+
+	// the synthetic trace isn't an opentelemetry span context
+	if sctx := trace.SpanContextFromContext(ctx); sctx.IsValid() {
+		t.Errorf("got span context %v, want none", sctx)
+	}
+
+	carrier := propagation.MapCarrier{}
+	// the propagators don't send the synthetic trace
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	if len(carrier) != 0 {
+		t.Errorf("got headers %v, want none", carrier)
+	}
+
+	// the trace is kept by the nested calls
+	if got := WithNewTrace(ctx); !syntheticSpanContext(got).Equal(syntheticSpanContext(ctx)) {
+		t.Errorf("got another trace of a context with a trace")
+	}
+
+	sctx := syntheticSpanContext(ctx)
+
+	for _, tc := range []struct {
+		name string
+		opts HandlerOptions
+	}{
+		{"span", HandlerOptions{}},
+		// the synthetic trace is used when the lookup has none
+		{"lookup", HandlerOptions{TraceFromContext: func(context.Context) (string, string, bool, bool) {
+			return "", "", false, false
+		}}},
+	} {
+		tc.opts.ProjectID = "my-project"
+		logger, entries := capture(t, &tc.opts)
+		logger.InfoContext(ctx, "traced")
+
+		entry := single(t, entries())
+		if got := entry[FieldTrace]; got != "projects/my-project/traces/"+sctx.TraceID().String() {
+			t.Errorf("%s: got trace %v, want %v", tc.name, got, sctx.TraceID())
+		}
+
+		if got := entry[FieldSpanID]; got != sctx.SpanID().String() {
+			t.Errorf("%s: got span id %v, want %v", tc.name, got, sctx.SpanID())
+		}
+	}
+}
+
+func TestWithNewTraceOfSpan(t *testing.T) {
+	parent := trace.ContextWithSpanContext(context.Background(), knownSpanContext(t))
+
+	ctx := WithNewTrace(parent)
+	if ctx != parent {
+		t.Errorf("got a new context of a context with a span")
+	}
+
+	carrier := propagation.MapCarrier{}
+	// the real span is still propagated
+	propagation.TraceContext{}.Inject(ctx, carrier)
+
+	if got := carrier.Get("traceparent"); got != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("got traceparent %q", got)
+	}
+}
@@ -0,0 +1,90 @@
+package slogr
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// watchInterval is the interval between the reads of a watched level file.
+var watchInterval = 5 * time.Second
+
+// WatchLevel sets a given level from the file at path, and reloads it on
+// SIGHUP and whenever the content of the file changes, until the context is
+// cancelled. The file is polled by path, so the symlink swaps of a Kubernetes
+// ConfigMap volume are picked up too. The applied changes are logged at
+// LevelInfo with the logger of the context. A file with an invalid level is
+// ignored and the previous level is kept. There's no SIGHUP on js/wasm, where
+// the file is only polled.
+//
+// WatchLevel blocks. It returns an error when the file cannot be read or has
+// an invalid level at start up, and nil once the context is cancelled.
+func WatchLevel(ctx context.Context, path string, v *slog.LevelVar) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	level, err := ParseLevel(string(data))
+	if err != nil {
+		return fmt.Errorf("slogr: invalid level file %q: %w", path, err)
+	}
+
+	v.Set(level)
+
+	signals := make(chan os.Signal, 1)
+	// without the signals of the platform, the file is only polled
+	_ = notifyReload(signals)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+
+	logger := FromContext(ctx)
+
+	reload := func(force bool) {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			logger.WarnContext(ctx, "log level file unreadable", slog.String("path", path), Error(err))
+			return
+		}
+
+		// the file is polled, so only the changes are applied
+		if !force && bytes.Equal(content, data) {
+			return
+		}
+
+		data = content
+
+		next, err := ParseLevel(string(content))
+		if err != nil {
+			logger.WarnContext(ctx, "log level file ignored", slog.String("path", path), Error(err))
+			return
+		}
+
+		if previous := v.Level(); previous != next {
+			v.Set(next)
+			// report the change
+			logger.InfoContext(ctx, "log level changed",
+				slog.String("path", path),
+				slog.String("level", levelString(next)),
+				slog.String("previous", levelString(previous)),
+			)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-signals:
+			reload(true)
+		case <-ticker.C:
+			reload(false)
+		}
+	}
+}
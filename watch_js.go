@@ -0,0 +1,11 @@
+//go:build js
+
+package slogr
+
+import "os"
+
+// notifyReload reports ErrUnsupportedPlatform, since there are no signals on
+// js.
+func notifyReload(_ chan<- os.Signal) error {
+	return ErrUnsupportedPlatform
+}
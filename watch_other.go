@@ -0,0 +1,15 @@
+//go:build !js
+
+package slogr
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyReload relays SIGHUP to a given channel.
+func notifyReload(c chan<- os.Signal) error {
+	signal.Notify(c, syscall.SIGHUP)
+	return nil
+}
@@ -0,0 +1,72 @@
+package slogr
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchLevel(t *testing.T) {
+	interval := watchInterval
+	watchInterval = time.Millisecond
+	defer func() { watchInterval = interval }()
+
+	path := filepath.Join(t.TempDir(), "level")
+	if err := os.WriteFile(path, []byte("debug"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		level       = &slog.LevelVar{}
+		ctx, cancel = context.WithCancel(context.Background())
+		errs        = make(chan error, 1)
+	)
+
+	go func() { errs <- WatchLevel(ctx, path, level) }()
+
+	waitLevel := func(want slog.Level) {
+		t.Helper()
+
+		deadline := time.Now().Add(time.Second)
+		for level.Level() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("got level %v, want %v", level.Level(), want)
+			}
+
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitLevel(slog.LevelDebug)
+
+	for _, content := range []string{"invalid", "error"} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitLevel(slog.LevelError)
+	cancel()
+
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWatchLevelInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "level")
+	if err := WatchLevel(context.Background(), path, &slog.LevelVar{}); err == nil {
+		t.Fatal("got no error of a missing file")
+	}
+
+	if err := os.WriteFile(path, []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := WatchLevel(context.Background(), path, &slog.LevelVar{}); err == nil {
+		t.Fatal("got no error of an invalid level")
+	}
+}
@@ -0,0 +1,241 @@
+// Package zapslog provides a [zapcore.Core] that writes the zap entries to a
+// [slog.Handler], such as the handler of slogr, so the services that still log
+// with zap write the same Google Cloud Logging entries meanwhile.
+package zapslog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+
+	"github.com/ralch/slogr"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+var _ zapcore.Core = &Core{}
+
+// Core implements a [zapcore.Core].
+//
+// The zap levels map to the slog levels, DPanic to slogr.LevelCritical, Panic
+// to slogr.LevelAlert, and Fatal to slogr.LevelEmergency. The fields are the
+// attributes of the records, a Namespace is a group of the fields that follow
+// it, the objects are groups and the arrays are lists. The errors are the
+// values of slogr.Error with the key of their field, so their causes are kept,
+// and the errors with a stack add it as the Verbose field, as in zap.
+// The name of the logger is the log name of the entries, as given to
+// slogr.Name, the caller is their source location, and the stack is their
+// slogr.StackTraceKey.
+//
+// The Core only checks the level of the handler, so the sampling of zap, which
+// wraps the Core, applies as is.
+type Core struct {
+	handler slog.Handler
+	spaces  []namespace
+}
+
+// namespace represents an open namespace of a core with its fields.
+type namespace struct {
+	key   string
+	attrs []slog.Attr
+}
+
+// NewCore creates a Core that writes to a given handler.
+func NewCore(handler slog.Handler) *Core {
+	return &Core{handler: handler}
+}
+
+// Enabled implements zapcore.LevelEnabler
+func (c *Core) Enabled(level zapcore.Level) bool {
+	return c.handler.Enabled(context.Background(), Level(level))
+}
+
+// With implements zapcore.Core
+func (c *Core) With(fields []zapcore.Field) zapcore.Core {
+	x := &Core{
+		handler: c.handler,
+		spaces:  slices.Clone(c.spaces),
+	}
+
+	for index, field := range fields {
+		// the namespace applies to the fields of the later calls too
+		if field.Type == zapcore.NamespaceType {
+			x.add(attrs(fields[:index]))
+			x.spaces = append(x.spaces, namespace{key: field.Key})
+			return x.With(fields[index+1:])
+		}
+	}
+
+	x.add(attrs(fields))
+	return x
+}
+
+// add adds given attributes to the innermost namespace, or to the handler.
+func (c *Core) add(attrs []slog.Attr) {
+	if len(attrs) == 0 {
+		return
+	}
+
+	if len(c.spaces) == 0 {
+		c.handler = c.handler.WithAttrs(attrs)
+		return
+	}
+
+	space := &c.spaces[len(c.spaces)-1]
+	space.attrs = append(slices.Clip(space.attrs), attrs...)
+}
+
+// Check implements zapcore.Core
+func (c *Core) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+
+	return checked
+}
+
+// Write implements zapcore.Core
+func (c *Core) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	var pc uintptr
+	if entry.Caller.Defined {
+		pc = entry.Caller.PC
+	}
+
+	r := slog.NewRecord(entry.Time, Level(entry.Level), entry.Message, pc)
+
+	if entry.LoggerName != "" {
+		r.AddAttrs(slogr.Name(entry.LoggerName))
+	}
+
+	items := attrs(fields)
+	// the namespaces are groups, but the name and the stack are top level
+	for index := len(c.spaces) - 1; index >= 0; index-- {
+		space := c.spaces[index]
+		items = []slog.Attr{{
+			Key:   space.key,
+			Value: slog.GroupValue(append(slices.Clip(space.attrs), items...)...),
+		}}
+	}
+
+	r.AddAttrs(items...)
+
+	if entry.Stack != "" {
+		r.AddAttrs(slog.String(slogr.StackTraceKey, entry.Stack))
+	}
+
+	return c.handler.Handle(context.Background(), r)
+}
+
+// Sync implements zapcore.Core. It flushes the handler when it implements
+// slogr.Flusher.
+func (c *Core) Sync() error {
+	if flusher, ok := c.handler.(slogr.Flusher); ok {
+		return flusher.Flush()
+	}
+
+	return nil
+}
+
+// Level returns the slog level of a given zap level.
+func Level(level zapcore.Level) slog.Level {
+	switch {
+	case level < zapcore.InfoLevel:
+		return slog.LevelDebug
+	case level < zapcore.WarnLevel:
+		return slog.LevelInfo
+	case level < zapcore.ErrorLevel:
+		return slog.LevelWarn
+	case level < zapcore.DPanicLevel:
+		return slog.LevelError
+	case level < zapcore.PanicLevel:
+		return slogr.LevelCritical
+	case level < zapcore.FatalLevel:
+		return slogr.LevelAlert
+	default:
+		return slogr.LevelEmergency
+	}
+}
+
+// attrs returns the attributes of given fields. The fields after a namespace
+// are its group.
+func attrs(fields []zapcore.Field) []slog.Attr {
+	items := make([]slog.Attr, 0, len(fields))
+
+	for index, field := range fields {
+		switch field.Type {
+		case zapcore.SkipType:
+		case zapcore.NamespaceType:
+			return append(items, slog.Attr{
+				Key:   field.Key,
+				Value: slog.GroupValue(attrs(fields[index+1:])...),
+			})
+		case zapcore.ErrorType:
+			if err, ok := field.Interface.(error); ok && err != nil {
+				items = append(items, slog.Attr{Key: field.Key, Value: slogr.Error(err).Value})
+				// the errors with a stack format it with %+v, as in zap
+				if _, ok := err.(fmt.Formatter); ok {
+					if verbose := fmt.Sprintf("%+v", err); verbose != err.Error() {
+						items = append(items, slog.String(field.Key+"Verbose", verbose))
+					}
+				}
+			}
+		default:
+			encoder := zapcore.NewMapObjectEncoder()
+			field.AddTo(encoder)
+
+			for _, key := range sortedKeys(encoder.Fields) {
+				items = append(items, slog.Attr{Key: key, Value: value(encoder.Fields[key])})
+			}
+		}
+	}
+
+	return items
+}
+
+// value returns the slog value of a given value of a map object encoder.
+func value(v any) slog.Value {
+	switch v := v.(type) {
+	case map[string]any:
+		attrs := make([]slog.Attr, 0, len(v))
+
+		for _, key := range sortedKeys(v) {
+			attrs = append(attrs, slog.Attr{Key: key, Value: value(v[key])})
+		}
+
+		return slog.GroupValue(attrs...)
+	case []any:
+		// the lists are JSON, as the items may be objects
+		data, err := json.Marshal(v)
+		if err != nil {
+			return slog.AnyValue(v)
+		}
+
+		var items []any
+		if err := json.Unmarshal(data, &items); err != nil {
+			return slog.AnyValue(v)
+		}
+
+		list, err := structpb.NewList(items)
+		if err != nil {
+			return slog.AnyValue(v)
+		}
+
+		return slog.AnyValue(list)
+	default:
+		return slog.AnyValue(v)
+	}
+}
+
+// sortedKeys returns the keys of a given map in order.
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
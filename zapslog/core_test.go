@@ -0,0 +1,219 @@
+package zapslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"testing"
+
+	"github.com/ralch/slogr"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// capture returns a zap logger of a core that writes to a buffer, and a
+// function that returns the entries written so far.
+func capture(t *testing.T, level slog.Level, opts ...zap.Option) (*zap.Logger, func() []map[string]any) {
+	t.Helper()
+
+	buffer := &bytes.Buffer{}
+	core := NewCore(slogr.NewHandler(buffer, &slogr.HandlerOptions{Level: level, AddSource: true}))
+
+	return zap.New(core, opts...), func() []map[string]any {
+		var entries []map[string]any
+
+		decoder := json.NewDecoder(bytes.NewReader(buffer.Bytes()))
+		for decoder.More() {
+			entry := map[string]any{}
+			if err := decoder.Decode(&entry); err != nil {
+				t.Fatal(err)
+			}
+
+			entries = append(entries, entry)
+		}
+
+		return entries
+	}
+}
+
+func payload(entry map[string]any) map[string]any {
+	value, _ := entry["message"].(map[string]any)
+	return value
+}
+
+func TestLevel(t *testing.T) {
+	cases := []struct {
+		level zapcore.Level
+		want  slog.Level
+	}{
+		{level: zapcore.DebugLevel, want: slog.LevelDebug},
+		{level: zapcore.InfoLevel, want: slog.LevelInfo},
+		{level: zapcore.WarnLevel, want: slog.LevelWarn},
+		{level: zapcore.ErrorLevel, want: slog.LevelError},
+		{level: zapcore.DPanicLevel, want: slogr.LevelCritical},
+		{level: zapcore.PanicLevel, want: slogr.LevelAlert},
+		{level: zapcore.FatalLevel, want: slogr.LevelEmergency},
+	}
+
+	for _, item := range cases {
+		if got := Level(item.level); got != item.want {
+			t.Errorf("Level(%v) = %v, want %v", item.level, got, item.want)
+		}
+	}
+}
+
+func TestCoreCheck(t *testing.T) {
+	core := NewCore(slogr.NewHandler(&bytes.Buffer{}, &slogr.HandlerOptions{Level: slog.LevelInfo}))
+
+	if checked := core.Check(zapcore.Entry{Level: zapcore.DebugLevel}, nil); checked != nil {
+		t.Error("a disabled entry is checked")
+	}
+
+	if checked := core.Check(zapcore.Entry{Level: zapcore.InfoLevel}, nil); checked == nil {
+		t.Error("an enabled entry isn't checked")
+	}
+}
+
+func TestCoreWrite(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo, zap.AddCaller())
+
+	item := zapcore.ObjectMarshalerFunc(func(encoder zapcore.ObjectEncoder) error {
+		encoder.AddString("id", "a")
+		return nil
+	})
+
+	list := zapcore.ArrayMarshalerFunc(func(encoder zapcore.ArrayEncoder) error {
+		return encoder.AppendObject(item)
+	})
+
+	logger.Named("worker").Info("done",
+		zap.Int("count", 2),
+		zap.Object("item", item),
+		zap.Array("items", list),
+		zap.NamedError("cause", fmt.Errorf("wrap: %w", errors.New("inner"))),
+		zap.Skip(),
+	)
+
+	got := entries()
+	if len(got) != 1 {
+		t.Fatalf("got %d entries, want 1", len(got))
+	}
+
+	fields := payload(got[0])
+
+	if fields["logName"] != "worker" || fields["count"] != float64(2) {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+
+	if value, _ := fields["item"].(map[string]any); value["id"] != "a" {
+		t.Errorf("item = %v", fields["item"])
+	}
+
+	if value, _ := fields["items"].([]any); len(value) != 1 {
+		t.Errorf("items = %v", fields["items"])
+	}
+
+	if cause, _ := fields["cause"].(map[string]any); cause["message"] != "wrap: inner" {
+		t.Errorf("cause = %v", fields["cause"])
+	}
+
+	if _, ok := fields["error"]; ok {
+		t.Errorf("the named error lost its key: %v", fields)
+	}
+
+	location, _ := got[0]["logging.googleapis.com/sourceLocation"].(map[string]any)
+	if location["function"] != "github.com/ralch/slogr/zapslog.TestCoreWrite" {
+		t.Errorf("sourceLocation = %v", location)
+	}
+}
+
+func TestCoreNamespace(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo)
+
+	logger.Named("api").
+		With(zap.String("service", "a"), zap.Namespace("call"), zap.String("id", "1")).
+		Info("handled", zap.Namespace("result"), zap.Int("status", 200))
+
+	fields := payload(entries()[0])
+
+	if fields["service"] != "a" || fields["logName"] != "api" {
+		t.Errorf("unexpected top level fields: %v", fields)
+	}
+
+	call, _ := fields["call"].(map[string]any)
+	if call["id"] != "1" {
+		t.Fatalf("call = %v", fields["call"])
+	}
+
+	if result, _ := call["result"].(map[string]any); result["status"] != float64(200) {
+		t.Errorf("result = %v", call["result"])
+	}
+}
+
+func TestCoreTerminalLevels(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo, zap.WithFatalHook(zapcore.WriteThenPanic))
+
+	logger.DPanic("dpanic")
+
+	for _, log := range []func(string, ...zap.Field){logger.Panic, logger.Fatal} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("the logger didn't panic")
+				}
+			}()
+
+			log("terminal")
+		}()
+	}
+
+	got := entries()
+	if len(got) != 3 {
+		t.Fatalf("got %d entries, want 3", len(got))
+	}
+
+	for index, severity := range []string{"CRITICAL", "ALERT", "EMERGENCY"} {
+		if got[index]["severity"] != severity {
+			t.Errorf("entry %d: severity %v, want %s", index, got[index]["severity"], severity)
+		}
+	}
+}
+
+func TestCoreStack(t *testing.T) {
+	logger, entries := capture(t, slog.LevelInfo, zap.AddStacktrace(zapcore.ErrorLevel))
+
+	logger.Error("failed")
+
+	stack, _ := payload(entries()[0])[slogr.StackTraceKey].(string)
+	if stack == "" {
+		t.Error("the stack of the entry is missing")
+	}
+}
+
+type flushHandler struct {
+	slog.Handler
+	flushed int
+}
+
+func (h *flushHandler) Flush() error {
+	h.flushed++
+	return nil
+}
+
+func TestCoreSync(t *testing.T) {
+	handler := &flushHandler{Handler: slog.NewTextHandler(&bytes.Buffer{}, nil)}
+
+	if err := zap.New(NewCore(handler)).Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	if handler.flushed != 1 {
+		t.Errorf("flushed %d times, want 1", handler.flushed)
+	}
+
+	if err := NewCore(slog.NewTextHandler(&bytes.Buffer{}, nil)).Sync(); err != nil {
+		t.Error(err)
+	}
+}